@@ -0,0 +1,218 @@
+// Package cli implements the subcommands of the server binary
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/redirects"
+	"github.com/rcovery/go-url-shortener/shorturl/sharded"
+	"github.com/rcovery/go-url-shortener/shorturl/staticsite"
+	"github.com/rcovery/go-url-shortener/shorturl/transfer"
+)
+
+const usage = `usage: go-url-shortener <command> [flags]
+
+commands:
+  import -format csv|json|ndjson -conflict skip|overwrite|rename [file]
+  export -format csv|json|ndjson [-owner o] [-tag t] [-domain d] [-o file]
+  redirects -format nginx|apache|netlify|caddy [-valid-for 24h] [-o file]
+  site -out dir
+  rebalance [-dry-run]    move links to the shard they hash to, with sharded storage
+
+Without a command, the HTTP server is started.`
+
+var ErrUnknownCommand = errors.New("unknown command")
+
+// Run executes the subcommand in args[0] against repo, through service.
+// Files default to stdin and stdout
+func Run(ctx context.Context, args []string, repo shorturl.Repository, service *shorturl.Service, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w\n%s", ErrUnknownCommand, usage)
+	}
+
+	switch args[0] {
+	case "import":
+		return runImport(ctx, args[1:], service, stdin, stdout)
+	case "export":
+		return runExport(ctx, args[1:], service, stdout)
+	case "redirects":
+		return runRedirects(ctx, args[1:], service, stdout)
+	case "site":
+		return runSite(ctx, args[1:], service, stdout)
+	case "rebalance":
+		return runRebalance(ctx, args[1:], repo, stdout)
+	case "help", "-h", "--help":
+		_, err := fmt.Fprintln(stdout, usage)
+		return err
+	}
+
+	return fmt.Errorf("%w %q\n%s", ErrUnknownCommand, args[0], usage)
+}
+
+func runImport(ctx context.Context, args []string, service *shorturl.Service, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	rawFormat := flags.String("format", "csv", "csv, json or ndjson")
+	rawStrategy := flags.String("conflict", "skip", "what to do with taken names: skip, overwrite or rename")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	format, formatErr := transfer.ParseFormat(*rawFormat)
+	if formatErr != nil {
+		return formatErr
+	}
+	strategy, strategyErr := transfer.ParseConflictStrategy(*rawStrategy)
+	if strategyErr != nil {
+		return strategyErr
+	}
+
+	input := stdin
+	if flags.NArg() > 0 {
+		file, openErr := os.Open(flags.Arg(0))
+		if openErr != nil {
+			return openErr
+		}
+		defer file.Close()
+		input = file
+	}
+
+	report, importErr := transfer.Import(ctx, input, format, strategy, service)
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(report); encodeErr != nil {
+		return errors.Join(importErr, encodeErr)
+	}
+
+	return importErr
+}
+
+type repeated []string
+
+func (r *repeated) String() string {
+	return fmt.Sprint(*r)
+}
+
+func (r *repeated) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
+
+func runExport(ctx context.Context, args []string, service *shorturl.Service, stdout io.Writer) error {
+	var filter shorturl.ListFilter
+	var tags repeated
+
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	rawFormat := flags.String("format", "csv", "csv, json or ndjson")
+	output := flags.String("o", "", "output file, stdout by default")
+	flags.StringVar(&filter.Owner, "owner", "", "only links of this owner")
+	flags.Var(&tags, "tag", "only links with this tag, repeatable")
+	flags.StringVar(&filter.Domain, "domain", "", "only links pointing at this domain or its subdomains")
+	flags.StringVar(&filter.Host, "host", "", "only links pointing at this exact host")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	filter.Tags = tags
+
+	format, formatErr := transfer.ParseFormat(*rawFormat)
+	if formatErr != nil {
+		return formatErr
+	}
+
+	if *output == "" {
+		_, exportErr := transfer.Export(ctx, stdout, format, service, filter)
+		return exportErr
+	}
+
+	file, createErr := os.Create(*output)
+	if createErr != nil {
+		return createErr
+	}
+	_, exportErr := transfer.Export(ctx, file, format, service, filter)
+	return errors.Join(exportErr, file.Close())
+}
+
+func runRedirects(ctx context.Context, args []string, service *shorturl.Service, stdout io.Writer) error {
+	flags := flag.NewFlagSet("redirects", flag.ContinueOnError)
+	rawFormat := flags.String("format", "nginx", "nginx, apache, netlify or caddy")
+	validFor := flags.Duration("valid-for", 0, "leave out links expiring within this long")
+	output := flags.String("o", "", "output file, stdout by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	format, formatErr := redirects.ParseFormat(*rawFormat)
+	if formatErr != nil {
+		return formatErr
+	}
+
+	writer := stdout
+	var file *os.File
+	if *output != "" {
+		var createErr error
+		if file, createErr = os.Create(*output); createErr != nil {
+			return createErr
+		}
+		writer = file
+	}
+
+	summary, writeErr := redirects.Write(ctx, writer, format, service, time.Now(), *validFor)
+	if file != nil {
+		writeErr = errors.Join(writeErr, file.Close())
+	}
+	for _, name := range summary.Skipped {
+		log.Printf("skipped %q: the name cannot be expressed in %s", name, format)
+	}
+
+	return writeErr
+}
+
+func runSite(ctx context.Context, args []string, service *shorturl.Service, stdout io.Writer) error {
+	flags := flag.NewFlagSet("site", flag.ContinueOnError)
+	out := flags.String("out", "site", "output directory, reused between runs")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, generateErr := staticsite.Generate(ctx, *out, service, time.Now())
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(report); encodeErr != nil {
+		return errors.Join(generateErr, encodeErr)
+	}
+
+	return generateErr
+}
+
+func runRebalance(ctx context.Context, args []string, repo shorturl.Repository, stdout io.Writer) error {
+	flags := flag.NewFlagSet("rebalance", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only count the links that would move")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	shardedRepo, isSharded := repo.(*sharded.Repository)
+	if !isSharded {
+		return errors.New("rebalance needs sharded storage, STORAGE_URL=sharded:")
+	}
+
+	report, rebalanceErr := shardedRepo.Rebalance(ctx, *dryRun)
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(report); encodeErr != nil {
+		return errors.Join(rebalanceErr, encodeErr)
+	}
+
+	return rebalanceErr
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -21,3 +22,19 @@ func InitConfig() {
 func GetString(key string) string {
 	return viper.GetString(key)
 }
+
+func GetDuration(key string) time.Duration {
+	return viper.GetDuration(key)
+}
+
+func GetBool(key string) bool {
+	return viper.GetBool(key)
+}
+
+func GetInt(key string) int {
+	return viper.GetInt(key)
+}
+
+func GetFloat64(key string) float64 {
+	return viper.GetFloat64(key)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+type batchCreateRequest struct {
+	Items []json.RawMessage `json:"items"`
+}
+
+// handleBatchCreate serves POST /api/url/batch. Each item is decoded on its
+// own, so one malformed item is reported in its result instead of
+// rejecting the batch
+func handleBatchCreate(baseCtx context.Context, service *shorturl.Service) {
+	http.HandleFunc("/api/url/batch", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			{
+				ctx, ctxCancel := context.WithTimeout(baseCtx, 30*time.Second)
+				defer ctxCancel()
+				extendWriteDeadline(w, 30*time.Second)
+
+				var body batchCreateRequest
+				if !decodeJSONBody(w, r, 16*MB, &body) {
+					return
+				}
+
+				items := make([]shorturl.ShortURL, len(body.Items))
+				malformed := map[int]bool{}
+				for i, rawItem := range body.Items {
+					if err := json.Unmarshal(rawItem, &items[i]); err != nil {
+						malformed[i] = true
+						// An unnamed item fails validation without reaching the repository
+						items[i] = shorturl.ShortURL{}
+					}
+				}
+
+				results, batchErr := service.CreateMany(ctx, items)
+				if errors.Is(batchErr, errs.InvalidError) {
+					log.Println(batchErr)
+					writeJSONError(w, http.StatusBadRequest, "invalid_batch")
+					return
+				}
+				if batchErr != nil {
+					log.Println(batchErr)
+					writeJSONError(w, http.StatusInternalServerError, "create_failed")
+					return
+				}
+
+				for i := range malformed {
+					results[i].Error = "invalid_json"
+				}
+
+				writeJSON(w, http.StatusOK, map[string]any{
+					"results": results,
+				})
+			}
+		default:
+			{
+				writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+			}
+		}
+	})
+}
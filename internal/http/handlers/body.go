@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// decodeJSONBody reads a JSON body of at most limit bytes into v. On
+// failure it writes the error response and returns false
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, limit int64, v any) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "application/json" {
+		writeJSONError(w, http.StatusBadRequest, "invalid_content_type")
+		return false
+	}
+
+	rawBody := http.MaxBytesReader(w, r.Body, limit)
+	body, err := io.ReadAll(rawBody)
+	if err != nil {
+		log.Println("failed reading body:", err)
+		writeJSONError(w, http.StatusBadRequest, "invalid_body")
+		return false
+	}
+	if len(body) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "empty_body")
+		return false
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		log.Println("failed decoding json:", err)
+		writeJSONError(w, http.StatusBadRequest, "invalid_json")
+		return false
+	}
+
+	return true
+}
+
+// extendWriteDeadline lets a long-running handler outlive the server's
+// WriteTimeout
+func extendWriteDeadline(w http.ResponseWriter, timeout time.Duration) {
+	err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout))
+	if err != nil {
+		log.Println("failed extending write deadline:", err)
+	}
+}
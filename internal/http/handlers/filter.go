@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+// parseListFilter reads a shorturl.ListFilter from query parameters:
+//
+//	owner, tag (repeatable), meta.<key>, domain, host,
+//	created_after, created_before, expires_after, expires_before, changed_after (RFC 3339),
+//	sort, cursor, limit
+func parseListFilter(query url.Values) (shorturl.ListFilter, error) {
+	filter := shorturl.ListFilter{
+		Owner:  query.Get("owner"),
+		Tags:   query["tag"],
+		Domain: query.Get("domain"),
+		Host:   query.Get("host"),
+		Sort:   shorturl.Sort(query.Get("sort")),
+	}
+
+	for key, values := range query {
+		metaKey, isMeta := strings.CutPrefix(key, "meta.")
+		if !isMeta || metaKey == "" || len(values) == 0 {
+			continue
+		}
+		if filter.Metadata == nil {
+			filter.Metadata = shorturl.Metadata{}
+		}
+		filter.Metadata[metaKey] = values[0]
+	}
+
+	times := map[string]*time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+		"expires_after":  &filter.ExpiresAfter,
+		"expires_before": &filter.ExpiresBefore,
+		"changed_after":  &filter.ChangedAfter,
+	}
+	for key, target := range times {
+		raw := query.Get(key)
+		if raw == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		*target = parsed
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			return filter, fmt.Errorf("invalid limit: %w", err)
+		}
+		filter.Limit = limit
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		cursor, err := shorturl.ParseCursor(raw)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = &cursor
+	}
+
+	return filter, nil
+}
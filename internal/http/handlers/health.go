@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Readiness tells load balancers whether the server should get traffic.
+// It starts out not ready
+type Readiness struct {
+	ready atomic.Bool
+
+	mu     sync.Mutex
+	checks map[string]func() string
+}
+
+func (r *Readiness) SetReady(ready bool) {
+	r.ready.Store(ready)
+}
+
+func (r *Readiness) Ready() bool {
+	return r.ready.Load()
+}
+
+// Report adds the result of check under name to health responses. Checks
+// are informational: a degraded dependency doesn't make the server unready,
+// since it may still answer from memory
+func (r *Readiness) Report(name string, check func() string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.checks == nil {
+		r.checks = map[string]func() string{}
+	}
+	r.checks[name] = check
+}
+
+func (r *Readiness) results() map[string]string {
+	r.mu.Lock()
+	checks := maps.Clone(r.checks)
+	r.mu.Unlock()
+
+	if len(checks) == 0 {
+		return nil
+	}
+	results := make(map[string]string, len(checks))
+	for name, check := range checks {
+		results[name] = check()
+	}
+	return results
+}
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// HandleHealth serves /api/healthz, answering as long as the process runs,
+// and /api/readyz, answering 503 until readiness is set. They live under
+// /api/ so no short link can shadow them
+func HandleHealth(baseCtx context.Context, readiness *Readiness) {
+	http.HandleFunc("/api/healthz", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			{
+				writeJSON(w, http.StatusOK, healthResponse{Status: "ok", Checks: readiness.results()})
+			}
+		default:
+			{
+				writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+			}
+		}
+	})
+
+	http.HandleFunc("/api/readyz", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			{
+				if baseCtx.Err() != nil || !readiness.Ready() {
+					writeJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "not_ready", Checks: readiness.results()})
+					return
+				}
+				writeJSON(w, http.StatusOK, healthResponse{Status: "ready", Checks: readiness.results()})
+			}
+		default:
+			{
+				writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+			}
+		}
+	})
+}
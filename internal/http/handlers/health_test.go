@@ -0,0 +1,68 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/internal/http/handlers"
+)
+
+func TestHealth(t *testing.T) {
+	readiness := &handlers.Readiness{}
+	handlers.HandleHealth(context.Background(), readiness)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	status := func(path string) int {
+		return get(path).Code
+	}
+
+	t.Run("should be alive but not ready until set", func(t *testing.T) {
+		if code := status("/api/healthz"); code != http.StatusOK {
+			t.Errorf("want %d from /api/healthz, got %d", http.StatusOK, code)
+		}
+		if code := status("/api/readyz"); code != http.StatusServiceUnavailable {
+			t.Errorf("want %d from /api/readyz, got %d", http.StatusServiceUnavailable, code)
+		}
+	})
+
+	t.Run("should leave top-level paths to short links", func(t *testing.T) {
+		for _, path := range []string{"/healthz", "/readyz"} {
+			if code := status(path); code != http.StatusNotFound {
+				t.Errorf("want %s unclaimed, got %d", path, code)
+			}
+		}
+	})
+
+	t.Run("should be ready once set", func(t *testing.T) {
+		readiness.SetReady(true)
+		if code := status("/api/readyz"); code != http.StatusOK {
+			t.Errorf("want %d from /api/readyz, got %d", http.StatusOK, code)
+		}
+	})
+	t.Run("should report checks without becoming unready", func(t *testing.T) {
+		readiness.Report("database", func() string { return "open" })
+
+		w := get("/api/readyz")
+		if w.Code != http.StatusOK {
+			t.Errorf("want %d from /api/readyz, got %d", http.StatusOK, w.Code)
+		}
+		if want := `"checks":{"database":"open"}`; !strings.Contains(w.Body.String(), want) {
+			t.Errorf("want %s in %q", want, w.Body.String())
+		}
+	})
+
+	t.Run("should refuse other methods with a JSON error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/readyz", nil))
+		if w.Code != http.StatusMethodNotAllowed || !strings.Contains(w.Body.String(), `"method_not_allowed"`) {
+			t.Errorf("want %d method_not_allowed, got %d %q", http.StatusMethodNotAllowed, w.Code, w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+// redirectTimeout is the budget of a redirect that has to reach the repository
+const redirectTimeout = 100 * time.Millisecond
+
+// Resolver finds where a short link currently points. shorturl.Service
+// resolves from the database, snapshot.Store from memory
+type Resolver interface {
+	Location(ctx context.Context, name string) (shorturl.Location, error)
+}
+
+// cachedResolver can answer some redirects from memory, with no context
+type cachedResolver interface {
+	CachedLocation(name string) (shorturl.Location, bool)
+}
+
+// Shared between responses, which only read them
+var (
+	jsonContentType = []string{"application/json"}
+	notFoundBody    = []byte(`{"error":"not_found"}` + "\n")
+	notFoundLength  = []string{strconv.Itoa(len(notFoundBody))}
+)
+
+// HandleRedirect serves /{url_name}, the only route a follower needs
+func HandleRedirect(baseCtx context.Context, resolver Resolver) {
+	http.HandleFunc("/{url_name}", RedirectHandler(baseCtx, resolver))
+}
+
+// RedirectHandler answers GET and HEAD. Redirects the resolver knows from
+// memory are served without allocating; the others get redirectTimeout to
+// reach the repository
+func RedirectHandler(baseCtx context.Context, resolver Resolver) http.HandlerFunc {
+	cached, _ := resolver.(cachedResolver)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+			return
+		}
+
+		urlName := r.PathValue("url_name")
+		if cached != nil {
+			if location, known := cached.CachedLocation(urlName); known {
+				writeRedirect(w, r, location)
+				return
+			}
+		}
+
+		ctx, ctxCancel := context.WithTimeout(baseCtx, redirectTimeout)
+		defer ctxCancel()
+
+		location, locationErr := resolver.Location(ctx, urlName)
+		switch {
+		case locationErr == nil, errors.Is(locationErr, errs.NotFoundError):
+			writeRedirect(w, r, location)
+		case errors.Is(locationErr, errs.UnavailableError), errors.Is(locationErr, context.DeadlineExceeded):
+			// Telling clients the link is gone would be a lie they may cache
+			writeJSONError(w, http.StatusServiceUnavailable, "unavailable")
+		default:
+			log.Println(locationErr)
+			writeJSONError(w, http.StatusInternalServerError, "redirect_failed")
+		}
+	}
+}
+
+// writeRedirect sends a 303 to location, or a 404 when it is nil
+func writeRedirect(w http.ResponseWriter, r *http.Request, location shorturl.Location) {
+	header := w.Header()
+	if location == nil {
+		header["Content-Type"] = jsonContentType
+		header["Content-Length"] = notFoundLength
+		w.WriteHeader(http.StatusNotFound)
+		if r.Method != http.MethodHead {
+			w.Write(notFoundBody)
+		}
+		return
+	}
+
+	header["Location"] = location
+	w.WriteHeader(http.StatusSeeOther)
+}
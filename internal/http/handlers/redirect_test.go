@@ -0,0 +1,225 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/internal/http/handlers"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/bloom"
+	"github.com/rcovery/go-url-shortener/shorturl/cache"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
+)
+
+// discardWriter is a ResponseWriter that keeps nothing but the status, and
+// reuses its header map, so benchmarks measure the handler alone
+type discardWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(status int)      { w.status = status }
+
+func (w *discardWriter) reset() {
+	clear(w.header)
+	w.status = 0
+}
+
+func newRedirectRequest(method, name string) *http.Request {
+	r := httptest.NewRequest(method, "/"+name, nil)
+	r.SetPathValue("url_name", name)
+	return r
+}
+
+// newResolver builds the redirect path as main does: a Bloom filter and a
+// cache in front of an in-memory repository holding size links
+func newResolver(tb testing.TB, size int) *shorturl.Service {
+	tb.Helper()
+	ctx := context.Background()
+
+	repo := memory.NewRepository()
+	for i := range size {
+		id, _ := shorturl.NewID()
+		link, _ := shorturl.NewLink(fmt.Sprintf("https://example.com/articles/%d?utm_source=short", i))
+		if err := repo.Insert(ctx, shorturl.ShortURL{ID: id, Name: fmt.Sprintf("link-%d", i), Link: link, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+			tb.Fatalf("Insert failed unexpectedly: %v", err)
+		}
+	}
+
+	cached, cacheErr := cache.Wrap(repo, cache.Options{Size: size})
+	if cacheErr != nil {
+		tb.Fatalf("cannot build the cache: %v", cacheErr)
+	}
+	names, bloomErr := bloom.New(cached, bloom.Options{Capacity: size})
+	if bloomErr != nil {
+		tb.Fatalf("cannot build the filter: %v", bloomErr)
+	}
+	if rebuildErr := names.Rebuild(ctx); rebuildErr != nil {
+		tb.Fatalf("Rebuild failed unexpectedly: %v", rebuildErr)
+	}
+
+	return shorturl.NewService(names)
+}
+
+// failingResolver fails every lookup with err
+type failingResolver struct{ err error }
+
+func (r failingResolver) Location(ctx context.Context, name string) (shorturl.Location, error) {
+	return nil, r.err
+}
+
+func TestRedirect(t *testing.T) {
+	handler := handlers.RedirectHandler(context.Background(), newResolver(t, 10))
+
+	t.Run("should redirect GET and HEAD", func(t *testing.T) {
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			w := httptest.NewRecorder()
+			handler(w, newRedirectRequest(method, "link-3"))
+
+			if w.Code != http.StatusSeeOther {
+				t.Errorf("%s: want %d, got %d", method, http.StatusSeeOther, w.Code)
+			}
+			if location := w.Header().Get("Location"); location != "https://example.com/articles/3?utm_source=short" {
+				t.Errorf("%s: want the link's destination, got %q", method, location)
+			}
+		}
+	})
+
+	t.Run("should answer unknown names with a 404", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler(w, newRedirectRequest(http.MethodGet, "unknown"))
+
+		if w.Code != http.StatusNotFound || w.Body.String() != "{\"error\":\"not_found\"}\n" {
+			t.Errorf("want a JSON 404, got %d %q", w.Code, w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		handler(w, newRedirectRequest(http.MethodHead, "unknown"))
+		if w.Code != http.StatusNotFound || w.Body.Len() != 0 {
+			t.Errorf("want a 404 without body for HEAD, got %d %q", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("should answer 503 rather than 404 while the repository is unavailable", func(t *testing.T) {
+		handler := handlers.RedirectHandler(context.Background(), failingResolver{errs.UnavailableError.New("circuit breaker is open")})
+
+		w := httptest.NewRecorder()
+		handler(w, newRedirectRequest(http.MethodGet, "link-3"))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("want %d, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+
+		handler = handlers.RedirectHandler(context.Background(), failingResolver{errors.New("syntax error")})
+		w = httptest.NewRecorder()
+		handler(w, newRedirectRequest(http.MethodGet, "link-3"))
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("want %d, got %d", http.StatusInternalServerError, w.Code)
+		}
+	})
+
+	t.Run("should refuse other methods", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler(w, newRedirectRequest(http.MethodPost, "link-3"))
+
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("want %d, got %d", http.StatusMethodNotAllowed, w.Code)
+		}
+	})
+
+	t.Run("should not allocate for cached redirects and definite misses", func(t *testing.T) {
+		w := &discardWriter{header: http.Header{}}
+		for _, name := range []string{"link-3", "unknown"} {
+			r := newRedirectRequest(http.MethodGet, name)
+			handler(w, r)
+
+			allocs := testing.AllocsPerRun(100, func() {
+				w.reset()
+				handler(w, r)
+			})
+			if allocs != 0 {
+				t.Errorf("%s: want no allocations, got %.1f", name, allocs)
+			}
+		}
+	})
+}
+
+func BenchmarkRedirect(b *testing.B) {
+	const size = 10000
+	handler := handlers.RedirectHandler(context.Background(), newResolver(b, size))
+
+	requests := make([]*http.Request, size)
+	for i := range requests {
+		requests[i] = newRedirectRequest(http.MethodGet, fmt.Sprintf("link-%d", i))
+	}
+	// Warms the cache
+	w := &discardWriter{header: http.Header{}}
+	for _, r := range requests {
+		handler(w, r)
+	}
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			w.reset()
+			handler(w, requests[i%size])
+			i++
+		}
+	})
+
+	b.Run("head", func(b *testing.B) {
+		r := newRedirectRequest(http.MethodHead, "link-42")
+		b.ReportAllocs()
+		for b.Loop() {
+			w.reset()
+			handler(w, r)
+		}
+	})
+
+	b.Run("never created", func(b *testing.B) {
+		r := newRedirectRequest(http.MethodGet, "random-probe")
+		b.ReportAllocs()
+		for b.Loop() {
+			w.reset()
+			handler(w, r)
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			w := &discardWriter{header: http.Header{}}
+			i := 0
+			for pb.Next() {
+				w.reset()
+				handler(w, requests[i%size])
+				i++
+			}
+		})
+	})
+}
+
+func BenchmarkRedirectUncached(b *testing.B) {
+	repo := memory.NewRepository()
+	id, _ := shorturl.NewID()
+	link, _ := shorturl.NewLink("https://example.com/articles/1")
+	repo.Insert(context.Background(), shorturl.ShortURL{ID: id, Name: "link", Link: link})
+
+	handler := handlers.RedirectHandler(context.Background(), shorturl.NewService(repo))
+	r := newRedirectRequest(http.MethodGet, "link")
+	w := &discardWriter{header: http.Header{}}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		w.reset()
+		handler(w, r)
+	}
+}
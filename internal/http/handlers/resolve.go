@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+type resolveRequest struct {
+	Names []string `json:"names"`
+}
+
+// handleResolve serves POST /api/url/resolve, resolving many names in one round trip
+func handleResolve(baseCtx context.Context, service *shorturl.Service) {
+	http.HandleFunc("/api/url/resolve", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			{
+				ctx, ctxCancel := context.WithTimeout(baseCtx, 1*time.Second)
+				defer ctxCancel()
+
+				var body resolveRequest
+				if !decodeJSONBody(w, r, 1*MB, &body) {
+					return
+				}
+
+				resolutions, resolveErr := service.Resolve(ctx, body.Names)
+				if errors.Is(resolveErr, errs.InvalidError) {
+					log.Println(resolveErr)
+					writeJSONError(w, http.StatusBadRequest, "invalid_names")
+					return
+				}
+				if resolveErr != nil {
+					log.Println(resolveErr)
+					writeJSONError(w, http.StatusInternalServerError, "resolve_failed")
+					return
+				}
+
+				writeJSON(w, http.StatusOK, map[string]any{
+					"results": resolutions,
+				})
+			}
+		default:
+			{
+				writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+			}
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+// handleReverseLookup serves GET /api/reverse?url=<url>&match=exact|prefix|host
+// and GET /api/reverse?domain=<host>
+func handleReverseLookup(baseCtx context.Context, service *shorturl.Service) {
+	http.HandleFunc("/api/reverse", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			{
+				ctx, ctxCancel := context.WithTimeout(baseCtx, 5*time.Second)
+				defer ctxCancel()
+				extendWriteDeadline(w, 5*time.Second)
+
+				params := r.URL.Query()
+				match, target := shorturl.DestinationMatch(params.Get("match")), params.Get("url")
+				if domain := params.Get("domain"); domain != "" {
+					match, target = shorturl.MatchHost, domain
+				}
+				if match == "" {
+					match = shorturl.MatchExact
+				}
+
+				query, queryErr := shorturl.NewDestinationQuery(match, target)
+				if queryErr != nil {
+					log.Println(queryErr)
+					writeJSONError(w, http.StatusBadRequest, "invalid_destination")
+					break
+				}
+
+				found, lookupErr := service.ReverseLookup(ctx, query)
+				if errors.Is(lookupErr, errs.InvalidError) {
+					writeJSONError(w, http.StatusBadRequest, "invalid_destination")
+					break
+				}
+				if lookupErr != nil {
+					log.Println(lookupErr)
+					writeJSONError(w, http.StatusInternalServerError, "lookup_failed")
+					break
+				}
+
+				writeJSON(w, http.StatusOK, map[string]any{
+					"match":  query.Match,
+					"target": query.Target,
+					"items":  found,
+				})
+				break
+			}
+		default:
+			{
+				writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+			}
+		}
+	})
+}
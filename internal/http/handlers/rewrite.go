@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+type rewriteRequest struct {
+	Match     shorturl.DestinationMatch `json:"match"`
+	From      string                    `json:"from"`
+	To        string                    `json:"to"`
+	Reason    string                    `json:"reason"`
+	DryRun    bool                      `json:"dryRun"`
+	BatchSize int                       `json:"batchSize"`
+}
+
+// handleRewrite serves POST /api/rewrite, moving every destination on a
+// host or under a URL prefix to a new one
+func handleRewrite(baseCtx context.Context, service *shorturl.Service) {
+	http.HandleFunc("/api/rewrite", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			{
+				ctx, ctxCancel := context.WithTimeout(baseCtx, 5*time.Minute)
+				defer ctxCancel()
+				extendWriteDeadline(w, 5*time.Minute)
+
+				var body rewriteRequest
+				if !decodeJSONBody(w, r, 1*MB, &body) {
+					break
+				}
+				if body.Match == "" {
+					body.Match = shorturl.MatchHost
+				}
+
+				rule, ruleErr := shorturl.NewRewriteRule(body.Match, body.From, body.To)
+				if ruleErr != nil {
+					log.Println(ruleErr)
+					writeJSONError(w, http.StatusBadRequest, "invalid_rewrite")
+					break
+				}
+
+				report, rewriteErr := service.Rewrite(ctx, rule, body.Reason, body.DryRun, body.BatchSize)
+				if errors.Is(rewriteErr, errs.InvalidError) {
+					writeJSONError(w, http.StatusBadRequest, "invalid_rewrite")
+					break
+				}
+				if rewriteErr != nil {
+					// Batches committed before the failure stay applied
+					log.Println(rewriteErr)
+					writeJSON(w, http.StatusInternalServerError, map[string]any{
+						"error":  "rewrite_failed",
+						"report": report,
+					})
+					break
+				}
+
+				writeJSON(w, http.StatusOK, report)
+				break
+			}
+		default:
+			{
+				writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+			}
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/internal/http/handlers"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
+)
+
+func TestRoutes(t *testing.T) {
+	handlers.HandleShortURL(context.Background(), shorturl.NewService(memory.NewRepository()))
+
+	routes := []struct{ method, path string }{
+		{http.MethodGet, "/api/url/batch"},
+		{http.MethodGet, "/api/url/resolve"},
+		{http.MethodPost, "/api/export"},
+		{http.MethodGet, "/api/import"},
+		{http.MethodPost, "/api/export/redirects"},
+		{http.MethodPost, "/api/snapshot"},
+	}
+	for _, route := range routes {
+		t.Run("should refuse "+route.method+" "+route.path+" with a JSON error", func(t *testing.T) {
+			w := httptest.NewRecorder()
+			http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest(route.method, route.path, nil))
+
+			if w.Code != http.StatusMethodNotAllowed || !strings.Contains(w.Body.String(), `"method_not_allowed"`) {
+				t.Errorf("want %d method_not_allowed, got %d %q", http.StatusMethodNotAllowed, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+func HandleSearch(baseCtx context.Context, service *shorturl.SearchService) {
+	http.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			{
+				ctx, ctxCancel := context.WithTimeout(baseCtx, 1*time.Second)
+				defer ctxCancel()
+
+				limit := 0
+				if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
+					parsedLimit, limitErr := strconv.Atoi(rawLimit)
+					if limitErr != nil {
+						writeJSONError(w, http.StatusBadRequest, "invalid_limit")
+						break
+					}
+					limit = parsedLimit
+				}
+
+				results, searchErr := service.Search(ctx, r.URL.Query().Get("q"), limit)
+				if errors.Is(searchErr, errs.InvalidError) {
+					writeJSONError(w, http.StatusBadRequest, "invalid_query")
+					break
+				}
+				if searchErr != nil {
+					log.Println(searchErr)
+					writeJSONError(w, http.StatusInternalServerError, "search_failed")
+					break
+				}
+
+				writeJSON(w, http.StatusOK, map[string]any{
+					"results": results,
+				})
+				break
+			}
+		default:
+			{
+				writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+			}
+		}
+	})
+}
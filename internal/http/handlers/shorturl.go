@@ -3,12 +3,14 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
 )
 
 func HandleShortURL(baseCtx context.Context, service *shorturl.Service) {
@@ -45,7 +47,7 @@ func HandleShortURL(baseCtx context.Context, service *shorturl.Service) {
 					return
 				}
 
-				createdLink, URLErr := service.Create(ctx, createURLBody.ID, createURLBody.IdempotencyKey, createURLBody.Name, createURLBody.Link)
+				createdLink, URLErr := service.Create(ctx, createURLBody.ID, createURLBody.IdempotencyKey, createURLBody.Name, createURLBody.Link, createURLBody.Details)
 				if URLErr != nil {
 					log.Println(URLErr)
 					writeJSONError(w, http.StatusBadRequest, "create_failed")
@@ -57,7 +59,35 @@ func HandleShortURL(baseCtx context.Context, service *shorturl.Service) {
 					break
 				}
 
-				w.WriteHeader(200)
+				createURLBody.Link = createdLink
+				writeJSON(w, http.StatusOK, createURLBody)
+				break
+			}
+		case "GET":
+			{
+				ctx, ctxCancel := context.WithTimeout(baseCtx, 1*time.Second)
+				defer ctxCancel()
+
+				filter, filterErr := parseListFilter(r.URL.Query())
+				if filterErr != nil {
+					log.Println(filterErr)
+					writeJSONError(w, http.StatusBadRequest, "invalid_filter")
+					break
+				}
+
+				page, listErr := service.List(ctx, filter)
+				if errors.Is(listErr, errs.InvalidError) {
+					log.Println(listErr)
+					writeJSONError(w, http.StatusBadRequest, "invalid_filter")
+					break
+				}
+				if listErr != nil {
+					log.Println(listErr)
+					writeJSONError(w, http.StatusInternalServerError, "list_failed")
+					break
+				}
+
+				writeJSON(w, http.StatusOK, page)
 				break
 			}
 		default:
@@ -67,23 +97,30 @@ func HandleShortURL(baseCtx context.Context, service *shorturl.Service) {
 		}
 	})
 
-	http.HandleFunc("/{url_name}", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/api/url/{url_name}", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "GET":
 			{
-				ctx, ctxCancel := context.WithTimeout(baseCtx, 100*time.Millisecond)
+				ctx, ctxCancel := context.WithTimeout(baseCtx, 1*time.Second)
 				defer ctxCancel()
 
 				urlName := r.PathValue("url_name")
-				urlFromDatabase, selectionError := service.Select(ctx, urlName)
-				if selectionError != nil || urlFromDatabase == nil {
-					log.Println(selectionError)
+				urlFound, findErr := service.Find(ctx, urlName)
+				switch {
+				case findErr == nil:
+				case errors.Is(findErr, errs.NotFoundError):
 					writeJSONError(w, http.StatusNotFound, "not_found")
-					break
+					return
+				case errors.Is(findErr, errs.UnavailableError), errors.Is(findErr, context.DeadlineExceeded):
+					writeJSONError(w, http.StatusServiceUnavailable, "unavailable")
+					return
+				default:
+					log.Println(findErr)
+					writeJSONError(w, http.StatusInternalServerError, "find_failed")
+					return
 				}
 
-				w.Header().Add("Location", urlFromDatabase.String())
-				w.WriteHeader(303)
+				writeJSON(w, http.StatusOK, urlFound)
 				break
 			}
 		default:
@@ -92,6 +129,24 @@ func HandleShortURL(baseCtx context.Context, service *shorturl.Service) {
 			}
 		}
 	})
+
+	HandleRedirect(baseCtx, service)
+	handleReverseLookup(baseCtx, service)
+	handleRewrite(baseCtx, service)
+	handleBatchCreate(baseCtx, service)
+	handleResolve(baseCtx, service)
+	handleTransfer(baseCtx, service)
+	handleSnapshot(baseCtx, service)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encodeErr := json.NewEncoder(w).Encode(body)
+	if encodeErr != nil {
+		log.Println("failed encoding json:", encodeErr)
+	}
 }
 
 func writeJSONError(w http.ResponseWriter, status int, code string) {
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/snapshot"
+)
+
+// handleSnapshot serves GET /api/snapshot, the binary snapshot of every
+// active link, or with ?since=<RFC 3339> only those changed since then
+func handleSnapshot(baseCtx context.Context, service *shorturl.Service) {
+	http.HandleFunc("/api/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			{
+				ctx, ctxCancel := context.WithTimeout(baseCtx, 5*time.Minute)
+				defer ctxCancel()
+				extendWriteDeadline(w, 5*time.Minute)
+
+				var since time.Time
+				if raw := r.URL.Query().Get("since"); raw != "" {
+					parsed, parseErr := time.Parse(time.RFC3339Nano, raw)
+					if parseErr != nil {
+						writeJSONError(w, http.StatusBadRequest, "invalid_since")
+						return
+					}
+					since = parsed
+				}
+
+				out := &startedWriter{Writer: w}
+				w.Header().Set("Content-Type", "application/octet-stream")
+				if writeErr := snapshot.Write(ctx, out, service, time.Now(), since); writeErr != nil {
+					log.Println("failed writing snapshot:", writeErr)
+					if !out.started {
+						writeJSONError(w, http.StatusInternalServerError, "snapshot_failed")
+					}
+				}
+			}
+		default:
+			{
+				writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+			}
+		}
+	})
+}
+
+// startedWriter tells whether anything reached the client, after which a
+// failure can no longer change the status
+type startedWriter struct {
+	io.Writer
+	started bool
+}
+
+func (w *startedWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.Writer.Write(p)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/redirects"
+	"github.com/rcovery/go-url-shortener/shorturl/transfer"
+)
+
+// handleTransfer serves GET /api/export?format=csv|json|ndjson, which
+// takes the same filters as GET /api/url, and
+// GET /api/export/redirects?format=nginx|apache|netlify|caddy&valid_for=24h and
+// POST /api/import?format=csv|json|ndjson&conflict=skip|overwrite|rename
+func handleTransfer(baseCtx context.Context, service *shorturl.Service) {
+	http.HandleFunc("/api/export", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			{
+				ctx, ctxCancel := context.WithTimeout(baseCtx, 10*time.Minute)
+				defer ctxCancel()
+				extendWriteDeadline(w, 10*time.Minute)
+
+				format, formatErr := transfer.ParseFormat(r.URL.Query().Get("format"))
+				if formatErr != nil {
+					writeJSONError(w, http.StatusBadRequest, "invalid_format")
+					return
+				}
+				filter, filterErr := parseListFilter(r.URL.Query())
+				if filterErr != nil || filter.After != nil {
+					writeJSONError(w, http.StatusBadRequest, "invalid_filter")
+					return
+				}
+
+				w.Header().Set("Content-Type", format.ContentType())
+				w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="links.%s"`, format))
+
+				exported, exportErr := transfer.Export(ctx, w, format, service, filter)
+				if exportErr != nil && exported == 0 {
+					log.Println(exportErr)
+					writeJSONError(w, http.StatusInternalServerError, "export_failed")
+					return
+				}
+				if exportErr != nil {
+					// Streaming already started, so the status is sent; the body is cut short
+					log.Printf("export failed after %d links: %v", exported, exportErr)
+				}
+			}
+		default:
+			{
+				writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+			}
+		}
+	})
+
+	http.HandleFunc("/api/export/redirects", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			{
+				ctx, ctxCancel := context.WithTimeout(baseCtx, 10*time.Minute)
+				defer ctxCancel()
+				extendWriteDeadline(w, 10*time.Minute)
+
+				format, formatErr := redirects.ParseFormat(r.URL.Query().Get("format"))
+				if formatErr != nil {
+					writeJSONError(w, http.StatusBadRequest, "invalid_format")
+					return
+				}
+				var validFor time.Duration
+				if raw := r.URL.Query().Get("valid_for"); raw != "" {
+					parsed, parseErr := time.ParseDuration(raw)
+					if parseErr != nil || parsed < 0 {
+						writeJSONError(w, http.StatusBadRequest, "invalid_valid_for")
+						return
+					}
+					validFor = parsed
+				}
+
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				summary, writeErr := redirects.Write(ctx, w, format, service, time.Now(), validFor)
+				if writeErr != nil {
+					// The header line is already sent, so only the log can tell
+					log.Printf("redirect export failed after %d links: %v", summary.Written, writeErr)
+				}
+			}
+		default:
+			{
+				writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+			}
+		}
+	})
+
+	http.HandleFunc("/api/import", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			{
+				ctx, ctxCancel := context.WithTimeout(baseCtx, 10*time.Minute)
+				defer ctxCancel()
+				extendWriteDeadline(w, 10*time.Minute)
+
+				format, formatErr := transfer.ParseFormat(r.URL.Query().Get("format"))
+				if formatErr != nil {
+					writeJSONError(w, http.StatusBadRequest, "invalid_format")
+					return
+				}
+				strategy, strategyErr := transfer.ParseConflictStrategy(r.URL.Query().Get("conflict"))
+				if strategyErr != nil {
+					writeJSONError(w, http.StatusBadRequest, "invalid_conflict")
+					return
+				}
+
+				body := http.MaxBytesReader(w, r.Body, 64*MB)
+				report, importErr := transfer.Import(ctx, body, format, strategy, service)
+				if importErr != nil {
+					log.Println(importErr)
+					writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+						"error":   "import_failed",
+						"message": importErr.Error(),
+						"report":  report,
+					})
+					return
+				}
+
+				writeJSON(w, http.StatusOK, report)
+			}
+		default:
+			{
+				writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+			}
+		}
+	})
+}
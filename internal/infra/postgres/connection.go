@@ -3,14 +3,30 @@ package postgres
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/rcovery/go-url-shortener/internal/config"
 )
 
-func GetConnectionFromEnv() string {
+// GetConnectionFromEnv returns the DSN of the primary, at DBHOST, and one
+// per host in the comma-separated DBREPLICA_HOSTS, with the same credentials
+func GetConnectionFromEnv() (string, []string) {
+	primary := connectionTo(config.GetString("DBHOST"))
+
+	var replicas []string
+	for host := range strings.SplitSeq(config.GetString("DBREPLICA_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			replicas = append(replicas, connectionTo(host))
+		}
+	}
+
+	return primary, replicas
+}
+
+func connectionTo(host string) string {
 	return fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s sslmode=%s",
-		config.GetString("DBHOST"),
+		host,
 		config.GetString("DBUSER"),
 		config.GetString("DBPASS"),
 		config.GetString("DBDATABASE"),
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE shorturls
+  ADD COLUMN title text,
+  ADD COLUMN description text,
+  ADD COLUMN tags text[] NOT NULL DEFAULT '{}',
+  ADD COLUMN metadata jsonb NOT NULL DEFAULT '{}';
+
+CREATE INDEX shorturls_tags_idx ON shorturls USING GIN (tags);
+CREATE INDEX shorturls_metadata_idx ON shorturls USING GIN (metadata jsonb_path_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS shorturls_metadata_idx;
+DROP INDEX IF EXISTS shorturls_tags_idx;
+
+ALTER TABLE shorturls
+  DROP COLUMN IF EXISTS metadata,
+  DROP COLUMN IF EXISTS tags,
+  DROP COLUMN IF EXISTS description,
+  DROP COLUMN IF EXISTS title;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE shorturls
+  ADD COLUMN owner text,
+  ADD COLUMN link_host text;
+
+UPDATE shorturls
+  SET link_host = lower(substring(link from '^[a-zA-Z][a-zA-Z0-9+.-]*://(?:[^/?#@]*@)?([^/?#:]*)'));
+
+CREATE INDEX shorturls_owner_id_idx ON shorturls (owner, id);
+CREATE INDEX shorturls_link_host_idx ON shorturls (link_host, id);
+CREATE INDEX shorturls_created_at_idx ON shorturls (created_at);
+CREATE INDEX shorturls_expires_at_idx ON shorturls (expires_at, id);
+CREATE INDEX shorturls_name_id_idx ON shorturls (name, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS shorturls_name_id_idx;
+DROP INDEX IF EXISTS shorturls_expires_at_idx;
+DROP INDEX IF EXISTS shorturls_created_at_idx;
+DROP INDEX IF EXISTS shorturls_link_host_idx;
+DROP INDEX IF EXISTS shorturls_owner_id_idx;
+
+ALTER TABLE shorturls
+  DROP COLUMN IF EXISTS link_host,
+  DROP COLUMN IF EXISTS owner;
+-- +goose StatementEnd
@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE shorturls
+  ADD COLUMN search_vector tsvector;
+
+-- Names, titles and tags weigh more than the destination host, which weighs
+-- more than the destination path. Punctuation in paths is split so that
+-- "/decks/q3-review.pdf" is found by "q3" or "review".
+CREATE FUNCTION shorturls_search_vector() RETURNS trigger AS $$
+BEGIN
+  NEW.search_vector :=
+    setweight(to_tsvector('simple', coalesce(NEW.name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(NEW.title, '')), 'A') ||
+    setweight(to_tsvector('simple', array_to_string(NEW.tags, ' ')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(NEW.link_host, '')), 'C') ||
+    setweight(to_tsvector('simple', regexp_replace(
+      regexp_replace(NEW.link, '^[a-zA-Z][a-zA-Z0-9+.-]*://[^/?#]*', ''),
+      '[^[:alnum:]]+', ' ', 'g'
+    )), 'D');
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER shorturls_search_vector_trigger
+  BEFORE INSERT OR UPDATE OF name, title, tags, link, link_host ON shorturls
+  FOR EACH ROW EXECUTE FUNCTION shorturls_search_vector();
+
+-- Fires the trigger for existing rows
+UPDATE shorturls SET name = name;
+
+CREATE INDEX shorturls_search_vector_idx ON shorturls USING GIN (search_vector);
+CREATE INDEX shorturls_name_trgm_idx ON shorturls USING GIN (name gin_trgm_ops);
+CREATE INDEX shorturls_title_trgm_idx ON shorturls USING GIN (title gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS shorturls_title_trgm_idx;
+DROP INDEX IF EXISTS shorturls_name_trgm_idx;
+DROP INDEX IF EXISTS shorturls_search_vector_idx;
+DROP TRIGGER IF EXISTS shorturls_search_vector_trigger ON shorturls;
+DROP FUNCTION IF EXISTS shorturls_search_vector();
+
+ALTER TABLE shorturls
+  DROP COLUMN IF EXISTS search_vector;
+-- +goose StatementEnd
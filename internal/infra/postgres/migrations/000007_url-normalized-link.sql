@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE shorturls
+  ADD COLUMN link_normalized text;
+
+-- Best-effort backfill of shorturl.Link.Normalized(): lowercase scheme and
+-- host, no fragment, "/" for an empty path. New rows are normalized in Go.
+UPDATE shorturls
+  SET link_normalized =
+    lower(substring(link from '^[a-zA-Z][a-zA-Z0-9+.-]*://[^/?#]*')) ||
+    CASE
+      WHEN substring(link from '^[a-zA-Z][a-zA-Z0-9+.-]*://[^/?#]*(.*)$') LIKE '/%'
+        THEN regexp_replace(substring(link from '^[a-zA-Z][a-zA-Z0-9+.-]*://[^/?#]*(.*)$'), '#.*$', '')
+      ELSE '/' || regexp_replace(substring(link from '^[a-zA-Z][a-zA-Z0-9+.-]*://[^/?#]*(.*)$'), '#.*$', '')
+    END
+  WHERE link ~ '^[a-zA-Z][a-zA-Z0-9+.-]*://';
+
+UPDATE shorturls
+  SET link_normalized = link
+  WHERE link_normalized IS NULL;
+
+CREATE INDEX shorturls_link_normalized_idx ON shorturls (link_normalized text_pattern_ops, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS shorturls_link_normalized_idx;
+
+ALTER TABLE shorturls
+  DROP COLUMN IF EXISTS link_normalized;
+-- +goose StatementEnd
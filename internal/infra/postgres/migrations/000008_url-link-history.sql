@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE shorturl_link_history (
+ id BIGSERIAL PRIMARY KEY,
+ shorturl_id UUID NOT NULL REFERENCES shorturls (id) ON DELETE CASCADE,
+ old_link text NOT NULL,
+ new_link text NOT NULL,
+ reason text,
+ changed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX shorturl_link_history_shorturl_id_idx ON shorturl_link_history (shorturl_id, changed_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shorturl_link_history;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+UPDATE shorturls
+  SET updated_at = created_at
+  WHERE updated_at IS NULL;
+
+CREATE INDEX shorturls_updated_at_idx ON shorturls (updated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS shorturls_updated_at_idx;
+-- +goose StatementEnd
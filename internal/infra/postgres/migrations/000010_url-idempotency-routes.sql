@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Where each idempotency key's link lives, kept by the sharded repository
+-- in its directory database. Links are routed by name, so a key lookup
+-- first finds the name here
+CREATE TABLE shorturl_key_routes (
+ idempotency_key text PRIMARY KEY,
+ name VARCHAR(255) NOT NULL,
+ created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shorturl_key_routes;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Tells listening servers which names to drop from their caches. The
+-- payload is the name; a renamed row announces both names
+CREATE FUNCTION shorturls_notify_change() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    PERFORM pg_notify('shorturl_changes', OLD.name);
+    RETURN OLD;
+  END IF;
+
+  PERFORM pg_notify('shorturl_changes', NEW.name);
+  IF TG_OP = 'UPDATE' AND OLD.name <> NEW.name THEN
+    PERFORM pg_notify('shorturl_changes', OLD.name);
+  END IF;
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER shorturls_notify_change_trigger
+  AFTER INSERT OR UPDATE OR DELETE ON shorturls
+  FOR EACH ROW EXECUTE FUNCTION shorturls_notify_change();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS shorturls_notify_change_trigger ON shorturls;
+DROP FUNCTION IF EXISTS shorturls_notify_change();
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Lets search match typos and partial words in the destination host and
+-- path, as it already does for names and titles
+CREATE INDEX shorturls_link_normalized_trgm_idx ON shorturls USING GIN (link_normalized gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS shorturls_link_normalized_trgm_idx;
+-- +goose StatementEnd
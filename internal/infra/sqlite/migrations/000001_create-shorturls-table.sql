@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Times are Unix microseconds, the precision Postgres keeps.
+-- Tags are a JSON array and metadata a JSON object.
+CREATE TABLE shorturls (
+ id TEXT PRIMARY KEY,
+ name TEXT NOT NULL,
+ link TEXT NOT NULL,
+ link_host TEXT NOT NULL,
+ link_normalized TEXT NOT NULL,
+ idempotency_key TEXT,
+ expires_at INTEGER NOT NULL,
+ created_at INTEGER NOT NULL,
+ updated_at INTEGER NOT NULL,
+ owner TEXT,
+ title TEXT,
+ description TEXT,
+ tags TEXT NOT NULL DEFAULT '[]',
+ metadata TEXT NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX shorturls_name_idx ON shorturls (name, expires_at);
+CREATE INDEX shorturls_idempotency_key_idx ON shorturls (idempotency_key);
+CREATE INDEX shorturls_owner_idx ON shorturls (owner);
+CREATE INDEX shorturls_link_host_idx ON shorturls (link_host);
+CREATE INDEX shorturls_link_normalized_idx ON shorturls (link_normalized);
+CREATE INDEX shorturls_created_at_idx ON shorturls (created_at);
+CREATE INDEX shorturls_expires_at_idx ON shorturls (expires_at);
+CREATE INDEX shorturls_updated_at_idx ON shorturls (updated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shorturls;
+-- +goose StatementEnd
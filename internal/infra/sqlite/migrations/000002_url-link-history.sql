@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE shorturl_link_history (
+ id INTEGER PRIMARY KEY AUTOINCREMENT,
+ shorturl_id TEXT NOT NULL REFERENCES shorturls (id) ON DELETE CASCADE,
+ old_link TEXT NOT NULL,
+ new_link TEXT NOT NULL,
+ reason TEXT,
+ changed_at INTEGER NOT NULL
+);
+
+CREATE INDEX shorturl_link_history_shorturl_id_idx ON shorturl_link_history (shorturl_id, changed_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shorturl_link_history;
+-- +goose StatementEnd
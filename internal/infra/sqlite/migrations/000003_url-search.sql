@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+-- An external-content FTS5 index over the searchable columns, kept in
+-- sync by triggers like the Postgres search_vector
+CREATE VIRTUAL TABLE shorturls_fts USING fts5 (
+ name, title, description, tags, link,
+ content = 'shorturls',
+ content_rowid = 'rowid',
+ tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER shorturls_fts_insert AFTER INSERT ON shorturls BEGIN
+ INSERT INTO shorturls_fts (rowid, name, title, description, tags, link)
+ VALUES (new.rowid, new.name, new.title, new.description, new.tags, new.link);
+END;
+
+CREATE TRIGGER shorturls_fts_delete AFTER DELETE ON shorturls BEGIN
+ INSERT INTO shorturls_fts (shorturls_fts, rowid, name, title, description, tags, link)
+ VALUES ('delete', old.rowid, old.name, old.title, old.description, old.tags, old.link);
+END;
+
+CREATE TRIGGER shorturls_fts_update AFTER UPDATE ON shorturls BEGIN
+ INSERT INTO shorturls_fts (shorturls_fts, rowid, name, title, description, tags, link)
+ VALUES ('delete', old.rowid, old.name, old.title, old.description, old.tags, old.link);
+ INSERT INTO shorturls_fts (rowid, name, title, description, tags, link)
+ VALUES (new.rowid, new.name, new.title, new.description, new.tags, new.link);
+END;
+
+INSERT INTO shorturls_fts (shorturls_fts) VALUES ('rebuild');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS shorturls_fts_update;
+DROP TRIGGER IF EXISTS shorturls_fts_delete;
+DROP TRIGGER IF EXISTS shorturls_fts_insert;
+DROP TABLE IF EXISTS shorturls_fts;
+-- +goose StatementEnd
@@ -0,0 +1,53 @@
+// Package sqlite opens SQLite databases for the shorturl/sqlite repository
+// and keeps their schema migrated
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"net/url"
+	"strings"
+
+	"github.com/pressly/goose/v3"
+	_ "modernc.org/sqlite"
+)
+
+//go:embed migrations/*.sql
+var EmbedMigrations embed.FS
+
+// pragmas apply to every pooled connection. Write transactions take the
+// lock up front, so concurrent writers wait for busy_timeout instead of
+// failing when they upgrade from a read
+var pragmas = url.Values{
+	"_pragma": {"busy_timeout(5000)", "journal_mode(WAL)", "foreign_keys(1)", "synchronous(NORMAL)"},
+	"_txlock": {"immediate"},
+}
+
+// NewDatabaseConnection opens the database file at path, creating it when missing
+func NewDatabaseConnection(path string) (*sql.DB, error) {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	return sql.Open("sqlite", "file:"+path+separator+pragmas.Encode())
+}
+
+// Migrate applies every pending migration. SQLite deployments are a single
+// binary, so this runs at startup rather than from the goose CLI
+func Migrate(ctx context.Context, db *sql.DB) error {
+	migrations, subErr := fs.Sub(EmbedMigrations, "migrations")
+	if subErr != nil {
+		return subErr
+	}
+
+	provider, providerErr := goose.NewProvider(goose.DialectSQLite3, db, migrations)
+	if providerErr != nil {
+		return providerErr
+	}
+
+	_, upErr := provider.Up(ctx)
+	return upErr
+}
@@ -0,0 +1,23 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// SetupDatabase opens a migrated database in a temporary directory, removed with the test
+func SetupDatabase(ctx context.Context, t *testing.T) *sql.DB {
+	instance, instanceErr := NewDatabaseConnection(filepath.Join(t.TempDir(), "links.db"))
+	if instanceErr != nil {
+		t.Fatalf("Cannot open database: %v", instanceErr.Error())
+	}
+	t.Cleanup(func() { instance.Close() })
+
+	if err := Migrate(ctx, instance); err != nil {
+		t.Fatalf("Cannot migrate database: %v", err.Error())
+	}
+
+	return instance
+}
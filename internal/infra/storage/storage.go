@@ -0,0 +1,200 @@
+// Package storage opens the link store selected in config
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/internal/config"
+	infra_postgres "github.com/rcovery/go-url-shortener/internal/infra/postgres"
+	infra_sqlite "github.com/rcovery/go-url-shortener/internal/infra/sqlite"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
+	"github.com/rcovery/go-url-shortener/shorturl/postgres"
+	"github.com/rcovery/go-url-shortener/shorturl/sharded"
+	"github.com/rcovery/go-url-shortener/shorturl/sqlite"
+)
+
+const replicaCheckInterval = 5 * time.Second
+
+type Storage struct {
+	Repository shorturl.Repository
+	Searcher   shorturl.Searcher
+	Close      func() error
+
+	// changes are the databases announcing link changes
+	changes []string
+}
+
+// Watch evicts the links other servers change from evicter until ctx is
+// done. Only Postgres announces changes; for other stores it returns at once
+func (s Storage) Watch(ctx context.Context, evicter postgres.Evicter) {
+	var wg sync.WaitGroup
+	for _, dsn := range s.changes {
+		wg.Go(func() {
+			if listenErr := postgres.Listen(ctx, dsn, evicter); listenErr != nil {
+				log.Println("cannot listen for link changes:", listenErr)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// Open picks a backend from the scheme of dsn:
+//
+//	memory:                      links live in process memory
+//	postgres://... postgresql:// a Postgres database
+//	sqlite:path/to/links.db      a SQLite file, migrated on open
+//	sharded:                     Postgres databases listed in SHARDS
+//	empty                        Postgres, from the DB* settings
+//
+// Postgres reads go to the replicas in DBREPLICA_HOSTS, if any
+func Open(ctx context.Context, dsn string) (Storage, error) {
+	scheme, _, _ := strings.Cut(dsn, ":")
+
+	switch strings.ToLower(scheme) {
+	case "memory":
+		repo := memory.NewRepository()
+		return Storage{Repository: repo, Searcher: repo, Close: func() error { return nil }}, nil
+	case "sqlite":
+		path := strings.TrimPrefix(strings.TrimPrefix(dsn, scheme+":"), "//")
+		if path == "" {
+			return Storage{}, errors.New("sqlite storage needs a file path")
+		}
+
+		db, databaseErr := infra_sqlite.NewDatabaseConnection(path)
+		if databaseErr != nil {
+			return Storage{}, databaseErr
+		}
+		if migrateErr := infra_sqlite.Migrate(ctx, db); migrateErr != nil {
+			return Storage{}, errors.Join(migrateErr, db.Close())
+		}
+
+		repo := sqlite.NewRepository(db)
+		return Storage{Repository: repo, Searcher: repo, Close: db.Close}, nil
+	case "sharded":
+		return openSharded(config.GetString("SHARDS"), config.GetString("SHARD_INDEX"), config.GetBool("SHARD_REBALANCING"))
+	case "", "postgres", "postgresql":
+		primaryDSN, replicaDSNs := infra_postgres.GetConnectionFromEnv()
+		if dsn != "" {
+			primaryDSN = dsn
+		}
+
+		db, databaseErr := infra_postgres.NewDatabaseConnection(primaryDSN)
+		if databaseErr != nil {
+			return Storage{}, databaseErr
+		}
+
+		closers := []func() error{db.Close}
+		var replicas []*sql.DB
+		for _, replicaDSN := range replicaDSNs {
+			replica, replicaErr := infra_postgres.NewDatabaseConnection(replicaDSN)
+			if replicaErr != nil {
+				return Storage{}, replicaErr
+			}
+			replicas = append(replicas, replica)
+			closers = append(closers, replica.Close)
+		}
+
+		repo := postgres.NewRepository(db, replicas...)
+		repo.Retry = retryPolicy()
+		go repo.MonitorReplicas(ctx, replicaCheckInterval)
+
+		return Storage{Repository: repo, Searcher: repo, Close: closeAll(closers), changes: []string{primaryDSN}}, nil
+	}
+
+	return Storage{}, fmt.Errorf("unknown storage scheme %q", scheme)
+}
+
+// openSharded connects to the shards in spec, a comma-separated list of
+// name=dsn pairs. Names route links, so a shard keeps its name when its DSN
+// changes. The idempotency key index lives on indexShard, the first one by
+// default
+func openSharded(spec, indexShard string, rebalancing bool) (Storage, error) {
+	if spec == "" {
+		return Storage{}, errors.New("sharded storage needs SHARDS")
+	}
+
+	shards := map[string]sharded.Shard{}
+	var closers []func() error
+	fail := func(err error) (Storage, error) {
+		return Storage{}, errors.Join(err, closeAll(closers)())
+	}
+
+	var first *postgres.Repository
+	var index *postgres.Repository
+	var dsns []string
+	for entry := range strings.SplitSeq(spec, ",") {
+		name, dsn, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" || dsn == "" {
+			return fail(fmt.Errorf("invalid shard %q, want name=dsn", entry))
+		}
+		if _, duplicate := shards[name]; duplicate {
+			return fail(fmt.Errorf("shard %q is listed twice", name))
+		}
+
+		db, databaseErr := infra_postgres.NewDatabaseConnection(dsn)
+		if databaseErr != nil {
+			return fail(databaseErr)
+		}
+		closers = append(closers, db.Close)
+		dsns = append(dsns, dsn)
+
+		repo := postgres.NewRepository(db)
+		repo.Retry = retryPolicy()
+		shards[name] = repo
+		if first == nil {
+			first = repo
+		}
+		if name == indexShard {
+			index = repo
+		}
+	}
+
+	if indexShard == "" {
+		index = first
+	}
+	if index == nil {
+		return fail(fmt.Errorf("index shard %q is not in SHARDS", indexShard))
+	}
+
+	repo, shardedErr := sharded.NewRepository(shards, index)
+	if shardedErr != nil {
+		return fail(shardedErr)
+	}
+	repo.Rebalancing = rebalancing
+
+	return Storage{Repository: repo, Searcher: repo, Close: closeAll(closers), changes: dsns}, nil
+}
+
+// retryPolicy reads DBRETRY_ATTEMPTS, DBRETRY_BASE_DELAY and
+// DBRETRY_MAX_DELAY, each falling back to postgres.DefaultRetryPolicy
+func retryPolicy() postgres.RetryPolicy {
+	policy := postgres.DefaultRetryPolicy
+	if attempts := config.GetInt("DBRETRY_ATTEMPTS"); attempts > 0 {
+		policy.Attempts = attempts
+	}
+	if delay := config.GetDuration("DBRETRY_BASE_DELAY"); delay > 0 {
+		policy.BaseDelay = delay
+	}
+	if delay := config.GetDuration("DBRETRY_MAX_DELAY"); delay > 0 {
+		policy.MaxDelay = delay
+	}
+	return policy
+}
+
+func closeAll(closers []func() error) func() error {
+	return func() error {
+		var err error
+		for _, closer := range closers {
+			err = errors.Join(err, closer())
+		}
+		return err
+	}
+}
@@ -12,11 +12,18 @@ import (
 	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/rcovery/go-url-shortener/internal/cli"
 	"github.com/rcovery/go-url-shortener/internal/config"
 	"github.com/rcovery/go-url-shortener/internal/http/handlers"
-	infra_postgres "github.com/rcovery/go-url-shortener/internal/infra/postgres"
+	"github.com/rcovery/go-url-shortener/internal/infra/storage"
 	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/bloom"
+	"github.com/rcovery/go-url-shortener/shorturl/breaker"
+	"github.com/rcovery/go-url-shortener/shorturl/cache"
 	"github.com/rcovery/go-url-shortener/shorturl/postgres"
+	"github.com/rcovery/go-url-shortener/shorturl/rediscache"
+	"github.com/rcovery/go-url-shortener/shorturl/snapshot"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -25,10 +32,63 @@ func main() {
 	baseCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
-	connectionString := infra_postgres.GetConnectionFromEnv()
-	db, databaseErr := infra_postgres.NewDatabaseConnection(connectionString)
-	if databaseErr != nil {
-		panic(databaseErr)
+	// Followers serve redirects from a leader's snapshot, without a database
+	leader := config.GetString("FOLLOW_LEADER")
+
+	// Not ready until the first snapshot is applied or the cache is warm
+	readiness := &handlers.Readiness{}
+
+	var registerHandlers func()
+	if leader != "" {
+		store := snapshot.NewStore()
+		follower := snapshot.NewFollower(leader, followInterval(), store)
+		if syncErr := follower.Sync(baseCtx); syncErr != nil {
+			log.Fatal(syncErr)
+		}
+		log.Printf("following %s with %d links", leader, store.Len())
+		go follower.Run(baseCtx)
+		readiness.SetReady(true)
+
+		registerHandlers = func() {
+			handlers.HandleRedirect(baseCtx, store)
+		}
+	} else {
+		store, storageErr := storage.Open(baseCtx, config.GetString("STORAGE_URL"))
+		if storageErr != nil {
+			panic(storageErr)
+		}
+		defer store.Close()
+
+		repo := shared(guarded(store.Repository, readiness))
+		var evicters postgres.Evicters
+		localCache := cached(repo)
+		if localCache != nil {
+			repo = localCache
+			evicters = append(evicters, localCache)
+		}
+
+		// Subcommands run before telemetry is set up, since its exporters write to stdout
+		if len(os.Args) > 1 {
+			if cliErr := cli.Run(baseCtx, os.Args[1:], store.Repository, shorturl.NewService(repo), os.Stdin, os.Stdout); cliErr != nil {
+				log.Fatal(cliErr)
+			}
+			return
+		}
+
+		if names := filtered(baseCtx, repo); names != nil {
+			repo = names
+			evicters = append(evicters, names)
+		}
+		if len(evicters) > 0 {
+			go store.Watch(baseCtx, evicters)
+		}
+		serviceInstance := shorturl.NewService(repo)
+		go warmUp(baseCtx, localCache, readiness)
+
+		registerHandlers = func() {
+			handlers.HandleShortURL(baseCtx, serviceInstance)
+			handlers.HandleSearch(baseCtx, shorturl.NewSearchService(store.Searcher))
+		}
 	}
 
 	otelShutdown, err := config.SetupOTelSDK(baseCtx)
@@ -39,9 +99,8 @@ func main() {
 		err = errors.Join(err, otelShutdown(context.Background()))
 	}()
 
-	repoInstance := postgres.NewRepository(db)
-	serviceInstance := shorturl.NewService(repoInstance)
-	handlers.HandleShortURL(serviceInstance)
+	registerHandlers()
+	handlers.HandleHealth(baseCtx, readiness)
 	log.Println("Hello World")
 
 	host := config.GetString("HOST")
@@ -73,3 +132,121 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+func followInterval() time.Duration {
+	if interval := config.GetDuration("FOLLOW_INTERVAL"); interval > 0 {
+		return interval
+	}
+	return 10 * time.Second
+}
+
+// guarded fails calls to repo fast after BREAKER_FAILURES failures in a
+// row, for BREAKER_OPEN_FOR, and reports its state as the database check
+func guarded(repo shorturl.Repository, readiness *handlers.Readiness) shorturl.Repository {
+	guardedRepo, breakerErr := breaker.Wrap(repo, breaker.Options{
+		FailureThreshold: config.GetInt("BREAKER_FAILURES"),
+		OpenFor:          config.GetDuration("BREAKER_OPEN_FOR"),
+	})
+	if breakerErr != nil {
+		panic(breakerErr)
+	}
+
+	readiness.Report("database", func() string {
+		return guardedRepo.State().String()
+	})
+	return guardedRepo
+}
+
+// shared puts the Redis cache at REDIS_URL in front of repo, if set
+func shared(repo shorturl.Repository) shorturl.Repository {
+	rawURL := config.GetString("REDIS_URL")
+	if rawURL == "" {
+		return repo
+	}
+
+	redisOptions, parseErr := redis.ParseURL(rawURL)
+	if parseErr != nil {
+		panic(parseErr)
+	}
+	mode, modeErr := rediscache.ParseMode(config.GetString("REDIS_CACHE_MODE"))
+	if modeErr != nil {
+		panic(modeErr)
+	}
+
+	sharedRepo, cacheErr := rediscache.New(redis.NewClient(redisOptions), repo, rediscache.Options{
+		Mode:        mode,
+		TTL:         config.GetDuration("REDIS_CACHE_TTL"),
+		NegativeTTL: config.GetDuration("CACHE_NEGATIVE_TTL"),
+	})
+	if cacheErr != nil {
+		panic(cacheErr)
+	}
+	return sharedRepo
+}
+
+// cached puts an in-process cache in front of repo, unless CACHE_SIZE is 0
+func cached(repo shorturl.Repository) *cache.Repository {
+	size := config.GetInt("CACHE_SIZE")
+	if size <= 0 {
+		return nil
+	}
+
+	cachedRepo, cacheErr := cache.Wrap(repo, cache.Options{
+		Size:        size,
+		TTL:         config.GetDuration("CACHE_TTL"),
+		NegativeTTL: config.GetDuration("CACHE_NEGATIVE_TTL"),
+	})
+	if cacheErr != nil {
+		panic(cacheErr)
+	}
+	return cachedRepo
+}
+
+// filtered puts a Bloom filter of the active names in front of repo, rebuilt
+// every BLOOM_REBUILD_INTERVAL, unless that is 0
+func filtered(ctx context.Context, repo shorturl.Repository) *bloom.Repository {
+	interval := config.GetDuration("BLOOM_REBUILD_INTERVAL")
+	if interval <= 0 {
+		return nil
+	}
+
+	names, bloomErr := bloom.New(repo, bloom.Options{FalsePositiveRate: config.GetFloat64("BLOOM_FALSE_POSITIVE_RATE")})
+	if bloomErr != nil {
+		panic(bloomErr)
+	}
+
+	go func() {
+		// Lookups pass through until the first build is done
+		if rebuildErr := names.Rebuild(ctx); rebuildErr != nil {
+			log.Println("cannot build the name filter:", rebuildErr)
+		}
+		names.Run(ctx, interval)
+	}()
+	return names
+}
+
+// warmUp loads the most recent CACHE_WARM_SIZE links into the cache, then
+// reports ready. Loading stops after CACHE_WARM_TIMEOUT, since a partly
+// warm cache beats not taking traffic
+func warmUp(ctx context.Context, localCache *cache.Repository, readiness *handlers.Readiness) {
+	defer readiness.SetReady(true)
+
+	size := config.GetInt("CACHE_WARM_SIZE")
+	if localCache == nil || size <= 0 {
+		return
+	}
+
+	timeout := config.GetDuration("CACHE_WARM_TIMEOUT")
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := time.Now()
+	loaded, warmErr := localCache.Warm(ctx, size)
+	if warmErr != nil {
+		log.Println("cache warm-up stopped early:", warmErr)
+	}
+	log.Printf("warmed the cache with %d links in %s", loaded, time.Since(started).Round(time.Millisecond))
+}
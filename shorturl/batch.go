@@ -0,0 +1,257 @@
+package shorturl
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+const MaxBatchSize = 5000
+
+type BatchStatus string
+
+const (
+	BatchCreated BatchStatus = "created"
+	// BatchExisting means the idempotency key was already used, and
+	// ShortURL is the link created back then
+	BatchExisting BatchStatus = "existing"
+	BatchFailed   BatchStatus = "error"
+)
+
+// BatchResult is the outcome of the item at Index of a batch
+type BatchResult struct {
+	Index    int                 `json:"index"`
+	Status   BatchStatus         `json:"status"`
+	ShortURL *SelectableShortURL `json:"shortURL,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// CreateMany creates links in bulk, with one lookup for idempotency keys
+// and one insert for the whole batch. It returns one result per item, in
+// order; items failing validation don't prevent the others from being created
+func (s *Service) CreateMany(ctx context.Context, items []ShortURL) ([]BatchResult, error) {
+	if len(items) == 0 {
+		return nil, errs.InvalidError.New("empty batch")
+	}
+	if len(items) > MaxBatchSize {
+		return nil, errs.InvalidError.New(fmt.Sprintf("a batch holds at most %d links", MaxBatchSize))
+	}
+
+	items = slices.Clone(items)
+	results := make([]BatchResult, len(items))
+	pending := make([]int, 0, len(items))
+	names := make(map[string]bool, len(items))
+	keys := make(map[IdempotencyKey]bool, len(items))
+	ids := make(map[ID]bool, len(items))
+	clientIDs := make(map[int]bool, len(items))
+
+	for i, item := range items {
+		results[i] = BatchResult{Index: i, Status: BatchFailed}
+
+		item.Name = strings.TrimSpace(item.Name)
+		if item.ID != "" {
+			id, idErr := ParseID(string(item.ID))
+			if idErr != nil {
+				results[i].Error = "invalid_id"
+				continue
+			}
+			item.ID = id
+		}
+
+		switch {
+		case item.Name == "":
+			results[i].Error = "invalid_name"
+			continue
+		case item.Link == nil || item.Link.Hostname() == "":
+			results[i].Error = "invalid_link"
+			continue
+		case names[item.Name] || (item.IdempotencyKey != "" && keys[item.IdempotencyKey]):
+			results[i].Error = "duplicate_in_batch"
+			continue
+		case item.ID != "" && ids[item.ID]:
+			// Renaming won't help, unlike the duplicates above
+			results[i].Error = "duplicate_id"
+			continue
+		}
+
+		clientIDs[i] = item.ID != ""
+		if item.ID == "" {
+			id, idErr := NewID()
+			if idErr != nil {
+				return nil, idErr
+			}
+			item.ID = id
+		}
+		if item.IdempotencyKey == "" {
+			idempotencyKey, keyErr := NewIdempotencyKey()
+			if keyErr != nil {
+				return nil, keyErr
+			}
+			item.IdempotencyKey = idempotencyKey
+		}
+		item.Details = item.Details.Normalize()
+
+		names[item.Name] = true
+		keys[item.IdempotencyKey] = true
+		ids[item.ID] = true
+		items[i] = item
+		pending = append(pending, i)
+	}
+
+	lookupKeys := make([]IdempotencyKey, 0, len(pending))
+	for _, i := range pending {
+		lookupKeys = append(lookupKeys, items[i].IdempotencyKey)
+	}
+	existing, lookupErr := s.repo.SelectByIdempotencyKeys(ctx, lookupKeys)
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+	existingByKey := make(map[IdempotencyKey]SelectableShortURL, len(existing))
+	for _, surl := range existing {
+		existingByKey[surl.IdempotencyKey] = surl
+	}
+
+	toInsert := make([]ShortURL, 0, len(pending))
+	insertIndexes := make(map[ID]int, len(pending))
+	for _, i := range pending {
+		if surl, found := existingByKey[items[i].IdempotencyKey]; found {
+			results[i].Status = BatchExisting
+			results[i].ShortURL = &surl
+			continue
+		}
+
+		toInsert = append(toInsert, items[i])
+		insertIndexes[items[i].ID] = i
+	}
+	if len(toInsert) == 0 {
+		return results, nil
+	}
+
+	insertedIDs, insertErr := s.repo.InsertMany(ctx, toInsert)
+	if insertErr != nil {
+		return nil, insertErr
+	}
+
+	for _, id := range insertedIDs {
+		i := insertIndexes[id]
+		delete(insertIndexes, id)
+
+		item := items[i]
+		results[i].Status = BatchCreated
+		results[i].ShortURL = &SelectableShortURL{
+			ID:             item.ID,
+			Name:           item.Name,
+			Link:           item.Link,
+			IdempotencyKey: item.IdempotencyKey,
+			ExpiresAt:      item.ExpiresAt,
+			Details:        item.Details,
+		}
+	}
+	if len(insertIndexes) == 0 {
+		return results, nil
+	}
+
+	// A skipped item either lost its name or reused a stored ID. Only
+	// client IDs can collide, so those without an active link by their
+	// name are reported as duplicates
+	skippedNames := make([]string, 0, len(insertIndexes))
+	for _, i := range insertIndexes {
+		skippedNames = append(skippedNames, items[i].Name)
+	}
+	holders, holdersErr := s.repo.SelectByNames(ctx, skippedNames)
+	if holdersErr != nil {
+		return nil, holdersErr
+	}
+	taken := make(map[string]bool, len(holders))
+	now := time.Now()
+	for _, surl := range holders {
+		if surl.ExpiresAt.After(now) {
+			taken[surl.Name] = true
+		}
+	}
+	for _, i := range insertIndexes {
+		if clientIDs[i] && !taken[items[i].Name] {
+			results[i].Error = "duplicate_id"
+			continue
+		}
+		results[i].Error = "name_taken"
+	}
+
+	return results, nil
+}
+
+// Overwrite points the active links named like surls to their new
+// destinations, recording reason in the link history. It returns the
+// names that now point at their new destination; names without an
+// active link, or whose link changed concurrently, are left out
+func (s *Service) Overwrite(ctx context.Context, surls []ShortURL, reason string) ([]string, error) {
+	names := make([]string, len(surls))
+	for i, surl := range surls {
+		names[i] = surl.Name
+	}
+
+	found, selectErr := s.repo.SelectByNames(ctx, names)
+	if selectErr != nil {
+		return nil, selectErr
+	}
+	current := make(map[string]SelectableShortURL, len(found))
+	now := time.Now()
+	for _, surl := range found {
+		if surl.ExpiresAt.After(now) {
+			current[surl.Name] = surl
+		}
+	}
+
+	var overwritten []string
+	changes := make([]LinkChange, 0, len(surls))
+	for _, surl := range surls {
+		existing, ok := current[surl.Name]
+		if !ok {
+			continue
+		}
+		if existing.Link.Equals(surl.Link) {
+			overwritten = append(overwritten, surl.Name)
+			continue
+		}
+		changes = append(changes, LinkChange{ID: existing.ID, Name: existing.Name, From: existing.Link, To: surl.Link})
+	}
+	if len(changes) == 0 {
+		return overwritten, nil
+	}
+
+	applied, updateErr := s.repo.UpdateLinks(ctx, changes, reason)
+	if updateErr != nil {
+		return nil, updateErr
+	}
+	if applied == len(changes) {
+		for _, change := range changes {
+			overwritten = append(overwritten, change.Name)
+		}
+		return overwritten, nil
+	}
+
+	// Some links changed in between; find out which ones took the new destination
+	changedNames := make([]string, len(changes))
+	for i, change := range changes {
+		changedNames[i] = change.Name
+	}
+	after, selectErr := s.repo.SelectByNames(ctx, changedNames)
+	if selectErr != nil {
+		return nil, selectErr
+	}
+	wanted := make(map[string]*Link, len(changes))
+	for _, change := range changes {
+		wanted[change.Name] = change.To
+	}
+	for _, surl := range after {
+		if surl.Link.Equals(wanted[surl.Name]) {
+			overwritten = append(overwritten, surl.Name)
+		}
+	}
+
+	return overwritten, nil
+}
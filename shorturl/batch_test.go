@@ -0,0 +1,157 @@
+package shorturl_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
+)
+
+func TestCreateMany(t *testing.T) {
+	t.Run("should report a result per item", func(t *testing.T) {
+		ctx := context.Background()
+		repo := memory.NewRepository()
+		service := shorturl.NewService(repo)
+
+		existingID, _ := shorturl.NewID()
+		existingKey, _ := shorturl.NewIdempotencyKey()
+		existingLink, _ := shorturl.NewLink("https://example.com/existing")
+		_, createErr := service.Create(ctx, existingID, existingKey, "taken", existingLink, shorturl.Details{})
+		if createErr != nil {
+			t.Fatalf("Create failed unexpectedly: %v", createErr)
+		}
+
+		link, _ := shorturl.NewLink("https://example.com/new")
+		items := []shorturl.ShortURL{
+			{Name: "fresh", Link: link},
+			{Name: "taken", Link: link},
+			{Name: "replayed", Link: link, IdempotencyKey: existingKey},
+			{Name: "fresh", Link: link},
+			{Name: "", Link: link},
+		}
+
+		results, err := service.CreateMany(ctx, items)
+		if err != nil {
+			t.Fatalf("CreateMany failed unexpectedly: %v", err)
+		}
+
+		want := []struct {
+			status shorturl.BatchStatus
+			err    string
+		}{
+			{shorturl.BatchCreated, ""},
+			{shorturl.BatchFailed, "name_taken"},
+			{shorturl.BatchExisting, ""},
+			{shorturl.BatchFailed, "duplicate_in_batch"},
+			{shorturl.BatchFailed, "invalid_name"},
+		}
+		for i, w := range want {
+			if results[i].Status != w.status || results[i].Error != w.err {
+				t.Errorf("item %d: want %s %q, got %s %q", i, w.status, w.err, results[i].Status, results[i].Error)
+			}
+		}
+
+		if results[2].ShortURL == nil || results[2].ShortURL.ID != existingID {
+			t.Errorf("want the existing link for a reused idempotency key, got %v", results[2].ShortURL)
+		}
+
+		found, selectErr := service.Select(ctx, "fresh")
+		if selectErr != nil {
+			t.Fatalf("cannot select a link created in batch: %v", selectErr)
+		}
+		if !found.Equals(link) {
+			t.Errorf("want %q, got %q", link, found)
+		}
+	})
+
+	t.Run("should report a reused ID as a duplicate", func(t *testing.T) {
+		service := shorturl.NewService(memory.NewRepository())
+
+		id, _ := shorturl.NewID()
+		link, _ := shorturl.NewLink("https://example.com/new")
+		results, err := service.CreateMany(context.Background(), []shorturl.ShortURL{
+			{ID: id, Name: "first", Link: link},
+			{ID: id, Name: "second", Link: link},
+		})
+		if err != nil {
+			t.Fatalf("CreateMany failed unexpectedly: %v", err)
+		}
+
+		if results[0].Status != shorturl.BatchCreated {
+			t.Errorf("want the first item created, got %s %q", results[0].Status, results[0].Error)
+		}
+		if results[1].Status != shorturl.BatchFailed || results[1].Error != "duplicate_id" {
+			t.Errorf("want the second item refused as a duplicate, got %s %q", results[1].Status, results[1].Error)
+		}
+	})
+
+	t.Run("should refuse an ID that is not a UUID", func(t *testing.T) {
+		service := shorturl.NewService(memory.NewRepository())
+
+		link, _ := shorturl.NewLink("https://example.com/new")
+		results, err := service.CreateMany(context.Background(), []shorturl.ShortURL{
+			{ID: "not-a-uuid", Name: "first", Link: link},
+			{Name: "second", Link: link},
+		})
+		if err != nil {
+			t.Fatalf("CreateMany failed unexpectedly: %v", err)
+		}
+
+		if results[0].Status != shorturl.BatchFailed || results[0].Error != "invalid_id" {
+			t.Errorf("want the first item refused as invalid, got %s %q", results[0].Status, results[0].Error)
+		}
+		if results[1].Status != shorturl.BatchCreated {
+			t.Errorf("want the second item created, got %s %q", results[1].Status, results[1].Error)
+		}
+	})
+
+	t.Run("should create an uppercase ID in its canonical form", func(t *testing.T) {
+		service := shorturl.NewService(memory.NewRepository())
+
+		id, _ := shorturl.NewID()
+		link, _ := shorturl.NewLink("https://example.com/new")
+		results, err := service.CreateMany(context.Background(), []shorturl.ShortURL{
+			{ID: shorturl.ID(strings.ToUpper(string(id))), Name: "upper", Link: link},
+		})
+		if err != nil {
+			t.Fatalf("CreateMany failed unexpectedly: %v", err)
+		}
+
+		if results[0].Status != shorturl.BatchCreated || results[0].ShortURL.ID != id {
+			t.Errorf("want %q created, got %s %q %+v", id, results[0].Status, results[0].Error, results[0].ShortURL)
+		}
+	})
+
+	t.Run("should report a stored ID as a duplicate", func(t *testing.T) {
+		ctx := context.Background()
+		service := shorturl.NewService(memory.NewRepository())
+
+		id, _ := shorturl.NewID()
+		key, _ := shorturl.NewIdempotencyKey()
+		link, _ := shorturl.NewLink("https://example.com/new")
+		if _, createErr := service.Create(ctx, id, key, "stored", link, shorturl.Details{}); createErr != nil {
+			t.Fatalf("Create failed unexpectedly: %v", createErr)
+		}
+
+		results, err := service.CreateMany(ctx, []shorturl.ShortURL{
+			{ID: id, Name: "reused", Link: link},
+			{Name: "stored", Link: link},
+			{Name: "other", Link: link},
+		})
+		if err != nil {
+			t.Fatalf("CreateMany failed unexpectedly: %v", err)
+		}
+
+		if results[0].Status != shorturl.BatchFailed || results[0].Error != "duplicate_id" {
+			t.Errorf("want the stored ID refused as a duplicate, got %s %q", results[0].Status, results[0].Error)
+		}
+		if results[1].Status != shorturl.BatchFailed || results[1].Error != "name_taken" {
+			t.Errorf("want the stored name refused as taken, got %s %q", results[1].Status, results[1].Error)
+		}
+		if results[2].Status != shorturl.BatchCreated {
+			t.Errorf("want the other item created, got %s %q", results[2].Status, results[2].Error)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+// Package bloom answers lookups of names that were never created without
+// reaching the repository, using a Bloom filter of the active names
+package bloom
+
+import (
+	"hash/maphash"
+	"math"
+	"sync/atomic"
+)
+
+// Filter is a Bloom filter of strings, safe for concurrent use. It never
+// reports an added string as missing, and reports a missing one as
+// present with about the false positive rate it was sized for
+type Filter struct {
+	words  []atomic.Uint64
+	bits   uint64
+	hashes int
+	seed   maphash.Seed
+	count  atomic.Int64
+}
+
+// NewFilter sizes a filter for capacity strings at falsePositiveRate.
+// Past capacity, false positives grow more frequent
+func NewFilter(capacity int, falsePositiveRate float64) *Filter {
+	capacity = max(capacity, 1)
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = DefaultFalsePositiveRate
+	}
+
+	bits := math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	hashes := int(math.Round(bits / float64(capacity) * math.Ln2))
+
+	words := (uint64(bits) + 63) / 64
+	return &Filter{
+		words:  make([]atomic.Uint64, words),
+		bits:   words * 64,
+		hashes: max(hashes, 1),
+		seed:   maphash.MakeSeed(),
+	}
+}
+
+// locations derives the filter's hash functions from two halves of one
+// 64-bit hash, as in Kirsch and Mitzenmacher's double hashing
+func (f *Filter) locations(s string) (uint64, uint64) {
+	h := maphash.String(f.seed, s)
+	return h & math.MaxUint32, h>>32 | 1
+}
+
+func (f *Filter) Add(s string) {
+	a, b := f.locations(s)
+	for i := range uint64(f.hashes) {
+		bit := (a + i*b) % f.bits
+		f.words[bit/64].Or(1 << (bit % 64))
+	}
+	f.count.Add(1)
+}
+
+// MayContain reports false only for strings that were never added
+func (f *Filter) MayContain(s string) bool {
+	a, b := f.locations(s)
+	for i := range uint64(f.hashes) {
+		bit := (a + i*b) % f.bits
+		if f.words[bit/64].Load()&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Len is how many strings were added, counting repeats
+func (f *Filter) Len() int {
+	return int(f.count.Load())
+}
@@ -0,0 +1,37 @@
+package bloom_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/shorturl/bloom"
+)
+
+func TestFilter(t *testing.T) {
+	filter := bloom.NewFilter(10000, 0.01)
+	for i := range 10000 {
+		filter.Add(fmt.Sprintf("added-%d", i))
+	}
+
+	t.Run("should find every added string", func(t *testing.T) {
+		for i := range 10000 {
+			if name := fmt.Sprintf("added-%d", i); !filter.MayContain(name) {
+				t.Fatalf("want %q found", name)
+			}
+		}
+	})
+
+	t.Run("should rarely find strings never added", func(t *testing.T) {
+		falsePositives := 0
+		for i := range 10000 {
+			if filter.MayContain(fmt.Sprintf("missing-%d", i)) {
+				falsePositives++
+			}
+		}
+
+		// 1% expected, with room for chance
+		if falsePositives > 200 {
+			t.Errorf("want about 100 false positives, got %d", falsePositives)
+		}
+	})
+}
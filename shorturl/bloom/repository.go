@@ -0,0 +1,221 @@
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+const (
+	DefaultFalsePositiveRate = 0.01
+	// DefaultCapacity sizes the first filter, before the names are counted
+	DefaultCapacity = 100000
+
+	rebuildPageSize = shorturl.MaxListLimit
+)
+
+type Options struct {
+	FalsePositiveRate float64
+	// Capacity sizes the first filter. Rebuilds size theirs from the
+	// previous count, with room to grow
+	Capacity int
+
+	// Meter records how lookups were answered, the global meter provider's by default
+	Meter metric.Meter
+}
+
+// Repository answers SelectByName with not found, without asking the
+// repository, for names its filter has never seen. Until the first Rebuild
+// finishes, and after Distrust, every lookup passes through.
+//
+// Names created by other servers only reach the filter through Invalidate
+// or a Rebuild, so servers sharing a store without change notifications
+// should rebuild often
+type Repository struct {
+	shorturl.Repository
+	options Options
+
+	mu sync.Mutex
+	// current is nil while it can't be trusted
+	current *Filter
+	// next is the filter a Rebuild is filling, which also gets new names
+	next *Filter
+	// purges counts Purge calls, so a Rebuild running across one isn't trusted
+	purges int
+
+	lookups metric.Int64Counter
+}
+
+// Built once, so counting doesn't allocate
+var (
+	missAttributes  = []metric.AddOption{metric.WithAttributes(attribute.String("result", "definite_miss"))}
+	maybeAttributes = []metric.AddOption{metric.WithAttributes(attribute.String("result", "maybe"))}
+	skipAttributes  = []metric.AddOption{metric.WithAttributes(attribute.String("result", "not_ready"))}
+)
+
+func New(repo shorturl.Repository, options Options) (*Repository, error) {
+	if options.FalsePositiveRate <= 0 || options.FalsePositiveRate >= 1 {
+		options.FalsePositiveRate = DefaultFalsePositiveRate
+	}
+	if options.Capacity <= 0 {
+		options.Capacity = DefaultCapacity
+	}
+	if options.Meter == nil {
+		options.Meter = otel.Meter("github.com/rcovery/go-url-shortener/shorturl/bloom")
+	}
+
+	lookups, counterErr := options.Meter.Int64Counter(
+		"shorturl.bloom.lookups",
+		metric.WithDescription("Name lookups checked against the Bloom filter, by result"),
+	)
+	if counterErr != nil {
+		return nil, counterErr
+	}
+
+	return &Repository{Repository: repo, options: options, lookups: lookups}, nil
+}
+
+func (r *Repository) filters() (*Filter, *Filter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current, r.next
+}
+
+func (r *Repository) SelectByName(ctx context.Context, name string) (shorturl.SelectableShortURL, error) {
+	current, _ := r.filters()
+	switch {
+	case current == nil:
+		r.lookups.Add(ctx, 1, skipAttributes...)
+	case !current.MayContain(name):
+		r.lookups.Add(ctx, 1, missAttributes...)
+		return shorturl.SelectableShortURL{}, errs.NotFoundError.New(fmt.Sprintf("ByName: %q", name))
+	default:
+		r.lookups.Add(ctx, 1, maybeAttributes...)
+	}
+
+	return r.Repository.SelectByName(ctx, name)
+}
+
+// CachedLocation answers names never created from the filter, and asks
+// the repository's memory about the others
+func (r *Repository) CachedLocation(name string) (shorturl.Location, bool) {
+	current, _ := r.filters()
+	if current != nil && !current.MayContain(name) {
+		r.lookups.Add(context.Background(), 1, missAttributes...)
+		return nil, true
+	}
+
+	cache, isCache := r.Repository.(shorturl.LocationCache)
+	if !isCache {
+		return nil, false
+	}
+	return cache.CachedLocation(name)
+}
+
+// add records names before they are written, so they are never missing
+// from the filter once readable
+func (r *Repository) add(names ...string) {
+	current, next := r.filters()
+	for _, name := range names {
+		if current != nil {
+			current.Add(name)
+		}
+		if next != nil {
+			next.Add(name)
+		}
+	}
+}
+
+func (r *Repository) Insert(ctx context.Context, surl shorturl.ShortURL) error {
+	r.add(surl.Name)
+	return r.Repository.Insert(ctx, surl)
+}
+
+func (r *Repository) InsertMany(ctx context.Context, surls []shorturl.ShortURL) ([]shorturl.ID, error) {
+	names := make([]string, len(surls))
+	for i, surl := range surls {
+		names[i] = surl.Name
+	}
+	r.add(names...)
+
+	return r.Repository.InsertMany(ctx, surls)
+}
+
+// Invalidate adds a name another server changed, which may be new
+func (r *Repository) Invalidate(name string) {
+	r.add(name)
+}
+
+// Purge stops trusting the filter until the next Rebuild, for when names
+// created elsewhere may have been missed
+func (r *Repository) Purge() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.current = nil
+	r.purges++
+}
+
+// Rebuild fills a new filter with the active names and swaps it in, which
+// also drops names no longer in use. Names inserted meanwhile go to both
+func (r *Repository) Rebuild(ctx context.Context) error {
+	r.mu.Lock()
+	if r.next != nil {
+		r.mu.Unlock()
+		return errs.InvalidError.New("a rebuild is already running")
+	}
+	capacity := r.options.Capacity
+	if r.current != nil {
+		capacity = max(capacity, 2*r.current.Len())
+	}
+	next := NewFilter(capacity, r.options.FalsePositiveRate)
+	r.next = next
+	purges := r.purges
+	r.mu.Unlock()
+
+	filter := shorturl.ListFilter{Sort: shorturl.SortCreated, ExpiresAfter: time.Now(), Limit: rebuildPageSize}
+	walkErr := shorturl.NewService(r.Repository).Walk(ctx, filter, func(surl shorturl.SelectableShortURL) error {
+		next.Add(surl.Name)
+		return nil
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.next = nil
+	if walkErr != nil {
+		return walkErr
+	}
+	if r.purges != purges {
+		return errs.InvalidError.New("names may have been missed during the rebuild")
+	}
+	r.current = next
+	return nil
+}
+
+// Run rebuilds the filter every interval until ctx is done
+func (r *Repository) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if rebuildErr := r.Rebuild(ctx); rebuildErr != nil {
+				log.Println("cannot rebuild the name filter:", rebuildErr)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package bloom_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/bloom"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+	"github.com/rcovery/go-url-shortener/shorturl/repotest"
+)
+
+func newGuarded(t *testing.T) (*bloom.Repository, *repotest.CountingRepository) {
+	t.Helper()
+
+	next := repotest.NewCountingRepository()
+	repo, err := bloom.New(next, bloom.Options{Capacity: 1000})
+	if err != nil {
+		t.Fatalf("cannot build the filter: %v", err)
+	}
+	return repo, next
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should answer names never created without a lookup", func(t *testing.T) {
+		repo, next := newGuarded(t)
+		repotest.Insert(t, next, repotest.NewShortURL(t, "existing", time.Now().Add(time.Hour)))
+		if err := repo.Rebuild(ctx); err != nil {
+			t.Fatalf("Rebuild failed unexpectedly: %v", err)
+		}
+
+		if _, err := repo.SelectByName(ctx, "random-probe"); !errors.Is(err, errs.NotFoundError) {
+			t.Errorf("want not found, got %v", err)
+		}
+		if _, err := repo.SelectByName(ctx, "existing"); err != nil {
+			t.Errorf("want the existing link, got %v", err)
+		}
+		if next.Lookups.Load() != 1 {
+			t.Errorf("want only the existing name looked up, got %d lookups", next.Lookups.Load())
+		}
+	})
+
+	t.Run("should pass every lookup through before the first rebuild", func(t *testing.T) {
+		repo, next := newGuarded(t)
+		repotest.Insert(t, next, repotest.NewShortURL(t, "existing", time.Now().Add(time.Hour)))
+
+		if _, err := repo.SelectByName(ctx, "existing"); err != nil {
+			t.Errorf("want the existing link, got %v", err)
+		}
+	})
+
+	t.Run("should find names inserted after a rebuild", func(t *testing.T) {
+		repo, _ := newGuarded(t)
+		repo.Rebuild(ctx)
+
+		repotest.Insert(t, repo, repotest.NewShortURL(t, "fresh", time.Now().Add(time.Hour)))
+		if _, err := repo.SelectByName(ctx, "fresh"); err != nil {
+			t.Errorf("want the new link, got %v", err)
+		}
+	})
+
+	t.Run("should find names created by other servers once announced", func(t *testing.T) {
+		repo, next := newGuarded(t)
+		repo.Rebuild(ctx)
+
+		repotest.Insert(t, next, repotest.NewShortURL(t, "elsewhere", time.Now().Add(time.Hour)))
+		repo.Invalidate("elsewhere")
+		if _, err := repo.SelectByName(ctx, "elsewhere"); err != nil {
+			t.Errorf("want the announced link, got %v", err)
+		}
+	})
+
+	t.Run("should pass lookups through after a purge", func(t *testing.T) {
+		repo, next := newGuarded(t)
+		repo.Rebuild(ctx)
+
+		repotest.Insert(t, next, repotest.NewShortURL(t, "missed", time.Now().Add(time.Hour)))
+		repo.Purge()
+		if _, err := repo.SelectByName(ctx, "missed"); err != nil {
+			t.Errorf("want the link, got %v", err)
+		}
+	})
+
+	t.Run("should drop deleted and expired names on rebuild", func(t *testing.T) {
+		repo, next := newGuarded(t)
+		deleted := repotest.NewShortURL(t, "deleted", time.Now().Add(time.Hour))
+		repotest.Insert(t, next, deleted)
+		repotest.Insert(t, next, repotest.NewShortURL(t, "expired", time.Now().Add(-time.Hour)))
+		repo.Rebuild(ctx)
+
+		next.DeleteByIDs(ctx, []shorturl.ID{deleted.ID})
+		repo.Rebuild(ctx)
+
+		next.Lookups.Store(0)
+		repo.SelectByName(ctx, "deleted")
+		repo.SelectByName(ctx, "expired")
+		if next.Lookups.Load() != 0 {
+			t.Errorf("want no lookups for names gone before the rebuild, got %d", next.Lookups.Load())
+		}
+	})
+}
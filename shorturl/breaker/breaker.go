@@ -0,0 +1,174 @@
+// Package breaker stops calling a failing repository for a while, so
+// requests fail fast instead of each waiting for its timeout
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+const (
+	DefaultFailureThreshold = 5
+	DefaultOpenFor          = 5 * time.Second
+)
+
+type State int
+
+const (
+	// Closed lets every call through
+	Closed State = iota
+	// HalfOpen lets one trial call through, which decides the next state
+	HalfOpen
+	// Open fails every call without trying
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half_open"
+	case Open:
+		return "open"
+	}
+	return "unknown"
+}
+
+type Options struct {
+	// FailureThreshold is how many failures in a row open the breaker
+	FailureThreshold int
+	// OpenFor is how long the breaker stays open before a trial call
+	OpenFor time.Duration
+
+	// Now is the clock, time.Now by default
+	Now func() time.Time
+	// Meter records the state and rejected calls of a wrapped repository,
+	// the global meter provider's by default
+	Meter metric.Meter
+}
+
+// Breaker counts failures in a row, and opens after too many
+type Breaker struct {
+	options Options
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	// trial is true while the half-open trial call runs
+	trial bool
+
+	rejected atomic.Int64
+}
+
+func New(options Options) *Breaker {
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = DefaultFailureThreshold
+	}
+	if options.OpenFor <= 0 {
+		options.OpenFor = DefaultOpenFor
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	return &Breaker{options: options}
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// Rejected returns how many calls failed fast while the breaker was open
+func (b *Breaker) Rejected() int64 {
+	return b.rejected.Load()
+}
+
+// allow reports whether a call may go through, and whether it is the trial
+func (b *Breaker) allow() (bool, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		if b.options.Now().Sub(b.openedAt) < b.options.OpenFor {
+			return false, false
+		}
+		b.state = HalfOpen
+		fallthrough
+	case HalfOpen:
+		if b.trial {
+			return false, false
+		}
+		b.trial = true
+		return true, true
+	}
+	return true, false
+}
+
+func (b *Breaker) record(err error, trial bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		b.trial = false
+	}
+	// A caller giving up says nothing about the repository
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if !Failure(err) {
+		if trial || b.state == Closed {
+			b.state = Closed
+			b.failures = 0
+		}
+		return
+	}
+
+	b.failures++
+	if trial || (b.state == Closed && b.failures >= b.options.FailureThreshold) {
+		b.state = Open
+		b.openedAt = b.options.Now()
+	}
+}
+
+// Failure reports whether err says the repository is unwell, rather than
+// the caller asking for something missing or invalid, or giving up
+func Failure(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, errs.NotFoundError),
+		errors.Is(err, errs.InvalidError),
+		errors.Is(err, errs.AlreadyExistsError),
+		errors.Is(err, context.Canceled):
+		return false
+	}
+	return true
+}
+
+// Do runs fn unless the breaker is open, in which case it returns an
+// errs.UnavailableError right away
+func Do[T any](b *Breaker, fn func() (T, error)) (T, error) {
+	allowed, trial := b.allow()
+	if !allowed {
+		b.rejected.Add(1)
+		var zero T
+		return zero, errs.UnavailableError.New("circuit breaker is open")
+	}
+
+	result, err := fn()
+	b.record(err, trial)
+	return result, err
+}
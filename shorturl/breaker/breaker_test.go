@@ -0,0 +1,127 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl/breaker"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+var errDown = errors.New("connection refused")
+
+func call(b *breaker.Breaker, err error) error {
+	_, callErr := breaker.Do(b, func() (struct{}, error) {
+		return struct{}{}, err
+	})
+	return callErr
+}
+
+func TestBreaker(t *testing.T) {
+	newBreaker := func() (*breaker.Breaker, *clock) {
+		clock := &clock{now: time.Now()}
+		return breaker.New(breaker.Options{FailureThreshold: 3, OpenFor: time.Second, Now: clock.Now}), clock
+	}
+
+	t.Run("should open after failures in a row and fail fast", func(t *testing.T) {
+		b, _ := newBreaker()
+		for range 3 {
+			call(b, errDown)
+		}
+		if b.State() != breaker.Open {
+			t.Fatalf("want %s, got %s", breaker.Open, b.State())
+		}
+
+		ran := false
+		_, err := breaker.Do(b, func() (struct{}, error) {
+			ran = true
+			return struct{}{}, nil
+		})
+		if ran || !errors.Is(err, errs.UnavailableError) {
+			t.Errorf("want an unavailable error without calling, got %v (called: %t)", err, ran)
+		}
+		if b.Rejected() != 1 {
+			t.Errorf("want 1 rejected call, got %d", b.Rejected())
+		}
+	})
+
+	t.Run("should not count answers about the request as failures", func(t *testing.T) {
+		b, _ := newBreaker()
+		for _, err := range []error{
+			errs.NotFoundError.New("missing"),
+			errs.InvalidError.New("bad"),
+			errs.AlreadyExistsError.New("taken"),
+			context.Canceled,
+		} {
+			call(b, err)
+			call(b, err)
+			call(b, err)
+		}
+		if b.State() != breaker.Closed {
+			t.Errorf("want %s, got %s", breaker.Closed, b.State())
+		}
+	})
+
+	t.Run("should reset the count on success", func(t *testing.T) {
+		b, _ := newBreaker()
+		call(b, errDown)
+		call(b, errDown)
+		call(b, nil)
+		call(b, errDown)
+		if b.State() != breaker.Closed {
+			t.Errorf("want %s, got %s", breaker.Closed, b.State())
+		}
+	})
+
+	t.Run("should close after a successful trial", func(t *testing.T) {
+		b, clock := newBreaker()
+		for range 3 {
+			call(b, errDown)
+		}
+
+		clock.now = clock.now.Add(2 * time.Second)
+		if err := call(b, nil); err != nil {
+			t.Fatalf("want the trial to run, got %v", err)
+		}
+		if b.State() != breaker.Closed {
+			t.Errorf("want %s, got %s", breaker.Closed, b.State())
+		}
+	})
+
+	t.Run("should reopen after a failed trial", func(t *testing.T) {
+		b, clock := newBreaker()
+		for range 3 {
+			call(b, errDown)
+		}
+
+		clock.now = clock.now.Add(2 * time.Second)
+		call(b, errDown)
+		if b.State() != breaker.Open {
+			t.Errorf("want %s, got %s", breaker.Open, b.State())
+		}
+		if err := call(b, nil); !errors.Is(err, errs.UnavailableError) {
+			t.Errorf("want calls to fail fast again, got %v", err)
+		}
+	})
+
+	t.Run("should let a single trial through at a time", func(t *testing.T) {
+		b, clock := newBreaker()
+		for range 3 {
+			call(b, errDown)
+		}
+		clock.now = clock.now.Add(2 * time.Second)
+
+		breaker.Do(b, func() (struct{}, error) {
+			if err := call(b, nil); !errors.Is(err, errs.UnavailableError) {
+				t.Errorf("want a second call during the trial to fail fast, got %v", err)
+			}
+			return struct{}{}, nil
+		})
+	})
+}
@@ -0,0 +1,109 @@
+package breaker
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+// Repository sends every call through a Breaker. Calls made while it is
+// open fail with errs.UnavailableError, which caches answer with the
+// links they still hold
+type Repository struct {
+	*Breaker
+	repo shorturl.Repository
+}
+
+// Wrap records the breaker's state as the shorturl.breaker.state gauge,
+// 0 closed, 1 half open and 2 open, and the calls it failed fast as
+// shorturl.breaker.rejected
+func Wrap(repo shorturl.Repository, options Options) (*Repository, error) {
+	if options.Meter == nil {
+		options.Meter = otel.Meter("github.com/rcovery/go-url-shortener/shorturl/breaker")
+	}
+
+	wrapped := &Repository{Breaker: New(options), repo: repo}
+	_, gaugeErr := options.Meter.Int64ObservableGauge(
+		"shorturl.breaker.state",
+		metric.WithDescription("State of the repository circuit breaker: 0 closed, 1 half open, 2 open"),
+		metric.WithInt64Callback(func(ctx context.Context, observer metric.Int64Observer) error {
+			observer.Observe(int64(wrapped.State()))
+			return nil
+		}),
+	)
+	if gaugeErr != nil {
+		return nil, gaugeErr
+	}
+
+	_, counterErr := options.Meter.Int64ObservableCounter(
+		"shorturl.breaker.rejected",
+		metric.WithDescription("Repository calls failed fast while the circuit breaker was open"),
+		metric.WithInt64Callback(func(ctx context.Context, observer metric.Int64Observer) error {
+			observer.Observe(wrapped.Rejected())
+			return nil
+		}),
+	)
+	if counterErr != nil {
+		return nil, counterErr
+	}
+
+	return wrapped, nil
+}
+
+func (r *Repository) SelectByName(ctx context.Context, name string) (shorturl.SelectableShortURL, error) {
+	return Do(r.Breaker, func() (shorturl.SelectableShortURL, error) {
+		return r.repo.SelectByName(ctx, name)
+	})
+}
+
+func (r *Repository) SelectByIdempotencyKey(ctx context.Context, idempotencyKey shorturl.IdempotencyKey) (shorturl.SelectableShortURL, error) {
+	return Do(r.Breaker, func() (shorturl.SelectableShortURL, error) {
+		return r.repo.SelectByIdempotencyKey(ctx, idempotencyKey)
+	})
+}
+
+func (r *Repository) List(ctx context.Context, filter shorturl.ListFilter) ([]shorturl.SelectableShortURL, error) {
+	return Do(r.Breaker, func() ([]shorturl.SelectableShortURL, error) {
+		return r.repo.List(ctx, filter)
+	})
+}
+
+func (r *Repository) SelectByDestination(ctx context.Context, query shorturl.DestinationQuery) ([]shorturl.SelectableShortURL, error) {
+	return Do(r.Breaker, func() ([]shorturl.SelectableShortURL, error) {
+		return r.repo.SelectByDestination(ctx, query)
+	})
+}
+
+func (r *Repository) SelectByIdempotencyKeys(ctx context.Context, idempotencyKeys []shorturl.IdempotencyKey) ([]shorturl.SelectableShortURL, error) {
+	return Do(r.Breaker, func() ([]shorturl.SelectableShortURL, error) {
+		return r.repo.SelectByIdempotencyKeys(ctx, idempotencyKeys)
+	})
+}
+
+func (r *Repository) SelectByNames(ctx context.Context, names []string) ([]shorturl.SelectableShortURL, error) {
+	return Do(r.Breaker, func() ([]shorturl.SelectableShortURL, error) {
+		return r.repo.SelectByNames(ctx, names)
+	})
+}
+
+func (r *Repository) Insert(ctx context.Context, surl shorturl.ShortURL) error {
+	_, err := Do(r.Breaker, func() (struct{}, error) {
+		return struct{}{}, r.repo.Insert(ctx, surl)
+	})
+	return err
+}
+
+func (r *Repository) InsertMany(ctx context.Context, surls []shorturl.ShortURL) ([]shorturl.ID, error) {
+	return Do(r.Breaker, func() ([]shorturl.ID, error) {
+		return r.repo.InsertMany(ctx, surls)
+	})
+}
+
+func (r *Repository) UpdateLinks(ctx context.Context, changes []shorturl.LinkChange, reason string) (int, error) {
+	return Do(r.Breaker, func() (int, error) {
+		return r.repo.UpdateLinks(ctx, changes, reason)
+	})
+}
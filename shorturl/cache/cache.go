@@ -0,0 +1,367 @@
+// Package cache keeps recently resolved links in process memory, in front
+// of a slower shorturl.Reader
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+const (
+	DefaultSize        = 10000
+	DefaultTTL         = time.Minute
+	DefaultNegativeTTL = 5 * time.Second
+)
+
+type Options struct {
+	// Size is how many names are kept, found or not
+	Size int
+	// TTL bounds how long a link is served from memory. It is cut short
+	// when the link expires sooner
+	TTL time.Duration
+	// NegativeTTL is how long a name that was not found stays not found
+	NegativeTTL time.Duration
+
+	// Now is the clock entries expire by, time.Now by default
+	Now func() time.Time
+	// Meter records hits and misses, the global meter provider's by default
+	Meter metric.Meter
+}
+
+type entry struct {
+	name      string
+	surl      shorturl.SelectableShortURL
+	found     bool
+	expiresAt time.Time
+	// staleUntil is when a link itself expires. Until then it can still be
+	// served past expiresAt, while the repository is unavailable
+	staleUntil time.Time
+	// location is built once, so redirects served from memory don't allocate
+	location shorturl.Location
+}
+
+// Reader answers SelectByName from a bounded LRU, and passes every other
+// read through. Errors other than not found are never cached
+type Reader struct {
+	next    shorturl.Reader
+	options Options
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+
+	// warming collects the names invalidated while a Warm page is read,
+	// which may be stale in it. It is nil when no page is being read
+	warming *invalidations
+	// changed holds the names invalidated since they were last read. Their
+	// next lookup is fresh, so a lagging replica or shared cache can't
+	// bring the old link back
+	changed map[string]struct{}
+
+	flights flights
+	lookups metric.Int64Counter
+}
+
+// Built once, so counting doesn't allocate
+var (
+	hitAttributes      = []metric.AddOption{metric.WithAttributes(attribute.String("result", "hit"))}
+	negativeAttributes = []metric.AddOption{metric.WithAttributes(attribute.String("result", "negative_hit"))}
+	missAttributes     = []metric.AddOption{metric.WithAttributes(attribute.String("result", "miss"))}
+	sharedAttributes   = []metric.AddOption{metric.WithAttributes(attribute.String("result", "coalesced"))}
+	staleAttributes    = []metric.AddOption{metric.WithAttributes(attribute.String("result", "stale"))}
+)
+
+func New(next shorturl.Reader, options Options) (*Reader, error) {
+	if options.Size <= 0 {
+		options.Size = DefaultSize
+	}
+	if options.TTL <= 0 {
+		options.TTL = DefaultTTL
+	}
+	if options.NegativeTTL <= 0 {
+		options.NegativeTTL = DefaultNegativeTTL
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+	if options.Meter == nil {
+		options.Meter = otel.Meter("github.com/rcovery/go-url-shortener/shorturl/cache")
+	}
+
+	lookups, counterErr := options.Meter.Int64Counter(
+		"shorturl.cache.lookups",
+		metric.WithDescription("Name lookups answered by the link cache, by result"),
+	)
+	if counterErr != nil {
+		return nil, counterErr
+	}
+
+	return &Reader{
+		next:    next,
+		options: options,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+		changed: map[string]struct{}{},
+		lookups: lookups,
+	}, nil
+}
+
+func (c *Reader) SelectByName(ctx context.Context, name string) (shorturl.SelectableShortURL, error) {
+	if cached, found, ok := c.get(name); ok {
+		if !found {
+			c.lookups.Add(ctx, 1, negativeAttributes...)
+			return shorturl.SelectableShortURL{}, errs.NotFoundError.New(fmt.Sprintf("ByName: %q", name))
+		}
+		c.lookups.Add(ctx, 1, hitAttributes...)
+		return cached, nil
+	}
+
+	// Concurrent misses for one name share a single repository call
+	surl, err, shared := c.flights.do(ctx, name, func(ctx context.Context) (shorturl.SelectableShortURL, error) {
+		if c.isChanged(name) {
+			ctx = shorturl.Fresh(ctx)
+		}
+		return c.next.SelectByName(ctx, name)
+	}, func(surl shorturl.SelectableShortURL, err error) {
+		switch {
+		case err == nil:
+			c.put(name, surl, true)
+		case errors.Is(err, errs.NotFoundError):
+			c.put(name, shorturl.SelectableShortURL{}, false)
+		}
+	})
+	if errors.Is(err, errs.UnavailableError) {
+		// The last known link beats no answer at all
+		if stale, ok := c.stale(name); ok {
+			c.lookups.Add(ctx, 1, staleAttributes...)
+			return stale, nil
+		}
+	}
+	if shared {
+		c.lookups.Add(ctx, 1, sharedAttributes...)
+	} else {
+		c.lookups.Add(ctx, 1, missAttributes...)
+	}
+
+	return surl, err
+}
+
+// Invalidate drops name, so its next lookup reaches the repository, and
+// is fresh
+func (c *Reader) Invalidate(name string) {
+	// A lookup already running may have read the old link; it is not kept
+	c.flights.forget(name, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.warming != nil {
+			c.warming.names[name] = struct{}{}
+		}
+		// Forgetting marks only makes lookups less fresh, so a flood of
+		// changes can't grow the set past the cache
+		if len(c.changed) >= c.options.Size {
+			clear(c.changed)
+		}
+		c.changed[name] = struct{}{}
+		if element, ok := c.entries[name]; ok {
+			c.order.Remove(element)
+			delete(c.entries, name)
+		}
+	})
+}
+
+// CachedLocation answers a redirect from memory, without allocating. It
+// never reaches the repository, so unknown names are left to SelectByName
+func (c *Reader) CachedLocation(name string) (shorturl.Location, bool) {
+	c.mu.Lock()
+	element, ok := c.entries[name]
+	if !ok {
+		c.mu.Unlock()
+		return nil, false
+	}
+
+	cached := element.Value.(*entry)
+	if !c.options.Now().Before(cached.expiresAt) {
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	c.mu.Unlock()
+
+	if !cached.found {
+		c.lookups.Add(context.Background(), 1, negativeAttributes...)
+		return nil, true
+	}
+	c.lookups.Add(context.Background(), 1, hitAttributes...)
+	return cached.location, true
+}
+
+// Purge drops every name, for when changes may have been missed
+func (c *Reader) Purge() {
+	c.flights.forgetAll(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.warming != nil {
+			c.warming.all = true
+		}
+		c.order.Init()
+		clear(c.entries)
+	})
+}
+
+// Len returns how many names are cached, expired entries included
+func (c *Reader) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *Reader) get(name string) (shorturl.SelectableShortURL, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[name]
+	if !ok {
+		return shorturl.SelectableShortURL{}, false, false
+	}
+
+	cached := element.Value.(*entry)
+	now := c.options.Now()
+	if !now.Before(cached.expiresAt) {
+		// Kept while it may still be served stale
+		if !now.Before(cached.staleUntil) {
+			c.order.Remove(element)
+			delete(c.entries, name)
+		}
+		return shorturl.SelectableShortURL{}, false, false
+	}
+
+	c.order.MoveToFront(element)
+	return clone(cached.surl), cached.found, true
+}
+
+// stale returns a link past its TTL that has not expired yet
+func (c *Reader) stale(name string) (shorturl.SelectableShortURL, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[name]
+	if !ok {
+		return shorturl.SelectableShortURL{}, false
+	}
+
+	cached := element.Value.(*entry)
+	if !cached.found || !c.options.Now().Before(cached.staleUntil) {
+		return shorturl.SelectableShortURL{}, false
+	}
+
+	return clone(cached.surl), true
+}
+
+func (c *Reader) isChanged(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, changed := c.changed[name]
+	return changed
+}
+
+func (c *Reader) put(name string, surl shorturl.SelectableShortURL, found bool) {
+	cached, cacheable := c.newEntry(name, surl, found)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Read since the last change, or the result would have been forgotten
+	delete(c.changed, name)
+	if !cacheable {
+		return
+	}
+
+	if element, ok := c.entries[name]; ok {
+		element.Value = cached
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[name] = c.order.PushFront(cached)
+	for c.order.Len() > c.options.Size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).name)
+	}
+}
+
+// newEntry reports false for links expiring before they could be served
+func (c *Reader) newEntry(name string, surl shorturl.SelectableShortURL, found bool) (*entry, bool) {
+	now := c.options.Now()
+	if !found {
+		expiresAt := now.Add(c.options.NegativeTTL)
+		return &entry{name: name, expiresAt: expiresAt, staleUntil: expiresAt}, true
+	}
+
+	expiresAt := now.Add(c.options.TTL)
+	if surl.ExpiresAt.Before(expiresAt) {
+		expiresAt = surl.ExpiresAt
+	}
+	if !now.Before(expiresAt) {
+		return nil, false
+	}
+
+	surl = clone(surl)
+	return &entry{
+		name:       name,
+		surl:       surl,
+		found:      true,
+		expiresAt:  expiresAt,
+		staleUntil: surl.ExpiresAt,
+		location:   shorturl.NewLocation(surl.Link),
+	}, true
+}
+
+// clone copies what a caller could change in place, so cached links stay as read
+func clone(surl shorturl.SelectableShortURL) shorturl.SelectableShortURL {
+	if surl.Link != nil {
+		link := *surl.Link
+		surl.Link = &link
+	}
+	if surl.Tags != nil {
+		surl.Tags = append([]string{}, surl.Tags...)
+	}
+	surl.Metadata = maps.Clone(surl.Metadata)
+	return surl
+}
+
+func (c *Reader) SelectByIdempotencyKey(ctx context.Context, idempotencyKey shorturl.IdempotencyKey) (shorturl.SelectableShortURL, error) {
+	return c.next.SelectByIdempotencyKey(ctx, idempotencyKey)
+}
+
+func (c *Reader) List(ctx context.Context, filter shorturl.ListFilter) ([]shorturl.SelectableShortURL, error) {
+	return c.next.List(ctx, filter)
+}
+
+func (c *Reader) SelectByDestination(ctx context.Context, query shorturl.DestinationQuery) ([]shorturl.SelectableShortURL, error) {
+	return c.next.SelectByDestination(ctx, query)
+}
+
+func (c *Reader) SelectByIdempotencyKeys(ctx context.Context, idempotencyKeys []shorturl.IdempotencyKey) ([]shorturl.SelectableShortURL, error) {
+	return c.next.SelectByIdempotencyKeys(ctx, idempotencyKeys)
+}
+
+func (c *Reader) SelectByNames(ctx context.Context, names []string) ([]shorturl.SelectableShortURL, error) {
+	return c.next.SelectByNames(ctx, names)
+}
@@ -0,0 +1,275 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/cache"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+	"github.com/rcovery/go-url-shortener/shorturl/repotest"
+)
+
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+// lagging serves the links it was given in behind, like a replica that
+// hasn't caught up, unless the lookup is fresh
+type lagging struct {
+	*repotest.CountingRepository
+	behind map[string]shorturl.SelectableShortURL
+}
+
+func (r *lagging) SelectByName(ctx context.Context, name string) (shorturl.SelectableShortURL, error) {
+	if old, ok := r.behind[name]; ok && !shorturl.IsFresh(ctx) {
+		return old, nil
+	}
+	return r.CountingRepository.SelectByName(ctx, name)
+}
+
+func newCache(t *testing.T, options cache.Options) (*cache.Repository, *repotest.CountingRepository, *clock) {
+	t.Helper()
+
+	clock := &clock{now: time.Now()}
+	options.Now = clock.Now
+
+	next := repotest.NewCountingRepository()
+	repo, err := cache.Wrap(next, options)
+	if err != nil {
+		t.Fatalf("cannot build the cache: %v", err)
+	}
+	return repo, next, clock
+}
+
+func TestReader(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should serve repeated lookups from memory", func(t *testing.T) {
+		repo, next, _ := newCache(t, cache.Options{})
+		repotest.Insert(t, next, repotest.NewShortURL(t, "cached", time.Now().Add(time.Hour)))
+
+		for range 3 {
+			surl, err := repo.SelectByName(ctx, "cached")
+			if err != nil || surl.Name != "cached" {
+				t.Fatalf("want the cached link, got %q (%v)", surl.Name, err)
+			}
+		}
+		if next.Lookups.Load() != 1 {
+			t.Errorf("want 1 lookup, got %d", next.Lookups.Load())
+		}
+	})
+
+	t.Run("should not let callers change a cached link", func(t *testing.T) {
+		repo, next, _ := newCache(t, cache.Options{})
+		repotest.Insert(t, next, repotest.NewShortURL(t, "cached", time.Now().Add(time.Hour)))
+
+		first, _ := repo.SelectByName(ctx, "cached")
+		first.Tags = append(first.Tags, "changed")
+		changed, _ := shorturl.NewLink("https://example.org/")
+		*first.Link = *changed
+
+		second, _ := repo.SelectByName(ctx, "cached")
+		if second.Link.String() != "https://example.com/cached" || len(second.Tags) != 0 {
+			t.Errorf("want the cached link unchanged, got %q %v", second.Link, second.Tags)
+		}
+	})
+
+	t.Run("should stop serving a link once it expires", func(t *testing.T) {
+		repo, next, clock := newCache(t, cache.Options{TTL: time.Hour})
+		repotest.Insert(t, next, repotest.NewShortURL(t, "short-lived", clock.now.Add(time.Minute)))
+
+		repo.SelectByName(ctx, "short-lived")
+		clock.now = clock.now.Add(2 * time.Minute)
+		repo.SelectByName(ctx, "short-lived")
+
+		if next.Lookups.Load() != 2 {
+			t.Errorf("want the expired entry looked up again, got %d lookups", next.Lookups.Load())
+		}
+	})
+
+	t.Run("should cache misses for the negative TTL", func(t *testing.T) {
+		repo, next, clock := newCache(t, cache.Options{NegativeTTL: time.Second})
+
+		for range 2 {
+			if _, err := repo.SelectByName(ctx, "missing"); !errors.Is(err, errs.NotFoundError) {
+				t.Fatalf("want not found, got %v", err)
+			}
+		}
+		if next.Lookups.Load() != 1 {
+			t.Errorf("want 1 lookup while the miss is cached, got %d", next.Lookups.Load())
+		}
+
+		clock.now = clock.now.Add(2 * time.Second)
+		repo.SelectByName(ctx, "missing")
+		if next.Lookups.Load() != 2 {
+			t.Errorf("want the miss looked up again after the negative TTL, got %d", next.Lookups.Load())
+		}
+	})
+
+	t.Run("should forget a cached miss when the name is created", func(t *testing.T) {
+		repo, _, _ := newCache(t, cache.Options{})
+
+		repo.SelectByName(ctx, "soon")
+		repotest.Insert(t, repo, repotest.NewShortURL(t, "soon", time.Now().Add(time.Hour)))
+
+		if _, err := repo.SelectByName(ctx, "soon"); err != nil {
+			t.Errorf("want the new link, got %v", err)
+		}
+	})
+
+	t.Run("should not cache failed lookups", func(t *testing.T) {
+		repo, next, _ := newCache(t, cache.Options{})
+		next.Err = errors.New("connection refused")
+
+		repo.SelectByName(ctx, "broken")
+		repo.SelectByName(ctx, "broken")
+		if next.Lookups.Load() != 2 {
+			t.Errorf("want every failed lookup retried, got %d lookups", next.Lookups.Load())
+		}
+	})
+
+	t.Run("should serve a stale link while the repository is unavailable", func(t *testing.T) {
+		repo, next, clock := newCache(t, cache.Options{TTL: time.Minute})
+		repotest.Insert(t, next, repotest.NewShortURL(t, "stale", clock.now.Add(time.Hour)))
+
+		repo.SelectByName(ctx, "stale")
+		clock.now = clock.now.Add(2 * time.Minute)
+		next.Err = errs.UnavailableError.New("circuit breaker is open")
+
+		surl, err := repo.SelectByName(ctx, "stale")
+		if err != nil || surl.Name != "stale" {
+			t.Fatalf("want the stale link, got %q (%v)", surl.Name, err)
+		}
+
+		clock.now = clock.now.Add(time.Hour)
+		if _, err = repo.SelectByName(ctx, "stale"); !errors.Is(err, errs.UnavailableError) {
+			t.Errorf("want unavailable once the link itself expired, got %v", err)
+		}
+	})
+
+	t.Run("should not serve a stale link for other failures", func(t *testing.T) {
+		repo, next, clock := newCache(t, cache.Options{TTL: time.Minute})
+		repotest.Insert(t, next, repotest.NewShortURL(t, "stale", clock.now.Add(time.Hour)))
+
+		repo.SelectByName(ctx, "stale")
+		clock.now = clock.now.Add(2 * time.Minute)
+		next.Err = errors.New("syntax error")
+
+		if _, err := repo.SelectByName(ctx, "stale"); err == nil {
+			t.Errorf("want the error, got the stale link")
+		}
+	})
+
+	t.Run("should evict the least recently used name", func(t *testing.T) {
+		repo, next, _ := newCache(t, cache.Options{Size: 2})
+		for _, name := range []string{"a", "b", "c"} {
+			repotest.Insert(t, next, repotest.NewShortURL(t, name, time.Now().Add(time.Hour)))
+		}
+
+		repo.SelectByName(ctx, "a")
+		repo.SelectByName(ctx, "b")
+		repo.SelectByName(ctx, "a")
+		repo.SelectByName(ctx, "c")
+		if repo.Len() != 2 {
+			t.Fatalf("want 2 cached names, got %d", repo.Len())
+		}
+
+		next.Lookups.Store(0)
+		repo.SelectByName(ctx, "a")
+		repo.SelectByName(ctx, "b")
+		if next.Lookups.Load() != 1 {
+			t.Errorf("want only the evicted name looked up, got %d lookups", next.Lookups.Load())
+		}
+	})
+
+	t.Run("should count hits and misses", func(t *testing.T) {
+		reader := sdkmetric.NewManualReader()
+		meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+		repo, next, _ := newCache(t, cache.Options{Meter: meter})
+		repotest.Insert(t, next, repotest.NewShortURL(t, "counted", time.Now().Add(time.Hour)))
+		repo.SelectByName(ctx, "counted")
+		repo.SelectByName(ctx, "counted")
+		repo.SelectByName(ctx, "counted")
+		repo.SelectByName(ctx, "missing")
+		repo.SelectByName(ctx, "missing")
+
+		var collected metricdata.ResourceMetrics
+		if err := reader.Collect(ctx, &collected); err != nil {
+			t.Fatalf("cannot collect metrics: %v", err)
+		}
+
+		counts := map[string]int64{}
+		for _, scope := range collected.ScopeMetrics {
+			for _, m := range scope.Metrics {
+				sum, ok := m.Data.(metricdata.Sum[int64])
+				if m.Name != "shorturl.cache.lookups" || !ok {
+					continue
+				}
+				for _, point := range sum.DataPoints {
+					result, _ := point.Attributes.Value("result")
+					counts[result.AsString()] = point.Value
+				}
+			}
+		}
+
+		want := map[string]int64{"hit": 2, "negative_hit": 1, "miss": 2}
+		for result, count := range want {
+			if counts[result] != count {
+				t.Errorf("want %d %s, got %d", count, result, counts[result])
+			}
+		}
+	})
+}
+
+func TestPurge(t *testing.T) {
+	t.Run("should read a changed name fresh after it is invalidated", func(t *testing.T) {
+		ctx := context.Background()
+		next := &lagging{CountingRepository: repotest.NewCountingRepository()}
+		reader, err := cache.New(next, cache.Options{})
+		if err != nil {
+			t.Fatalf("cannot build the cache: %v", err)
+		}
+		surl := repotest.NewShortURL(t, "changed", time.Now().Add(time.Hour))
+		repotest.Insert(t, next, surl)
+		old, _ := reader.SelectByName(ctx, "changed")
+
+		// Another server changes the link, and its notification arrives
+		// before the read path catches up
+		moved, _ := shorturl.NewLink("https://example.org/moved")
+		if _, updateErr := next.UpdateLinks(ctx, []shorturl.LinkChange{{ID: surl.ID, Name: "changed", From: surl.Link, To: moved}}, "test"); updateErr != nil {
+			t.Fatalf("UpdateLinks failed unexpectedly: %v", updateErr)
+		}
+		next.behind = map[string]shorturl.SelectableShortURL{"changed": old}
+		reader.Invalidate("changed")
+
+		found, err := reader.SelectByName(ctx, "changed")
+		if err != nil || !found.Link.Equals(moved) {
+			t.Errorf("want %q, got %q (%v)", moved, found.Link, err)
+		}
+	})
+
+	t.Run("should drop every name", func(t *testing.T) {
+		ctx := context.Background()
+		repo, next, _ := newCache(t, cache.Options{})
+		repotest.Insert(t, next, repotest.NewShortURL(t, "a", time.Now().Add(time.Hour)))
+
+		repo.SelectByName(ctx, "a")
+		repo.SelectByName(ctx, "missing")
+		repo.Purge()
+
+		if repo.Len() != 0 {
+			t.Fatalf("want an empty cache, got %d names", repo.Len())
+		}
+		repo.SelectByName(ctx, "a")
+		if next.Lookups.Load() != 3 {
+			t.Errorf("want the name looked up again, got %d lookups", next.Lookups.Load())
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+type flight struct {
+	done chan struct{}
+	surl shorturl.SelectableShortURL
+	err  error
+
+	waiters int
+	cancel  context.CancelFunc
+	// forgotten flights started before a write to their name, so their
+	// result may be stale and is not kept
+	forgotten bool
+}
+
+// flights lets concurrent lookups of one name share a single call. The
+// call runs apart from any caller's context, so one caller timing out
+// doesn't fail the others; it is only cancelled once every caller gave up
+type flights struct {
+	mu      sync.Mutex
+	pending map[string]*flight
+}
+
+// do returns fn's result for name, and whether it came from a call another
+// caller started. keep is given the result unless name was forgotten meanwhile
+func (f *flights) do(ctx context.Context, name string, fn func(ctx context.Context) (shorturl.SelectableShortURL, error), keep func(shorturl.SelectableShortURL, error)) (shorturl.SelectableShortURL, error, bool) {
+	f.mu.Lock()
+	if f.pending == nil {
+		f.pending = map[string]*flight{}
+	}
+
+	current, shared := f.pending[name]
+	if !shared {
+		// Keeps ctx's values, such as the trace, but not its deadline
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		current = &flight{done: make(chan struct{}), cancel: cancel}
+		f.pending[name] = current
+
+		go func() {
+			surl, err := fn(flightCtx)
+			cancel()
+
+			f.mu.Lock()
+			if f.pending[name] == current {
+				delete(f.pending, name)
+			}
+			if !current.forgotten {
+				keep(surl, err)
+			}
+			current.surl, current.err = surl, err
+			f.mu.Unlock()
+			close(current.done)
+		}()
+	}
+	current.waiters++
+	f.mu.Unlock()
+
+	select {
+	case <-current.done:
+		return clone(current.surl), current.err, shared
+	case <-ctx.Done():
+		f.mu.Lock()
+		current.waiters--
+		if current.waiters == 0 {
+			current.cancel()
+			// Later callers start over instead of joining a cancelled call
+			if f.pending[name] == current {
+				delete(f.pending, name)
+			}
+		}
+		f.mu.Unlock()
+		return shorturl.SelectableShortURL{}, ctx.Err(), shared
+	}
+}
+
+// forget makes later callers of name start a new call, and runs drop while
+// no call for name can keep its result
+func (f *flights) forget(name string, drop func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if current, ok := f.pending[name]; ok {
+		current.forgotten = true
+		delete(f.pending, name)
+	}
+	drop()
+}
+
+// forgetAll is forget for every name
+func (f *flights) forgetAll(drop func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for name, current := range f.pending {
+		current.forgotten = true
+		delete(f.pending, name)
+	}
+	drop()
+}
+
+// exclusive runs fn while no call can keep its result or be forgotten
+func (f *flights) exclusive(fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fn()
+}
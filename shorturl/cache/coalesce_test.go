@@ -0,0 +1,146 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/cache"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
+	"github.com/rcovery/go-url-shortener/shorturl/repotest"
+)
+
+// blockingRepository reads a name, then holds the answer until release is closed
+type blockingRepository struct {
+	*memory.Repository
+	calls     atomic.Int32
+	started   chan struct{}
+	release   chan struct{}
+	cancelled chan struct{}
+}
+
+func newBlockingRepository() *blockingRepository {
+	return &blockingRepository{
+		Repository: memory.NewRepository(),
+		started:    make(chan struct{}, 100),
+		release:    make(chan struct{}),
+		cancelled:  make(chan struct{}, 100),
+	}
+}
+
+func (r *blockingRepository) SelectByName(ctx context.Context, name string) (shorturl.SelectableShortURL, error) {
+	r.calls.Add(1)
+	surl, err := r.Repository.SelectByName(ctx, name)
+	r.started <- struct{}{}
+
+	select {
+	case <-r.release:
+		return surl, err
+	case <-ctx.Done():
+		r.cancelled <- struct{}{}
+		return shorturl.SelectableShortURL{}, ctx.Err()
+	}
+}
+
+func TestCoalescing(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should share one call between concurrent misses", func(t *testing.T) {
+		next := newBlockingRepository()
+		repotest.Insert(t, next, repotest.NewShortURL(t, "viral", time.Now().Add(time.Hour)))
+		reader, _ := cache.New(next, cache.Options{})
+
+		var wg sync.WaitGroup
+		var failures atomic.Int32
+		for range 50 {
+			wg.Go(func() {
+				if surl, err := reader.SelectByName(ctx, "viral"); err != nil || surl.Name != "viral" {
+					failures.Add(1)
+				}
+			})
+		}
+
+		<-next.started
+		close(next.release)
+		wg.Wait()
+
+		if failures.Load() != 0 {
+			t.Errorf("want every caller to get the link, %d failed", failures.Load())
+		}
+		if next.calls.Load() != 1 {
+			t.Errorf("want 1 repository call, got %d", next.calls.Load())
+		}
+	})
+
+	t.Run("should not fail the others when one caller gives up", func(t *testing.T) {
+		next := newBlockingRepository()
+		repotest.Insert(t, next, repotest.NewShortURL(t, "viral", time.Now().Add(time.Hour)))
+		reader, _ := cache.New(next, cache.Options{})
+
+		impatient, cancel := context.WithCancel(ctx)
+		impatientErr := make(chan error)
+		go func() {
+			_, err := reader.SelectByName(impatient, "viral")
+			impatientErr <- err
+		}()
+		<-next.started
+
+		patientErr := make(chan error)
+		go func() {
+			_, err := reader.SelectByName(ctx, "viral")
+			patientErr <- err
+		}()
+
+		cancel()
+		if err := <-impatientErr; !errors.Is(err, context.Canceled) {
+			t.Errorf("want the cancelled caller to stop, got %v", err)
+		}
+
+		close(next.release)
+		if err := <-patientErr; err != nil {
+			t.Errorf("want the other caller to get the link, got %v", err)
+		}
+	})
+
+	t.Run("should cancel the call once every caller gave up", func(t *testing.T) {
+		next := newBlockingRepository()
+		reader, _ := cache.New(next, cache.Options{})
+
+		timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		if _, err := reader.SelectByName(timeout, "slow"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want the deadline error, got %v", err)
+		}
+
+		select {
+		case <-next.cancelled:
+		case <-time.After(time.Second):
+			t.Errorf("want the repository call cancelled")
+		}
+	})
+
+	t.Run("should not keep a lookup that raced a write", func(t *testing.T) {
+		next := newBlockingRepository()
+		repo, _ := cache.Wrap(next, cache.Options{})
+
+		done := make(chan struct{})
+		go func() {
+			repo.SelectByName(ctx, "fresh")
+			close(done)
+		}()
+		<-next.started
+
+		repotest.Insert(t, repo, repotest.NewShortURL(t, "fresh", time.Now().Add(time.Hour)))
+		close(next.release)
+		<-done
+
+		if _, err := repo.SelectByName(ctx, "fresh"); err != nil {
+			t.Errorf("want the new link, got %v", err)
+		}
+	})
+}
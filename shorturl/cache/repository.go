@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+// Repository caches a repository's reads and forgets the names its own
+// writes touch. Writes made by other processes show up once entries expire
+type Repository struct {
+	*Reader
+	writer shorturl.Writer
+}
+
+func Wrap(repo shorturl.Repository, options Options) (*Repository, error) {
+	reader, readerErr := New(repo, options)
+	if readerErr != nil {
+		return nil, readerErr
+	}
+
+	return &Repository{Reader: reader, writer: repo}, nil
+}
+
+func (r *Repository) Insert(ctx context.Context, surl shorturl.ShortURL) error {
+	defer r.Invalidate(surl.Name)
+	return r.writer.Insert(ctx, surl)
+}
+
+func (r *Repository) InsertMany(ctx context.Context, surls []shorturl.ShortURL) ([]shorturl.ID, error) {
+	defer func() {
+		for _, surl := range surls {
+			r.Invalidate(surl.Name)
+		}
+	}()
+	return r.writer.InsertMany(ctx, surls)
+}
+
+func (r *Repository) UpdateLinks(ctx context.Context, changes []shorturl.LinkChange, reason string) (int, error) {
+	defer func() {
+		for _, change := range changes {
+			r.Invalidate(change.Name)
+		}
+	}()
+	return r.writer.UpdateLinks(ctx, changes, reason)
+}
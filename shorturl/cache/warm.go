@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+// Warm loads up to size of the most recently created active links, so a
+// new server doesn't send all its first redirects to the repository. It
+// stops early when ctx is done, returning how many links it loaded.
+// Names already cached are left alone, being at least as fresh. Warm is
+// not meant to run twice at once
+func (c *Reader) Warm(ctx context.Context, size int) (int, error) {
+	size = min(size, c.options.Size)
+	filter := shorturl.ListFilter{Sort: shorturl.SortCreatedDesc, ExpiresAfter: time.Now()}
+
+	loaded := 0
+	for loaded < size {
+		filter.Limit = min(size-loaded, shorturl.MaxListLimit)
+
+		c.mu.Lock()
+		c.warming = &invalidations{names: map[string]struct{}{}}
+		c.mu.Unlock()
+
+		page, listErr := c.next.List(ctx, filter)
+
+		c.flights.exclusive(func() {
+			c.mu.Lock()
+			invalidated := c.warming
+			c.warming = nil
+			c.mu.Unlock()
+
+			// A name invalidated while the page was read may be stale in it
+			if listErr == nil && !invalidated.all {
+				loaded += c.warm(page, invalidated.names)
+			}
+		})
+		if listErr != nil {
+			return loaded, listErr
+		}
+
+		if len(page) < filter.Limit {
+			break
+		}
+		cursor := shorturl.NewCursor(filter.Sort, page[len(page)-1])
+		filter.After = &cursor
+	}
+
+	return loaded, nil
+}
+
+// invalidations are the names dropped while a Warm page is read. all is
+// set by a purge, which drops every name
+type invalidations struct {
+	names map[string]struct{}
+	all   bool
+}
+
+// warm adds links behind the ones already cached, up to the cache's size,
+// leaving out the skipped names
+func (c *Reader) warm(surls []shorturl.SelectableShortURL, skipped map[string]struct{}) int {
+	entries := make([]*entry, 0, len(surls))
+	for _, surl := range surls {
+		if _, skip := skipped[surl.Name]; skip {
+			continue
+		}
+		if cached, cacheable := c.newEntry(surl.Name, surl, true); cacheable {
+			entries = append(entries, cached)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	added := 0
+	for _, cached := range entries {
+		if c.order.Len() >= c.options.Size {
+			break
+		}
+		if _, present := c.entries[cached.name]; present {
+			continue
+		}
+		c.entries[cached.name] = c.order.PushBack(cached)
+		added++
+	}
+	return added
+}
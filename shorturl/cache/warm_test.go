@@ -0,0 +1,103 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/cache"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
+	"github.com/rcovery/go-url-shortener/shorturl/repotest"
+)
+
+func TestWarm(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should load the most recent active links", func(t *testing.T) {
+		repo, next, _ := newCache(t, cache.Options{})
+		for i := range 10 {
+			repotest.Insert(t, next, repotest.NewShortURL(t, fmt.Sprintf("link-%d", i), time.Now().Add(time.Hour)))
+		}
+		repotest.Insert(t, next, repotest.NewShortURL(t, "expired", time.Now().Add(-time.Hour)))
+
+		loaded, err := repo.Warm(ctx, 5)
+		if err != nil {
+			t.Fatalf("Warm failed unexpectedly: %v", err)
+		}
+		if loaded != 5 || repo.Len() != 5 {
+			t.Fatalf("want 5 links loaded, got %d (%d cached)", loaded, repo.Len())
+		}
+
+		for i := 5; i < 10; i++ {
+			name := fmt.Sprintf("link-%d", i)
+			if location, known := repo.CachedLocation(name); !known || location[0] != "https://example.com/"+name {
+				t.Errorf("want %q cached, got %v", name, location)
+			}
+		}
+		if _, known := repo.CachedLocation("link-0"); known {
+			t.Errorf("want older links left out")
+		}
+	})
+
+	t.Run("should not load more than the cache holds", func(t *testing.T) {
+		repo, next, _ := newCache(t, cache.Options{Size: 3})
+		for i := range 10 {
+			repotest.Insert(t, next, repotest.NewShortURL(t, fmt.Sprintf("link-%d", i), time.Now().Add(time.Hour)))
+		}
+
+		if loaded, _ := repo.Warm(ctx, 10); loaded != 3 {
+			t.Errorf("want 3 links loaded, got %d", loaded)
+		}
+	})
+
+	t.Run("should stop at the deadline", func(t *testing.T) {
+		repo, next, _ := newCache(t, cache.Options{})
+		repotest.Insert(t, next, repotest.NewShortURL(t, "link", time.Now().Add(time.Hour)))
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		if _, err := repo.Warm(cancelled, 10); !errors.Is(err, context.Canceled) {
+			t.Errorf("want the context error, got %v", err)
+		}
+	})
+
+	t.Run("should leave out only the names invalidated while loading", func(t *testing.T) {
+		next := &listing{Repository: memory.NewRepository()}
+		repo, err := cache.Wrap(next, cache.Options{})
+		if err != nil {
+			t.Fatalf("cannot build the cache: %v", err)
+		}
+		for i := range 10 {
+			repotest.Insert(t, next, repotest.NewShortURL(t, fmt.Sprintf("link-%d", i), time.Now().Add(time.Hour)))
+		}
+		next.during = func() { repo.Invalidate("link-7") }
+
+		loaded, err := repo.Warm(ctx, 10)
+		if err != nil {
+			t.Fatalf("Warm failed unexpectedly: %v", err)
+		}
+		if loaded != 9 {
+			t.Errorf("want 9 links loaded, got %d", loaded)
+		}
+		if _, known := repo.CachedLocation("link-7"); known {
+			t.Errorf("want the invalidated link left out")
+		}
+	})
+}
+
+// listing runs during while a List call reads
+type listing struct {
+	shorturl.Repository
+	during func()
+}
+
+func (r *listing) List(ctx context.Context, filter shorturl.ListFilter) ([]shorturl.SelectableShortURL, error) {
+	if r.during != nil {
+		r.during()
+	}
+	return r.Repository.List(ctx, filter)
+}
@@ -0,0 +1,85 @@
+package shorturl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+// DestinationMatch is how a destination is compared in a reverse lookup
+type DestinationMatch string
+
+const (
+	// MatchExact matches the normalized destination
+	MatchExact DestinationMatch = "exact"
+	// MatchPrefix matches normalized destinations starting with the target
+	MatchPrefix DestinationMatch = "prefix"
+	// MatchHost matches destinations on the same host
+	MatchHost DestinationMatch = "host"
+)
+
+// DestinationQuery selects links by where they point to. Target is a
+// normalized link for MatchExact and MatchPrefix, and a lowercase host
+// for MatchHost
+type DestinationQuery struct {
+	Match  DestinationMatch
+	Target string
+
+	AfterID ID
+	Limit   int
+}
+
+const reverseLookupPageSize = 500
+
+// NewDestinationQuery builds the query for a URL or, with MatchHost, a URL or a bare domain
+func NewDestinationQuery(match DestinationMatch, target string) (DestinationQuery, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return DestinationQuery{}, errs.InvalidError.New("empty destination")
+	}
+
+	switch match {
+	case MatchExact, MatchPrefix:
+		link, linkErr := NewLink(target)
+		if linkErr != nil || link.Hostname() == "" {
+			return DestinationQuery{}, errs.InvalidError.New(fmt.Sprintf("invalid destination %q", target))
+		}
+		return DestinationQuery{Match: match, Target: link.Normalized()}, nil
+	case MatchHost:
+		if strings.Contains(target, "://") {
+			link, linkErr := NewLink(target)
+			if linkErr != nil {
+				return DestinationQuery{}, errs.InvalidError.New(fmt.Sprintf("invalid destination %q", target))
+			}
+			target = link.Hostname()
+		}
+		if target == "" || strings.ContainsAny(target, "/?#") {
+			return DestinationQuery{}, errs.InvalidError.New(fmt.Sprintf("invalid host %q", target))
+		}
+		return DestinationQuery{Match: match, Target: strings.ToLower(target)}, nil
+	}
+
+	return DestinationQuery{}, errs.InvalidError.New(fmt.Sprintf("unknown match %q", match))
+}
+
+// ReverseLookup returns every link, active or expired, whose destination
+// matches query, in ID order
+func (s *Service) ReverseLookup(ctx context.Context, query DestinationQuery) ([]SelectableShortURL, error) {
+	found := []SelectableShortURL{}
+
+	query.Limit = reverseLookupPageSize
+	for {
+		page, lookupErr := s.repo.SelectByDestination(ctx, query)
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+
+		found = append(found, page...)
+		if len(page) < query.Limit {
+			return found, nil
+		}
+		query.AfterID = page[len(page)-1].ID
+	}
+}
@@ -0,0 +1,42 @@
+package shorturl_test
+
+import (
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+func TestNewDestinationQuery(t *testing.T) {
+	t.Run("should normalize an exact target", func(t *testing.T) {
+		query, err := shorturl.NewDestinationQuery(shorturl.MatchExact, "https://Partner.example.com")
+		if err != nil {
+			t.Fatalf("NewDestinationQuery() %v", err)
+		}
+
+		if query.Target != "https://partner.example.com/" {
+			t.Errorf("want %q, got %q", "https://partner.example.com/", query.Target)
+		}
+	})
+
+	t.Run("should accept a bare domain or a URL for host matches", func(t *testing.T) {
+		for _, target := range []string{"Partner.example.com", "https://partner.example.com/a/b"} {
+			query, err := shorturl.NewDestinationQuery(shorturl.MatchHost, target)
+			if err != nil {
+				t.Fatalf("NewDestinationQuery(%q) %v", target, err)
+			}
+
+			if query.Target != "partner.example.com" {
+				t.Errorf("want %q, got %q", "partner.example.com", query.Target)
+			}
+		}
+	})
+
+	t.Run("should reject unknown matches and empty targets", func(t *testing.T) {
+		if _, err := shorturl.NewDestinationQuery("fuzzy", "https://example.com"); err == nil {
+			t.Errorf("expected an error for an unknown match, got nil")
+		}
+		if _, err := shorturl.NewDestinationQuery(shorturl.MatchPrefix, " "); err == nil {
+			t.Errorf("expected an error for an empty target, got nil")
+		}
+	})
+}
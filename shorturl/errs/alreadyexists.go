@@ -0,0 +1,22 @@
+package errs
+
+import "errors"
+
+type errAlreadyExists struct {
+	Message string
+}
+
+func (err errAlreadyExists) Error() string {
+	return err.Message
+}
+
+func (err errAlreadyExists) New(msg string) errAlreadyExists {
+	err.Message = msg
+	return err
+}
+
+func (err errAlreadyExists) Is(target error) bool {
+	return errors.As(target, &errAlreadyExists{})
+}
+
+var AlreadyExistsError = errAlreadyExists{}
@@ -0,0 +1,22 @@
+package errs
+
+import "errors"
+
+type errInvalid struct {
+	Message string
+}
+
+func (err errInvalid) Error() string {
+	return err.Message
+}
+
+func (err errInvalid) New(msg string) errInvalid {
+	err.Message = msg
+	return err
+}
+
+func (err errInvalid) Is(target error) bool {
+	return errors.As(target, &errInvalid{})
+}
+
+var InvalidError = errInvalid{}
@@ -0,0 +1,23 @@
+package errs
+
+import "errors"
+
+// errUnavailable means the store is down or shedding load; retrying later may work
+type errUnavailable struct {
+	Message string
+}
+
+func (err errUnavailable) Error() string {
+	return err.Message
+}
+
+func (err errUnavailable) New(msg string) errUnavailable {
+	err.Message = msg
+	return err
+}
+
+func (err errUnavailable) Is(target error) bool {
+	return errors.As(target, &errUnavailable{})
+}
+
+var UnavailableError = errUnavailable{}
@@ -0,0 +1,18 @@
+package shorturl
+
+import "context"
+
+type freshKey struct{}
+
+// Fresh marks ctx for lookups that must see every committed write, such as
+// refilling a name a change notification evicted. Stores with replicas
+// answer them from the primary, and shared caches pass them through
+func Fresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshKey{}, true)
+}
+
+// IsFresh reports whether ctx was marked by Fresh
+func IsFresh(ctx context.Context) bool {
+	fresh, _ := ctx.Value(freshKey{}).(bool)
+	return fresh
+}
@@ -1,6 +1,9 @@
 package shorturl
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
 
 type ID string
 
@@ -8,3 +11,13 @@ func NewID() (ID, error) {
 	newuuid, err := uuid.NewV7()
 	return ID(newuuid.String()), err
 }
+
+// ParseID accepts any UUID a client sends, and returns it in the lowercase
+// form the database hands back
+func ParseID(raw string) (ID, error) {
+	parsed, err := uuid.Parse(raw)
+	if err != nil {
+		return "", errs.InvalidError.New("an ID must be a UUID")
+	}
+	return ID(parsed.String()), nil
+}
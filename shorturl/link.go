@@ -1,6 +1,10 @@
 package shorturl
 
-import "net/url"
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
 
 type Link url.URL
 
@@ -21,6 +25,31 @@ func (l *Link) String() string {
 	return (*url.URL)(l).String()
 }
 
+// Hostname is the lowercased destination host, without port
+func (l *Link) Hostname() string {
+	return strings.ToLower((*url.URL)(l).Hostname())
+}
+
+// Normalized is the canonical form destinations are matched on: lowercase
+// scheme and host, no default port, no fragment and at least a "/" path
+func (l *Link) Normalized() string {
+	normalized := *(*url.URL)(l)
+	normalized.Scheme = strings.ToLower(normalized.Scheme)
+	normalized.Host = strings.ToLower(normalized.Host)
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+
+	if port := normalized.Port(); (port == "443" && normalized.Scheme == "https") || (port == "80" && normalized.Scheme == "http") {
+		normalized.Host = strings.TrimSuffix(normalized.Host, ":"+port)
+	}
+	if normalized.Host != "" && normalized.Path == "" && normalized.Opaque == "" {
+		normalized.Path = "/"
+		normalized.RawPath = ""
+	}
+
+	return normalized.String()
+}
+
 func (l *Link) Equals(anotherLink *Link) bool {
 	return l.String() == anotherLink.String()
 }
@@ -34,3 +63,22 @@ func (l Link) String() string {
 
 It's just a design decision
 */
+
+func (l *Link) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.String())
+}
+
+func (l *Link) UnmarshalJSON(data []byte) error {
+	var rawURL string
+	if err := json.Unmarshal(data, &rawURL); err != nil {
+		return err
+	}
+
+	link, err := NewLink(rawURL)
+	if err != nil {
+		return err
+	}
+
+	*l = *link
+	return nil
+}
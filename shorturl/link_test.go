@@ -1,6 +1,7 @@
 package shorturl_test
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/rcovery/go-url-shortener/shorturl"
@@ -27,3 +28,57 @@ func TestNewLink(t *testing.T) {
 		assert.Nil(t, link)
 	})
 }
+
+func TestLinkJSON(t *testing.T) {
+	t.Run("should encode a Link as a plain string", func(t *testing.T) {
+		link, _ := shorturl.NewLink("https://example.com/path?q=1")
+
+		encoded, err := json.Marshal(link)
+		if err != nil {
+			t.Fatalf("Marshal() %v", err)
+		}
+
+		if string(encoded) != `"https://example.com/path?q=1"` {
+			t.Errorf("want %q, got %q", `"https://example.com/path?q=1"`, encoded)
+		}
+	})
+
+	t.Run("should decode a Link from a plain string", func(t *testing.T) {
+		var surl shorturl.ShortURL
+		err := json.Unmarshal([]byte(`{"name": "deck", "link": "https://example.com/deck", "tags": ["q3"]}`), &surl)
+		if err != nil {
+			t.Fatalf("Unmarshal() %v", err)
+		}
+
+		if surl.Link == nil || surl.Link.String() != "https://example.com/deck" {
+			t.Errorf("want %q, got %q", "https://example.com/deck", surl.Link)
+		}
+		if len(surl.Tags) != 1 || surl.Tags[0] != "q3" {
+			t.Errorf("want %q, got %q", []string{"q3"}, surl.Tags)
+		}
+	})
+}
+
+func TestLinkNormalized(t *testing.T) {
+	tests := []struct {
+		rawURL string
+		want   string
+	}{
+		{"HTTPS://Docs.Example.COM", "https://docs.example.com/"},
+		{"https://docs.example.com:443/Guide#intro", "https://docs.example.com/Guide"},
+		{"https://docs.example.com:8443/?q=1", "https://docs.example.com:8443/?q=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run("should normalize "+tt.rawURL, func(t *testing.T) {
+			link, err := shorturl.NewLink(tt.rawURL)
+			if err != nil {
+				t.Fatalf("NewLink() %v", err)
+			}
+
+			if got := link.Normalized(); got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package shorturl
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+)
+
+// Sort is the order of a listing. A leading "-" means descending
+type Sort string
+
+const (
+	SortCreated     Sort = "created"
+	SortCreatedDesc Sort = "-created"
+	SortName        Sort = "name"
+	SortNameDesc    Sort = "-name"
+	SortExpires     Sort = "expires"
+	SortExpiresDesc Sort = "-expires"
+)
+
+func (s Sort) Valid() bool {
+	switch s {
+	case SortCreated, SortCreatedDesc, SortName, SortNameDesc, SortExpires, SortExpiresDesc:
+		return true
+	}
+	return false
+}
+
+func (s Sort) Descending() bool {
+	return len(s) > 0 && s[0] == '-'
+}
+
+// Key is the value a link is ordered by, besides its ID.
+// Created order only needs the ID, since IDs are UUIDv7
+func (s Sort) Key(surl SelectableShortURL) string {
+	switch s {
+	case SortName, SortNameDesc:
+		return surl.Name
+	case SortExpires, SortExpiresDesc:
+		return surl.ExpiresAt.UTC().Format(time.RFC3339Nano)
+	}
+	return ""
+}
+
+// Less reports whether a comes before b in this order
+func (s Sort) Less(a, b SelectableShortURL) bool {
+	var order int
+	switch s {
+	case SortName, SortNameDesc:
+		order = cmp.Compare(a.Name, b.Name)
+	case SortExpires, SortExpiresDesc:
+		order = a.ExpiresAt.Compare(b.ExpiresAt)
+	}
+	if order == 0 {
+		order = cmp.Compare(a.ID, b.ID)
+	}
+
+	if s.Descending() {
+		return order > 0
+	}
+	return order < 0
+}
+
+// Cursor points at the last link of a page; the next page starts right after it
+type Cursor struct {
+	Sort Sort   `json:"s"`
+	Key  string `json:"k,omitempty"`
+	ID   ID     `json:"i"`
+}
+
+func NewCursor(sort Sort, surl SelectableShortURL) Cursor {
+	return Cursor{Sort: sort, Key: sort.Key(surl), ID: surl.ID}
+}
+
+func ParseCursor(raw string) (Cursor, error) {
+	var cursor Cursor
+
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor, errs.InvalidError.New("invalid cursor")
+	}
+	if err = json.Unmarshal(decoded, &cursor); err != nil || !cursor.Sort.Valid() || cursor.ID == "" {
+		return cursor, errs.InvalidError.New("invalid cursor")
+	}
+
+	return cursor, nil
+}
+
+func (c Cursor) String() string {
+	encoded, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// ListFilter narrows down a listing. Zero values don't filter
+type ListFilter struct {
+	Owner string
+	// Tags must all be present on a link
+	Tags []string
+	// Metadata must be contained in a link's metadata
+	Metadata Metadata
+	// Domain matches a destination host and all of its subdomains
+	Domain string
+	// Host matches a destination host exactly
+	Host string
+
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+	// ChangedAfter matches links created or given a new destination since then
+	ChangedAfter time.Time
+
+	Sort  Sort
+	After *Cursor
+	Limit int
+}
+
+type ListPage struct {
+	Items      []SelectableShortURL `json:"items"`
+	NextCursor string               `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,59 @@
+package shorturl_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+func TestCursor(t *testing.T) {
+	t.Run("should parse back an encoded cursor", func(t *testing.T) {
+		id, _ := shorturl.NewID()
+		surl := shorturl.SelectableShortURL{ID: id, Name: "q3-deck"}
+
+		cursor := shorturl.NewCursor(shorturl.SortNameDesc, surl)
+		parsed, err := shorturl.ParseCursor(cursor.String())
+		if err != nil {
+			t.Fatalf("ParseCursor() %v", err)
+		}
+
+		if parsed != cursor {
+			t.Errorf("want %v, got %v", cursor, parsed)
+		}
+	})
+
+	t.Run("should reject a tampered cursor", func(t *testing.T) {
+		_, err := shorturl.ParseCursor("not-a-cursor")
+		if err == nil {
+			t.Errorf("expected an error for an invalid cursor, got nil")
+		}
+	})
+}
+
+func TestSortLess(t *testing.T) {
+	now := time.Now()
+	first := shorturl.SelectableShortURL{ID: "0190a000-0000-7000-8000-000000000001", Name: "b", ExpiresAt: now}
+	second := shorturl.SelectableShortURL{ID: "0190a000-0000-7000-8000-000000000002", Name: "a", ExpiresAt: now}
+
+	t.Run("should order by ID when created", func(t *testing.T) {
+		if !shorturl.SortCreated.Less(first, second) {
+			t.Errorf("want first before second")
+		}
+		if !shorturl.SortCreatedDesc.Less(second, first) {
+			t.Errorf("want second before first")
+		}
+	})
+
+	t.Run("should order by name, then ID", func(t *testing.T) {
+		if !shorturl.SortName.Less(second, first) {
+			t.Errorf("want %q before %q", second.Name, first.Name)
+		}
+	})
+
+	t.Run("should break expiry ties by ID", func(t *testing.T) {
+		if !shorturl.SortExpires.Less(first, second) {
+			t.Errorf("want first before second")
+		}
+	})
+}
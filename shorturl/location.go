@@ -0,0 +1,54 @@
+package shorturl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+// Location is the Location header value redirecting to a link. It is built
+// once and set on every response as is, so it must never be changed
+type Location []string
+
+func NewLocation(link *Link) Location {
+	return Location{link.String()}
+}
+
+// LocationCache is implemented by repositories keeping links in memory,
+// which can answer redirects without a context or allocations
+type LocationCache interface {
+	// CachedLocation returns name's Location. known is false when memory
+	// can't tell; otherwise a nil Location means name has no active link
+	CachedLocation(name string) (location Location, known bool)
+}
+
+// CachedLocation answers from the repository's memory, when it has one
+func (s *Service) CachedLocation(name string) (Location, bool) {
+	cache, isCache := s.repo.(LocationCache)
+	if !isCache {
+		return nil, false
+	}
+	return cache.CachedLocation(name)
+}
+
+// Location is Select for redirects: where name points, as a Location header
+func (s *Service) Location(ctx context.Context, name string) (Location, error) {
+	if location, known := s.CachedLocation(name); known {
+		if location == nil {
+			return nil, errs.NotFoundError.New(fmt.Sprintf("cannot retrieve this URL with %q", name))
+		}
+		return location, nil
+	}
+
+	link, selectErr := s.Select(ctx, name)
+	if selectErr != nil {
+		return nil, selectErr
+	}
+	if link == nil {
+		return nil, errors.New("selected an empty URL")
+	}
+
+	return NewLocation(link), nil
+}
@@ -0,0 +1,198 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+func (r *Repository) List(ctx context.Context, filter shorturl.ListFilter) ([]shorturl.SelectableShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var metadata any
+	if len(filter.Metadata) > 0 {
+		encoded, metadataErr := json.Marshal(filter.Metadata)
+		if metadataErr != nil {
+			return nil, errs.InvalidError.New(metadataErr.Error())
+		}
+		json.Unmarshal(encoded, &metadata)
+	}
+
+	var after *shorturl.SelectableShortURL
+	if filter.After != nil {
+		cursor, cursorErr := cursorPosition(*filter.After)
+		if cursorErr != nil {
+			return nil, cursorErr
+		}
+		after = &cursor
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []*record
+	for _, rec := range r.records {
+		if !matchesFilter(rec, filter, metadata) {
+			continue
+		}
+		if after != nil && !filter.Sort.Less(*after, rec.surl) {
+			continue
+		}
+		matched = append(matched, rec)
+	}
+
+	slices.SortFunc(matched, func(a, b *record) int {
+		if filter.Sort.Less(a.surl, b.surl) {
+			return -1
+		}
+		return 1
+	})
+	if filter.Limit >= 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+
+	surls := make([]shorturl.SelectableShortURL, len(matched))
+	for i, rec := range matched {
+		surls[i] = rec.output()
+	}
+	return surls, nil
+}
+
+// cursorPosition turns a cursor back into the sort fields of the link it points at
+func cursorPosition(cursor shorturl.Cursor) (shorturl.SelectableShortURL, error) {
+	position := shorturl.SelectableShortURL{ID: cursor.ID}
+
+	switch cursor.Sort {
+	case shorturl.SortName, shorturl.SortNameDesc:
+		position.Name = cursor.Key
+	case shorturl.SortExpires, shorturl.SortExpiresDesc:
+		expiresAt, parseErr := time.Parse(time.RFC3339Nano, cursor.Key)
+		if parseErr != nil {
+			return position, errs.InvalidError.New(fmt.Sprintf("invalid cursor key %q", cursor.Key))
+		}
+		position.ExpiresAt = expiresAt
+	}
+
+	return position, nil
+}
+
+func matchesFilter(rec *record, filter shorturl.ListFilter, metadata any) bool {
+	surl := rec.surl
+	host := surl.Link.Hostname()
+
+	switch {
+	case filter.Owner != "" && surl.Owner != filter.Owner:
+		return false
+	case filter.Host != "" && host != strings.ToLower(filter.Host):
+		return false
+	case filter.Domain != "" && host != strings.ToLower(filter.Domain) && !strings.HasSuffix(host, "."+strings.ToLower(filter.Domain)):
+		return false
+	case !filter.CreatedAfter.IsZero() && surl.CreatedAt.Before(filter.CreatedAfter):
+		return false
+	case !filter.CreatedBefore.IsZero() && !surl.CreatedAt.Before(filter.CreatedBefore):
+		return false
+	case !filter.ExpiresAfter.IsZero() && surl.ExpiresAt.Before(filter.ExpiresAfter):
+		return false
+	case !filter.ExpiresBefore.IsZero() && !surl.ExpiresAt.Before(filter.ExpiresBefore):
+		return false
+	case !filter.ChangedAfter.IsZero() && rec.updatedAt.Before(filter.ChangedAfter):
+		return false
+	}
+
+	for _, tag := range filter.Tags {
+		if !slices.Contains(surl.Tags, tag) {
+			return false
+		}
+	}
+
+	if metadata != nil {
+		var stored any
+		json.Unmarshal(rec.metadata, &stored)
+		if !contains(stored, metadata) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// contains follows the JSONB @> operator: objects match when every key of
+// want is contained, arrays when every element of want is in have
+func contains(have, want any) bool {
+	switch want := want.(type) {
+	case map[string]any:
+		haveObject, ok := have.(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, value := range want {
+			haveValue, present := haveObject[key]
+			if !present || !contains(haveValue, value) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		haveArray, ok := have.([]any)
+		if !ok {
+			return false
+		}
+		for _, wanted := range want {
+			if !slices.ContainsFunc(haveArray, func(element any) bool { return contains(element, wanted) }) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return have == want
+}
+
+func (r *Repository) SelectByDestination(ctx context.Context, destination shorturl.DestinationQuery) ([]shorturl.SelectableShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var matches func(link *shorturl.Link) bool
+	switch destination.Match {
+	case shorturl.MatchExact:
+		matches = func(link *shorturl.Link) bool { return link.Normalized() == destination.Target }
+	case shorturl.MatchPrefix:
+		matches = func(link *shorturl.Link) bool { return strings.HasPrefix(link.Normalized(), destination.Target) }
+	case shorturl.MatchHost:
+		matches = func(link *shorturl.Link) bool { return link.Hostname() == destination.Target }
+	default:
+		return nil, errs.InvalidError.New(fmt.Sprintf("unknown match %q", destination.Match))
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []*record
+	for _, rec := range r.records {
+		if rec.surl.ID > destination.AfterID && matches(rec.surl.Link) {
+			matched = append(matched, rec)
+		}
+	}
+
+	slices.SortFunc(matched, func(a, b *record) int {
+		return strings.Compare(string(a.surl.ID), string(b.surl.ID))
+	})
+	if len(matched) > destination.Limit {
+		matched = matched[:destination.Limit]
+	}
+
+	surls := make([]shorturl.SelectableShortURL, len(matched))
+	for i, rec := range matched {
+		surls[i] = rec.output()
+	}
+	return surls, nil
+}
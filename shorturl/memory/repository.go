@@ -0,0 +1,323 @@
+// Package memory implements shorturl.Repository in process memory, for
+// development and tests. Links are lost when the process exits
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+// DefaultTTL is how long a link lives when it is inserted without an expiry
+const DefaultTTL = 24 * time.Hour
+
+type record struct {
+	surl      shorturl.SelectableShortURL
+	updatedAt time.Time
+	// metadata is kept as JSON, so reads decode a fresh copy like a database would
+	metadata []byte
+}
+
+// Repository is safe for concurrent use
+type Repository struct {
+	mu      sync.RWMutex
+	records map[shorturl.ID]*record
+	byName  map[string][]shorturl.ID
+	byKey   map[shorturl.IdempotencyKey][]shorturl.ID
+	history []shorturl.HistoryEntry
+	// keyRoutes serves the sharded repository's idempotency key index
+	keyRoutes map[shorturl.IdempotencyKey]string
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		records: map[shorturl.ID]*record{},
+		byName:  map[string][]shorturl.ID{},
+		byKey:   map[shorturl.IdempotencyKey][]shorturl.ID{},
+	}
+}
+
+// output copies a record, so callers cannot change what is stored
+func (rec *record) output() shorturl.SelectableShortURL {
+	surl := rec.surl
+	surl.Tags = append([]string{}, rec.surl.Tags...)
+
+	link := *rec.surl.Link
+	surl.Link = &link
+
+	surl.Metadata = shorturl.Metadata{}
+	json.Unmarshal(rec.metadata, &surl.Metadata)
+	return surl
+}
+
+func (rec *record) active(now time.Time) bool {
+	return rec.surl.ExpiresAt.After(now)
+}
+
+// activeIn returns an active record among ids, the one expiring last when
+// there are several
+func (r *Repository) activeIn(ids []shorturl.ID, now time.Time) *record {
+	var found *record
+	for _, id := range ids {
+		rec := r.records[id]
+		if rec.active(now) && (found == nil || rec.surl.ExpiresAt.After(found.surl.ExpiresAt)) {
+			found = rec
+		}
+	}
+	return found
+}
+
+func (r *Repository) SelectByName(ctx context.Context, name string) (shorturl.SelectableShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return shorturl.SelectableShortURL{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rec := r.activeIn(r.byName[name], time.Now())
+	if rec == nil {
+		return shorturl.SelectableShortURL{}, errs.NotFoundError.New(fmt.Sprintf("ByName: %q", name))
+	}
+
+	return rec.output(), nil
+}
+
+func (r *Repository) SelectByIdempotencyKey(ctx context.Context, idempotencyKey shorturl.IdempotencyKey) (shorturl.SelectableShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return shorturl.SelectableShortURL{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rec := r.activeIn(r.byKey[idempotencyKey], time.Now())
+	if rec == nil {
+		return shorturl.SelectableShortURL{}, errs.NotFoundError.New(fmt.Sprintf("ByIdempotencyKey: %q", idempotencyKey))
+	}
+
+	return rec.output(), nil
+}
+
+func (r *Repository) SelectByIdempotencyKeys(ctx context.Context, idempotencyKeys []shorturl.IdempotencyKey) ([]shorturl.SelectableShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	seen := map[shorturl.ID]bool{}
+	var found []shorturl.SelectableShortURL
+	for _, key := range idempotencyKeys {
+		for _, id := range r.byKey[key] {
+			if rec := r.records[id]; rec.active(now) && !seen[id] {
+				seen[id] = true
+				found = append(found, rec.output())
+			}
+		}
+	}
+
+	return found, nil
+}
+
+func (r *Repository) SelectByNames(ctx context.Context, names []string) ([]shorturl.SelectableShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	seen := map[string]bool{}
+	var found []shorturl.SelectableShortURL
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		rec := r.activeIn(r.byName[name], now)
+		if rec == nil {
+			// No active link, so the most recently expired one
+			for _, id := range r.byName[name] {
+				if candidate := r.records[id]; rec == nil || candidate.surl.ExpiresAt.After(rec.surl.ExpiresAt) {
+					rec = candidate
+				}
+			}
+		}
+		if rec != nil {
+			found = append(found, rec.output())
+		}
+	}
+
+	return found, nil
+}
+
+// newRecord builds what Insert stores, applying the same defaults as the database
+func newRecord(surl shorturl.ShortURL, now time.Time) (*record, error) {
+	if surl.Link == nil {
+		return nil, errs.NotCreatedErr.New("missing link")
+	}
+
+	metadata := shorturl.Metadata{}
+	if surl.Metadata != nil {
+		metadata = surl.Metadata
+	}
+	encoded, metadataErr := json.Marshal(metadata)
+	if metadataErr != nil {
+		return nil, errs.NotCreatedErr.New(metadataErr.Error())
+	}
+
+	expiresAt := surl.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(DefaultTTL)
+	}
+
+	link := *surl.Link
+	return &record{
+		surl: shorturl.SelectableShortURL{
+			ID:             surl.ID,
+			Name:           surl.Name,
+			Link:           &link,
+			IdempotencyKey: surl.IdempotencyKey,
+			ExpiresAt:      expiresAt,
+			CreatedAt:      now,
+			Details: shorturl.Details{
+				Owner:       surl.Owner,
+				Title:       surl.Title,
+				Description: surl.Description,
+				Tags:        append([]string{}, surl.Tags...),
+			},
+		},
+		updatedAt: now,
+		metadata:  encoded,
+	}, nil
+}
+
+// add stores rec; the caller holds the write lock
+func (r *Repository) add(rec *record) {
+	id := rec.surl.ID
+	r.records[id] = rec
+	r.byName[rec.surl.Name] = append(r.byName[rec.surl.Name], id)
+	if rec.surl.IdempotencyKey != "" {
+		r.byKey[rec.surl.IdempotencyKey] = append(r.byKey[rec.surl.IdempotencyKey], id)
+	}
+}
+
+func (r *Repository) Insert(ctx context.Context, surl shorturl.ShortURL) error {
+	if err := ctx.Err(); err != nil {
+		return errs.NotCreatedErr.New(err.Error())
+	}
+
+	rec, recordErr := newRecord(surl, time.Now())
+	if recordErr != nil {
+		return recordErr
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, taken := r.records[surl.ID]; taken {
+		return errs.NotCreatedErr.New(fmt.Sprintf("duplicate id %q", surl.ID))
+	}
+
+	r.add(rec)
+	return nil
+}
+
+// InsertMany skips links whose ID is stored already, like the database's
+// ON CONFLICT DO NOTHING
+func (r *Repository) InsertMany(ctx context.Context, surls []shorturl.ShortURL) ([]shorturl.ID, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errs.NotCreatedErr.New(err.Error())
+	}
+
+	now := time.Now()
+	records := make([]*record, 0, len(surls))
+	for _, surl := range surls {
+		rec, recordErr := newRecord(surl, now)
+		if recordErr != nil {
+			return nil, recordErr
+		}
+		records = append(records, rec)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Like the database, names are checked against the links stored before
+	// the batch, so repeated names within a batch are all inserted
+	ids := map[shorturl.ID]bool{}
+	var inserted []shorturl.ID
+	var accepted []*record
+	for _, rec := range records {
+		if _, stored := r.records[rec.surl.ID]; stored || ids[rec.surl.ID] {
+			continue
+		}
+		if r.activeIn(r.byName[rec.surl.Name], now) != nil {
+			continue
+		}
+		ids[rec.surl.ID] = true
+		accepted = append(accepted, rec)
+	}
+	for _, rec := range accepted {
+		r.add(rec)
+		inserted = append(inserted, rec.surl.ID)
+	}
+
+	return inserted, nil
+}
+
+func (r *Repository) UpdateLinks(ctx context.Context, changes []shorturl.LinkChange, reason string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	applied := 0
+	for _, change := range changes {
+		rec, ok := r.records[change.ID]
+		if !ok || rec.surl.Link.String() != change.From.String() {
+			continue
+		}
+
+		to := *change.To
+		rec.surl.Link = &to
+		rec.updatedAt = now
+		r.history = append(r.history, shorturl.HistoryEntry{
+			ID:        change.ID,
+			OldLink:   change.From.String(),
+			NewLink:   change.To.String(),
+			Reason:    reason,
+			ChangedAt: now,
+		})
+		applied++
+	}
+
+	return applied, nil
+}
+
+// History returns the destination changes recorded by UpdateLinks, oldest first
+func (r *Repository) History(id shorturl.ID) []shorturl.HistoryEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []shorturl.HistoryEntry
+	for _, entry := range r.history {
+		if entry.ID == id {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
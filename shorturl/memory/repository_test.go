@@ -0,0 +1,75 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
+	"github.com/rcovery/go-url-shortener/shorturl/repotest"
+)
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should not share stored values with callers", func(t *testing.T) {
+		repo := memory.NewRepository()
+		surl := repotest.NewShortURLTo(t, "q3", "https://example.com", time.Time{})
+		surl.Tags = []string{"launch"}
+		if err := repo.Insert(ctx, surl); err != nil {
+			t.Fatalf("Insert() %v", err)
+		}
+		surl.Tags[0] = "changed"
+
+		found, _ := repo.SelectByName(ctx, "q3")
+		found.Link.Host = "changed.example"
+
+		again, _ := repo.SelectByName(ctx, "q3")
+		if again.Tags[0] != "launch" || again.Link.Host != "example.com" {
+			t.Errorf("want the stored link unchanged, got %+v", again)
+		}
+	})
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewRepository()
+	service := shorturl.NewService(repo)
+
+	for i, name := range []string{"c", "a", "b"} {
+		surl := repotest.NewShortURLTo(t, name, "https://docs.example.com/"+name, time.Time{})
+		surl.Metadata = shorturl.Metadata{"campaign": map[string]any{"channel": name, "index": i}}
+		if err := repo.Insert(ctx, surl); err != nil {
+			t.Fatalf("Insert() %v", err)
+		}
+	}
+
+	t.Run("should page through in sort order", func(t *testing.T) {
+		var names []string
+		err := service.Walk(ctx, shorturl.ListFilter{Sort: shorturl.SortNameDesc, Limit: 1}, func(surl shorturl.SelectableShortURL) error {
+			names = append(names, surl.Name)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk() %v", err)
+		}
+		if fmt.Sprint(names) != "[c b a]" {
+			t.Errorf("want [c b a], got %v", names)
+		}
+	})
+
+	t.Run("should filter by nested metadata and domain", func(t *testing.T) {
+		page, err := service.List(ctx, shorturl.ListFilter{
+			Domain:   "example.com",
+			Metadata: shorturl.Metadata{"campaign": map[string]any{"channel": "b"}},
+		})
+		if err != nil {
+			t.Fatalf("List() %v", err)
+		}
+		if len(page.Items) != 1 || page.Items[0].Name != "b" {
+			t.Errorf("want only b, got %+v", page.Items)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"html"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+// Search ranks active links by how many query words appear in their name,
+// title, description, tags or destination. It has none of the stemming or
+// typo tolerance of the Postgres search, but keeps the endpoint usable
+func (r *Repository) Search(ctx context.Context, query string, limit int) ([]shorturl.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	r.mu.RLock()
+	var results []shorturl.SearchResult
+	for _, rec := range r.records {
+		if !rec.active(now) {
+			continue
+		}
+
+		surl := rec.surl
+		text := strings.ToLower(strings.Join([]string{surl.Name, surl.Title, surl.Description, strings.Join(surl.Tags, " "), surl.Link.String()}, " "))
+
+		rank := 0.0
+		for _, word := range words {
+			rank += float64(strings.Count(text, word))
+		}
+		if rank == 0 {
+			continue
+		}
+
+		result := shorturl.SearchResult{SelectableShortURL: rec.output(), Rank: rank}
+		for field, value := range map[string]string{"name": surl.Name, "title": surl.Title, "link": surl.Link.String()} {
+			if highlighted, ok := highlight(value, words); ok {
+				if result.Highlights == nil {
+					result.Highlights = map[string]string{}
+				}
+				result.Highlights[field] = highlighted
+			}
+		}
+		results = append(results, result)
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(results, func(a, b shorturl.SearchResult) int {
+		if a.Rank != b.Rank {
+			if a.Rank > b.Rank {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(string(b.ID), string(a.ID))
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// highlight wraps every occurrence of words in <mark></mark>, escaping the rest as HTML
+func highlight(value string, words []string) (string, bool) {
+	lower := strings.ToLower(value)
+	marked := make([]bool, len(value))
+	found := false
+	for _, word := range words {
+		for start := 0; ; {
+			index := strings.Index(lower[start:], word)
+			if index < 0 {
+				break
+			}
+			for i := start + index; i < start+index+len(word); i++ {
+				marked[i] = true
+			}
+			found = true
+			start += index + len(word)
+		}
+	}
+	if !found || len(lower) != len(value) {
+		// Lowercasing changed byte offsets, so offsets cannot be mapped back
+		return "", false
+	}
+
+	var highlighted strings.Builder
+	for i := 0; i < len(value); {
+		j := i
+		for j < len(value) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			highlighted.WriteString("<mark>" + html.EscapeString(value[i:j]) + "</mark>")
+		} else {
+			highlighted.WriteString(html.EscapeString(value[i:j]))
+		}
+		i = j
+	}
+	return highlighted.String(), true
+}
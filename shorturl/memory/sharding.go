@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+// CopyIn stores links as they are, keeping their IDs and creation times,
+// along with the entries of history about them. Links already present are
+// left alone, history included
+func (r *Repository) CopyIn(ctx context.Context, surls []shorturl.SelectableShortURL, history []shorturl.HistoryEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	records := make([]*record, 0, len(surls))
+	for _, surl := range surls {
+		metadata := surl.Metadata
+		if metadata == nil {
+			metadata = shorturl.Metadata{}
+		}
+		encoded, metadataErr := json.Marshal(metadata)
+		if metadataErr != nil {
+			return errs.NotCreatedErr.New(metadataErr.Error())
+		}
+
+		link := *surl.Link
+		surl.Link = &link
+		surl.Tags = append([]string{}, surl.Tags...)
+		surl.Metadata = nil
+		records = append(records, &record{surl: surl, updatedAt: now, metadata: encoded})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	added := map[shorturl.ID]bool{}
+	for _, rec := range records {
+		if _, present := r.records[rec.surl.ID]; !present {
+			r.add(rec)
+			added[rec.surl.ID] = true
+		}
+	}
+	for _, entry := range history {
+		if added[entry.ID] {
+			r.history = append(r.history, entry)
+		}
+	}
+	return nil
+}
+
+// HistoryOf returns the link history of ids, oldest first
+func (r *Repository) HistoryOf(ctx context.Context, ids []shorturl.ID) ([]shorturl.HistoryEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []shorturl.HistoryEntry
+	for _, entry := range r.history {
+		if slices.Contains(ids, entry.ID) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (r *Repository) DeleteByIDs(ctx context.Context, ids []shorturl.ID) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for _, id := range ids {
+		rec, ok := r.records[id]
+		if !ok {
+			continue
+		}
+
+		delete(r.records, id)
+		r.byName[rec.surl.Name] = slices.DeleteFunc(r.byName[rec.surl.Name], func(other shorturl.ID) bool { return other == id })
+		if key := rec.surl.IdempotencyKey; key != "" {
+			r.byKey[key] = slices.DeleteFunc(r.byKey[key], func(other shorturl.ID) bool { return other == id })
+		}
+		r.history = slices.DeleteFunc(r.history, func(entry shorturl.HistoryEntry) bool { return entry.ID == id })
+		deleted++
+	}
+	return deleted, nil
+}
+
+// PutKeyRoutes records the name each idempotency key was used for
+func (r *Repository) PutKeyRoutes(ctx context.Context, routes map[shorturl.IdempotencyKey]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.keyRoutes == nil {
+		r.keyRoutes = map[shorturl.IdempotencyKey]string{}
+	}
+	for key, name := range routes {
+		r.keyRoutes[key] = name
+	}
+	return nil
+}
+
+// KeyRoutes returns the name recorded for each of keys that has one
+func (r *Repository) KeyRoutes(ctx context.Context, keys []shorturl.IdempotencyKey) (map[shorturl.IdempotencyKey]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	routes := map[shorturl.IdempotencyKey]string{}
+	for _, key := range keys {
+		if name, ok := r.keyRoutes[key]; ok {
+			routes[key] = name
+		}
+	}
+	return routes, nil
+}
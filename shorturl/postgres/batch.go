@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+func (r *Repository) SelectByIdempotencyKeys(ctx context.Context, idempotencyKeys []shorturl.IdempotencyKey) ([]shorturl.SelectableShortURL, error) {
+	if len(idempotencyKeys) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(idempotencyKeys))
+	for i, key := range idempotencyKeys {
+		keys[i] = string(key)
+	}
+
+	var surls []shorturl.SelectableShortURL
+	queryErr := r.Retry.Do(ctx, "SelectByIdempotencyKeys", func() error {
+		rows, err := r.DB.QueryContext(ctx, `
+			SELECT `+selectColumns+`
+			FROM shorturls
+			WHERE idempotency_key = ANY($1)
+				AND expires_at > NOW()
+		`, pq.Array(keys))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		surls, err = scanShortURLs(rows)
+		return err
+	})
+
+	return surls, queryErr
+}
+
+// InsertMany sends the whole batch as one multi-row INSERT, one array
+// parameter per column
+func (r *Repository) InsertMany(ctx context.Context, surls []shorturl.ShortURL) ([]shorturl.ID, error) {
+	if len(surls) == 0 {
+		return nil, nil
+	}
+
+	columns, columnsErr := newBatchColumns(surls)
+	if columnsErr != nil {
+		return nil, columnsErr
+	}
+
+	var inserted []shorturl.ID
+	insertionErr := r.Retry.DoWrite(ctx, "InsertMany", func() error {
+		inserted = nil
+
+		// Rows whose name is taken by an active link are skipped, matching what
+		// Service.Create checks before a single Insert. So are rows reusing a
+		// stored ID, which would otherwise fail the whole batch
+		rows, err := r.DB.QueryContext(ctx, `
+			INSERT INTO shorturls
+			(id, name, link, link_host, link_normalized, idempotency_key, expires_at, owner, title, description, tags, metadata)
+			SELECT
+				v.id::uuid, v.name, v.link, v.link_host, v.link_normalized, NULLIF(v.idempotency_key, ''),
+				COALESCE(NULLIF(v.expires_at, '')::timestamptz, NOW() + INTERVAL '1 day'),
+				NULLIF(v.owner, ''), NULLIF(v.title, ''), NULLIF(v.description, ''),
+				ARRAY(SELECT jsonb_array_elements_text(v.tags::jsonb)),
+				v.metadata::jsonb
+			FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[], $8::text[], $9::text[], $10::text[], $11::text[], $12::text[])
+				AS v(id, name, link, link_host, link_normalized, idempotency_key, expires_at, owner, title, description, tags, metadata)
+			WHERE NOT EXISTS (
+				SELECT 1
+				FROM shorturls s
+				WHERE s.name = v.name
+					AND s.expires_at > NOW()
+			)
+			ON CONFLICT (id) DO NOTHING
+			RETURNING id
+		`,
+			columns.args()...,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id shorturl.ID
+			if scanErr := rows.Scan(&id); scanErr != nil {
+				return scanErr
+			}
+			inserted = append(inserted, id)
+		}
+		return rows.Err()
+	})
+	if insertionErr != nil && isConnectionError(insertionErr) {
+		// The connection may have dropped after the commit. The statement
+		// is atomic, so the stored rows are all the ones it inserted
+		if stored, readErr := r.stored(ctx, surls); readErr == nil && len(stored) > 0 {
+			return stored, nil
+		}
+	}
+	if insertionErr != nil {
+		return nil, errs.NotCreatedErr.New(insertionErr.Error())
+	}
+
+	return inserted, nil
+}
+
+// batchColumns holds one array per column, so a whole batch is sent as
+// twelve parameters and expanded with unnest
+type batchColumns struct {
+	ids, names, links, hosts, normalized, keys, expiresAt []string
+	owners, titles, descriptions, tags, metadata          []string
+}
+
+func newBatchColumns(surls []shorturl.ShortURL) (batchColumns, error) {
+	var columns batchColumns
+	for _, surl := range surls {
+		metadata, metadataErr := marshalMetadata(surl.Metadata)
+		if metadataErr != nil {
+			return columns, errs.NotCreatedErr.New(metadataErr.Error())
+		}
+		tags, tagsErr := json.Marshal(tagsOrEmpty(surl.Tags))
+		if tagsErr != nil {
+			return columns, errs.NotCreatedErr.New(tagsErr.Error())
+		}
+
+		expiresAt := ""
+		if !surl.ExpiresAt.IsZero() {
+			expiresAt = surl.ExpiresAt.Format(time.RFC3339Nano)
+		}
+
+		columns.ids = append(columns.ids, string(surl.ID))
+		columns.names = append(columns.names, surl.Name)
+		columns.links = append(columns.links, surl.Link.String())
+		columns.hosts = append(columns.hosts, surl.Link.Hostname())
+		columns.normalized = append(columns.normalized, surl.Link.Normalized())
+		columns.keys = append(columns.keys, string(surl.IdempotencyKey))
+		columns.expiresAt = append(columns.expiresAt, expiresAt)
+		columns.owners = append(columns.owners, surl.Owner)
+		columns.titles = append(columns.titles, surl.Title)
+		columns.descriptions = append(columns.descriptions, surl.Description)
+		columns.tags = append(columns.tags, string(tags))
+		columns.metadata = append(columns.metadata, string(metadata))
+	}
+
+	return columns, nil
+}
+
+func (c batchColumns) args() []any {
+	return []any{
+		pq.Array(c.ids), pq.Array(c.names), pq.Array(c.links), pq.Array(c.hosts),
+		pq.Array(c.normalized), pq.Array(c.keys), pq.Array(c.expiresAt), pq.Array(c.owners),
+		pq.Array(c.titles), pq.Array(c.descriptions), pq.Array(c.tags), pq.Array(c.metadata),
+	}
+}
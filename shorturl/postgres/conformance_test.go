@@ -0,0 +1,25 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	infra_postgres "github.com/rcovery/go-url-shortener/internal/infra/postgres"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/postgres"
+	"github.com/rcovery/go-url-shortener/shorturl/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	ctx := context.Background()
+	instance, postgresContainer := infra_postgres.SetupContainer(ctx, t)
+	defer infra_postgres.TerminateContainer(postgresContainer)
+
+	// One container for the whole suite, emptied for every subtest
+	repotest.Run(t, func(t *testing.T) shorturl.Repository {
+		if _, err := instance.ExecContext(ctx, "TRUNCATE shorturls CASCADE"); err != nil {
+			t.Fatalf("cannot empty shorturls: %v", err)
+		}
+		return postgres.NewRepository(instance)
+	})
+}
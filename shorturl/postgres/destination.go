@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+func (r *Repository) SelectByDestination(ctx context.Context, destination shorturl.DestinationQuery) ([]shorturl.SelectableShortURL, error) {
+	var q query
+
+	switch destination.Match {
+	case shorturl.MatchExact:
+		q.where("link_normalized = " + q.arg(destination.Target))
+	case shorturl.MatchPrefix:
+		q.where("link_normalized LIKE " + q.arg(likeEscaper.Replace(destination.Target)+"%"))
+	case shorturl.MatchHost:
+		q.where("link_host = " + q.arg(destination.Target))
+	default:
+		return nil, errs.InvalidError.New(fmt.Sprintf("unknown match %q", destination.Match))
+	}
+
+	if destination.AfterID != "" {
+		q.where("id > " + q.arg(destination.AfterID))
+	}
+
+	limit := q.arg(destination.Limit)
+
+	var surls []shorturl.SelectableShortURL
+	readErr := r.read(ctx, "SelectByDestination", func(db *sql.DB) error {
+		rows, queryErr := db.QueryContext(ctx, `
+			SELECT `+selectColumns+`
+			FROM shorturls
+			`+q.whereClause()+`
+			ORDER BY id
+			LIMIT `+limit,
+			q.args...,
+		)
+		if queryErr != nil {
+			return queryErr
+		}
+		defer rows.Close()
+
+		var scanErr error
+		surls, scanErr = scanShortURLs(rows)
+		return scanErr
+	})
+
+	return surls, readErr
+}
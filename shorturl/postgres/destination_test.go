@@ -0,0 +1,64 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/lib/pq"
+	infra_postgres "github.com/rcovery/go-url-shortener/internal/infra/postgres"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/postgres"
+)
+
+func TestSelectByDestination(t *testing.T) {
+	t.Run("should match exact, prefix and host destinations", func(t *testing.T) {
+		ctx := context.Background()
+
+		instance, postgresContainer := infra_postgres.SetupContainer(ctx, t)
+		defer infra_postgres.TerminateContainer(postgresContainer)
+
+		repo := postgres.NewRepository(instance)
+
+		links := map[string]string{
+			"root":   "https://Partner.example.com",
+			"guide":  "https://partner.example.com/guides/setup",
+			"other":  "https://other.example.com/guides/setup",
+			"guide2": "https://partner.example.com/guides/faq#top",
+		}
+		for name, rawLink := range links {
+			id, _ := shorturl.NewID()
+			idempotencyKey, _ := shorturl.NewIdempotencyKey()
+			link, _ := shorturl.NewLink(rawLink)
+
+			insertErr := repo.Insert(ctx, shorturl.ShortURL{ID: id, Name: name, Link: link, IdempotencyKey: idempotencyKey})
+			if insertErr != nil {
+				t.Fatalf("There was an Insert Error %q", insertErr.Error())
+			}
+		}
+
+		tests := []struct {
+			match  shorturl.DestinationMatch
+			target string
+			want   int
+		}{
+			{shorturl.MatchExact, "https://partner.example.com/", 1},
+			{shorturl.MatchPrefix, "https://partner.example.com/guides/", 2},
+			{shorturl.MatchHost, "partner.example.com", 3},
+		}
+		for _, tt := range tests {
+			query, queryErr := shorturl.NewDestinationQuery(tt.match, tt.target)
+			if queryErr != nil {
+				t.Fatalf("NewDestinationQuery() %v", queryErr)
+			}
+			query.Limit = 10
+
+			found, err := repo.SelectByDestination(ctx, query)
+			if err != nil {
+				t.Fatalf("Cannot select by destination, instead got %q", err)
+			}
+			if len(found) != tt.want {
+				t.Errorf("%s %q: want %d links, got %d", tt.match, tt.target, tt.want, len(found))
+			}
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+// query accumulates WHERE conditions and their positional arguments
+type query struct {
+	conditions []string
+	args       []any
+}
+
+func (q *query) arg(value any) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *query) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+func (q *query) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *Repository) List(ctx context.Context, filter shorturl.ListFilter) ([]shorturl.SelectableShortURL, error) {
+	var q query
+
+	if filter.Owner != "" {
+		q.where("owner = " + q.arg(filter.Owner))
+	}
+	if len(filter.Tags) > 0 {
+		q.where("tags @> " + q.arg(pq.Array(filter.Tags)))
+	}
+	if len(filter.Metadata) > 0 {
+		metadata, metadataErr := marshalMetadata(filter.Metadata)
+		if metadataErr != nil {
+			return nil, errs.InvalidError.New(metadataErr.Error())
+		}
+		q.where("metadata @> " + q.arg(metadata) + "::jsonb")
+	}
+	if filter.Host != "" {
+		q.where("link_host = " + q.arg(strings.ToLower(filter.Host)))
+	}
+	if filter.Domain != "" {
+		domain := strings.ToLower(filter.Domain)
+		q.where("(link_host = " + q.arg(domain) + " OR link_host LIKE " + q.arg("%."+likeEscaper.Replace(domain)) + ")")
+	}
+	if !filter.CreatedAfter.IsZero() {
+		q.where("created_at >= " + q.arg(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		q.where("created_at < " + q.arg(filter.CreatedBefore))
+	}
+	if !filter.ExpiresAfter.IsZero() {
+		q.where("expires_at >= " + q.arg(filter.ExpiresAfter))
+	}
+	if !filter.ExpiresBefore.IsZero() {
+		q.where("expires_at < " + q.arg(filter.ExpiresBefore))
+	}
+	if !filter.ChangedAfter.IsZero() {
+		q.where("updated_at >= " + q.arg(filter.ChangedAfter))
+	}
+
+	column, direction, comparison := "", "ASC", ">"
+	if filter.Sort.Descending() {
+		direction, comparison = "DESC", "<"
+	}
+	switch filter.Sort {
+	case shorturl.SortName, shorturl.SortNameDesc:
+		column = "name"
+	case shorturl.SortExpires, shorturl.SortExpiresDesc:
+		column = "expires_at"
+	}
+
+	if filter.After != nil {
+		if column == "" {
+			q.where("id " + comparison + " " + q.arg(filter.After.ID))
+		} else {
+			q.where("(" + column + ", id) " + comparison + " (" + q.arg(filter.After.Key) + ", " + q.arg(filter.After.ID) + ")")
+		}
+	}
+
+	orderBy := "id " + direction
+	if column != "" {
+		orderBy = column + " " + direction + ", " + orderBy
+	}
+
+	limit := q.arg(filter.Limit)
+
+	var surls []shorturl.SelectableShortURL
+	readErr := r.read(ctx, "List", func(db *sql.DB) error {
+		rows, queryErr := db.QueryContext(ctx, `
+			SELECT `+selectColumns+`
+			FROM shorturls
+			`+q.whereClause()+`
+			ORDER BY `+orderBy+`
+			LIMIT `+limit,
+			q.args...,
+		)
+		if queryErr != nil {
+			return queryErr
+		}
+		defer rows.Close()
+
+		var scanErr error
+		surls, scanErr = scanShortURLs(rows)
+		return scanErr
+	})
+
+	return surls, readErr
+}
@@ -0,0 +1,102 @@
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	_ "github.com/lib/pq"
+	infra_postgres "github.com/rcovery/go-url-shortener/internal/infra/postgres"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/postgres"
+)
+
+func TestList(t *testing.T) {
+	t.Run("should page through links with a cursor", func(t *testing.T) {
+		ctx := context.Background()
+
+		instance, postgresContainer := infra_postgres.SetupContainer(ctx, t)
+		defer infra_postgres.TerminateContainer(postgresContainer)
+
+		repo := postgres.NewRepository(instance)
+		service := shorturl.NewService(repo)
+
+		for i := range 5 {
+			id, _ := shorturl.NewID()
+			idempotencyKey, _ := shorturl.NewIdempotencyKey()
+			link, _ := shorturl.NewLink(fmt.Sprintf("https://docs.example.com/%d", i))
+
+			insertErr := repo.Insert(ctx, shorturl.ShortURL{ID: id, Name: fmt.Sprintf("link-%d", i), Link: link, IdempotencyKey: idempotencyKey})
+			if insertErr != nil {
+				t.Fatalf("There was an Insert Error %q", insertErr.Error())
+			}
+		}
+
+		var names []string
+		filter := shorturl.ListFilter{Limit: 2, Sort: shorturl.SortNameDesc}
+		for {
+			page, err := service.List(ctx, filter)
+			if err != nil {
+				t.Fatalf("Cannot list URLs, instead got %q", err)
+			}
+			for _, item := range page.Items {
+				names = append(names, item.Name)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+
+			cursor, _ := shorturl.ParseCursor(page.NextCursor)
+			filter.After = &cursor
+		}
+
+		want := []string{"link-4", "link-3", "link-2", "link-1", "link-0"}
+		if fmt.Sprint(names) != fmt.Sprint(want) {
+			t.Errorf("want %q, got %q", want, names)
+		}
+	})
+
+	t.Run("should filter by tag and domain", func(t *testing.T) {
+		ctx := context.Background()
+
+		instance, postgresContainer := infra_postgres.SetupContainer(ctx, t)
+		defer infra_postgres.TerminateContainer(postgresContainer)
+
+		repo := postgres.NewRepository(instance)
+
+		links := map[string]string{
+			"docs":  "https://docs.example.com/a",
+			"shop":  "https://shop.example.org/b",
+			"email": "https://example.com/c",
+		}
+		for name, rawLink := range links {
+			id, _ := shorturl.NewID()
+			idempotencyKey, _ := shorturl.NewIdempotencyKey()
+			link, _ := shorturl.NewLink(rawLink)
+
+			insertErr := repo.Insert(ctx, shorturl.ShortURL{
+				ID: id, Name: name, Link: link, IdempotencyKey: idempotencyKey,
+				Details: shorturl.Details{Tags: []string{name}},
+			})
+			if insertErr != nil {
+				t.Fatalf("There was an Insert Error %q", insertErr.Error())
+			}
+		}
+
+		byDomain, err := repo.List(ctx, shorturl.ListFilter{Domain: "example.com", Sort: shorturl.SortName, Limit: 10})
+		if err != nil {
+			t.Fatalf("Cannot list URLs, instead got %q", err)
+		}
+		if len(byDomain) != 2 || byDomain[0].Name != "docs" || byDomain[1].Name != "email" {
+			t.Errorf("want docs and email, got %v", byDomain)
+		}
+
+		byTag, err := repo.List(ctx, shorturl.ListFilter{Tags: []string{"shop"}, Sort: shorturl.SortName, Limit: 10})
+		if err != nil {
+			t.Fatalf("Cannot list URLs, instead got %q", err)
+		}
+		if len(byTag) != 1 || byTag[0].Name != "shop" {
+			t.Errorf("want shop, got %v", byTag)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ChangesChannel is where a trigger announces the name of every link
+// inserted, updated or deleted
+const ChangesChannel = "shorturl_changes"
+
+const (
+	listenMinReconnect = time.Second
+	listenMaxReconnect = time.Minute
+	// listenPingInterval finds a dead connection when no change comes through
+	listenPingInterval = 90 * time.Second
+)
+
+// Evicter is a cache that changes are announced to
+type Evicter interface {
+	Invalidate(name string)
+	// Purge drops every entry
+	Purge()
+}
+
+// Evicters announces changes to several caches
+type Evicters []Evicter
+
+func (e Evicters) Invalidate(name string) {
+	for _, evicter := range e {
+		evicter.Invalidate(name)
+	}
+}
+
+func (e Evicters) Purge() {
+	for _, evicter := range e {
+		evicter.Purge()
+	}
+}
+
+// Listen evicts every changed name from evicter until ctx is done. While
+// the connection is down changes go unseen, so the whole cache is purged
+// once it is back
+func Listen(ctx context.Context, dsn string, evicter Evicter) error {
+	listener := pq.NewListener(dsn, listenMinReconnect, listenMaxReconnect, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			log.Println("lost the change notifications connection:", err)
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Println("cannot reconnect for change notifications:", err)
+		}
+	})
+	defer listener.Close()
+
+	if listenErr := listener.Listen(ChangesChannel); listenErr != nil {
+		return listenErr
+	}
+
+	ticker := time.NewTicker(listenPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			// lib/pq sends nil once it has reconnected
+			if notification == nil {
+				evicter.Purge()
+				continue
+			}
+			evicter.Invalidate(notification.Extra)
+		case <-ticker.C:
+			if pingErr := listener.Ping(); pingErr != nil {
+				log.Println("change notifications connection is down:", pingErr)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	infra_postgres "github.com/rcovery/go-url-shortener/internal/infra/postgres"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/postgres"
+)
+
+// evictions records what Listen evicts
+type evictions struct {
+	names  chan string
+	purges chan struct{}
+}
+
+func (e *evictions) Invalidate(name string) { e.names <- name }
+func (e *evictions) Purge()                 { e.purges <- struct{}{} }
+
+func TestListen(t *testing.T) {
+	ctx := context.Background()
+	db, postgresContainer := infra_postgres.SetupContainer(ctx, t)
+	defer infra_postgres.TerminateContainer(postgresContainer)
+
+	connectionString, err := postgresContainer.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("cannot get connection string: %v", err)
+	}
+
+	listenCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	evicted := &evictions{names: make(chan string, 10), purges: make(chan struct{}, 10)}
+	go postgres.Listen(listenCtx, connectionString, evicted)
+
+	waitFor := func(t *testing.T, want string) {
+		t.Helper()
+		for {
+			select {
+			case name := <-evicted.names:
+				// Left over from waiting for the subscription
+				if name == "ping" {
+					continue
+				}
+				if name != want {
+					t.Errorf("want %q evicted, got %q", want, name)
+				}
+			case <-time.After(5 * time.Second):
+				t.Errorf("want %q evicted, got nothing", want)
+			}
+			return
+		}
+	}
+
+	repo := postgres.NewRepository(db)
+	id, _ := shorturl.NewID()
+	link, _ := shorturl.NewLink("https://example.com/notified")
+
+	t.Run("should evict inserted, updated and deleted names", func(t *testing.T) {
+		// Listen subscribes in the background; retry until the first notification arrives
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if _, err := db.ExecContext(ctx, "SELECT pg_notify($1, 'ping')", postgres.ChangesChannel); err != nil {
+				t.Fatalf("cannot notify: %v", err)
+			}
+			select {
+			case <-evicted.names:
+			case <-time.After(100 * time.Millisecond):
+				if time.Now().Before(deadline) {
+					continue
+				}
+				t.Fatalf("Listen never subscribed")
+			}
+			break
+		}
+
+		if err := repo.Insert(ctx, shorturl.ShortURL{ID: id, Name: "notified", Link: link}); err != nil {
+			t.Fatalf("Insert() %v", err)
+		}
+		waitFor(t, "notified")
+
+		moved, _ := shorturl.NewLink("https://example.org/moved")
+		if _, err := repo.UpdateLinks(ctx, []shorturl.LinkChange{{ID: id, Name: "notified", From: link, To: moved}}, "test"); err != nil {
+			t.Fatalf("UpdateLinks() %v", err)
+		}
+		waitFor(t, "notified")
+
+		if _, err := repo.DeleteByIDs(ctx, []shorturl.ID{id}); err != nil {
+			t.Fatalf("DeleteByIDs() %v", err)
+		}
+		waitFor(t, "notified")
+	})
+
+	t.Run("should purge everything after reconnecting", func(t *testing.T) {
+		_, err := db.ExecContext(ctx, `
+			SELECT pg_terminate_backend(pid)
+			FROM pg_stat_activity
+			WHERE query LIKE 'LISTEN%' AND pid <> pg_backend_pid()
+		`)
+		if err != nil {
+			t.Fatalf("cannot drop the listening connection: %v", err)
+		}
+
+		select {
+		case <-evicted.purges:
+		case <-time.After(10 * time.Second):
+			t.Errorf("want a purge after reconnecting")
+		}
+	})
+}
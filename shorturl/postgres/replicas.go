@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// read runs fn on a healthy replica, in turn, or on the primary when there
+// is none. When a replica cannot be reached it is marked down and fn runs
+// again on the primary, so a failing replica costs one extra attempt.
+// Transient errors are then retried as r.Retry allows
+func (r *Repository) read(ctx context.Context, operation string, fn func(db *sql.DB) error) error {
+	return r.Retry.Do(ctx, operation, func() error {
+		return r.readOnce(ctx, fn)
+	})
+}
+
+func (r *Repository) readOnce(ctx context.Context, fn func(db *sql.DB) error) error {
+	// A fresh lookup can't wait for a replica to catch up
+	if shorturl.IsFresh(ctx) {
+		return fn(r.DB)
+	}
+
+	chosen := r.pickReplica()
+	if chosen == nil {
+		return fn(r.DB)
+	}
+
+	err := fn(chosen.db)
+	if err == nil || ctx.Err() != nil || !isConnectionError(err) {
+		return err
+	}
+
+	if chosen.healthy.CompareAndSwap(true, false) {
+		log.Println("postgres replica marked down:", err)
+	}
+	return fn(r.DB)
+}
+
+func (r *Repository) pickReplica() *replica {
+	count := uint64(len(r.replicas))
+	if count == 0 {
+		return nil
+	}
+
+	start := r.nextReplica.Add(1)
+	for i := range count {
+		if candidate := r.replicas[(start+i)%count]; candidate.healthy.Load() {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// MonitorReplicas pings every replica each interval until ctx is done,
+// bringing back the ones that answer and taking down the ones that don't
+func (r *Repository) MonitorReplicas(ctx context.Context, interval time.Duration) {
+	if len(r.replicas) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, candidate := range r.replicas {
+				pingCtx, cancel := context.WithTimeout(ctx, interval)
+				pingErr := candidate.db.PingContext(pingCtx)
+				cancel()
+
+				healthy := pingErr == nil
+				if candidate.healthy.Swap(healthy) != healthy {
+					if healthy {
+						log.Println("postgres replica back up")
+					} else {
+						log.Println("postgres replica marked down:", pingErr)
+					}
+				}
+			}
+		}
+	}
+}
+
+// isConnectionError tells failures of the server or the network, worth
+// retrying elsewhere, from errors in the query itself
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Class 08 is connection exception, 57P0x an administrator or
+		// crash shutdown, 53300 too many connections
+		code := string(pqErr.Code)
+		return strings.HasPrefix(code, "08") || strings.HasPrefix(code, "57P0") || code == "53300"
+	}
+
+	return false
+}
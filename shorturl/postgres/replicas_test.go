@@ -0,0 +1,116 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	infra_postgres "github.com/rcovery/go-url-shortener/internal/infra/postgres"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+	"github.com/rcovery/go-url-shortener/shorturl/postgres"
+)
+
+func TestReplicas(t *testing.T) {
+	ctx := context.Background()
+	primary, postgresContainer := infra_postgres.SetupContainer(ctx, t)
+	defer infra_postgres.TerminateContainer(postgresContainer)
+
+	// A second database in the same server stands in for a replica, so
+	// which one answered shows where a read went
+	if _, err := primary.ExecContext(ctx, "CREATE DATABASE replica"); err != nil {
+		t.Fatalf("cannot create the replica database: %v", err)
+	}
+	connectionString, err := postgresContainer.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("cannot get connection string: %v", err)
+	}
+	replica := infra_postgres.SetupDatabase(ctx, t, strings.Replace(connectionString, "/DBDATABASE?", "/replica?", 1))
+	infra_postgres.SetupMigrations(ctx, t, replica)
+
+	newShortURL := func(name string) shorturl.ShortURL {
+		id, _ := shorturl.NewID()
+		idempotencyKey, _ := shorturl.NewIdempotencyKey()
+		link, _ := shorturl.NewLink("https://example.com/" + name)
+		return shorturl.ShortURL{ID: id, Name: name, Link: link, IdempotencyKey: idempotencyKey}
+	}
+
+	t.Run("should read from replicas and write to the primary", func(t *testing.T) {
+		onReplica := newShortURL("on-replica")
+		if err := postgres.NewRepository(replica).Insert(ctx, onReplica); err != nil {
+			t.Fatalf("Insert() %v", err)
+		}
+
+		repo := postgres.NewRepository(primary, replica)
+		written := newShortURL("written")
+		if err := repo.Insert(ctx, written); err != nil {
+			t.Fatalf("Insert() %v", err)
+		}
+
+		if _, err := repo.SelectByName(ctx, onReplica.Name); err != nil {
+			t.Errorf("want %q read from the replica, got %v", onReplica.Name, err)
+		}
+		if _, err := repo.SelectByName(ctx, written.Name); !errors.Is(err, errs.NotFoundError) {
+			t.Errorf("want %q not on the replica, got %v", written.Name, err)
+		}
+		if found, err := repo.SelectByIdempotencyKey(ctx, written.IdempotencyKey); err != nil || found.ID != written.ID {
+			t.Errorf("want the idempotency lookup on the primary, got %+v %v", found, err)
+		}
+	})
+
+	t.Run("should read fresh lookups from the primary", func(t *testing.T) {
+		repo := postgres.NewRepository(primary, replica)
+		written := newShortURL("fresh")
+		if err := repo.Insert(ctx, written); err != nil {
+			t.Fatalf("Insert() %v", err)
+		}
+
+		if found, err := repo.SelectByName(shorturl.Fresh(ctx), written.Name); err != nil || found.ID != written.ID {
+			t.Errorf("want %q from the primary, got %+v %v", written.Name, found, err)
+		}
+	})
+
+	t.Run("should refuse a name taken on the primary while the replica lags", func(t *testing.T) {
+		repo := postgres.NewRepository(primary, replica)
+		service := shorturl.NewService(repo)
+		first := newShortURL("lagging")
+		if err := repo.Insert(ctx, first); err != nil {
+			t.Fatalf("Insert() %v", err)
+		}
+
+		second := newShortURL("lagging")
+		_, err := service.Create(ctx, second.ID, second.IdempotencyKey, second.Name, second.Link, shorturl.Details{})
+		if !errors.Is(err, errs.AlreadyExistsError) {
+			t.Errorf("want %v, got %v", errs.AlreadyExistsError, err)
+		}
+		if found, err := repo.SelectByIdempotencyKey(ctx, first.IdempotencyKey); err != nil || found.ID != first.ID {
+			t.Errorf("want %q still stored, got %+v %v", first.ID, found, err)
+		}
+	})
+
+	t.Run("should fail over to the primary when a replica is down", func(t *testing.T) {
+		unreachable, err := infra_postgres.NewDatabaseConnection("host=127.0.0.1 port=1 user=x dbname=x sslmode=disable connect_timeout=1")
+		if err != nil {
+			t.Fatalf("cannot open the unreachable replica: %v", err)
+		}
+		defer unreachable.Close()
+
+		repo := postgres.NewRepository(primary, unreachable)
+		written := newShortURL("failover")
+		if err := repo.Insert(ctx, written); err != nil {
+			t.Fatalf("Insert() %v", err)
+		}
+
+		start := time.Now()
+		for range 3 {
+			if _, err := repo.SelectByName(ctx, written.Name); err != nil {
+				t.Errorf("want %q from the primary, got %v", written.Name, err)
+			}
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("want the replica skipped after the first failure, took %v", elapsed)
+		}
+	})
+}
@@ -3,88 +3,236 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sync/atomic"
 
+	"github.com/lib/pq"
 	"github.com/rcovery/go-url-shortener/shorturl"
 	"github.com/rcovery/go-url-shortener/shorturl/errs"
 )
 
+// Repository writes to the primary DB. Reads go to the replicas when
+// there are any, except idempotency lookups, which must see writes made
+// moments before. Calls failing with a Transient error are retried as
+// Retry allows
 type Repository struct {
-	DB *sql.DB
+	DB    *sql.DB
+	Retry RetryPolicy
+
+	replicas    []*replica
+	nextReplica atomic.Uint64
 }
 
-func NewRepository(DB *sql.DB) *Repository {
-	return &Repository{
-		DB,
+func NewRepository(DB *sql.DB, replicas ...*sql.DB) *Repository {
+	repository := &Repository{
+		DB:    DB,
+		Retry: DefaultRetryPolicy,
+	}
+	for _, db := range replicas {
+		candidate := &replica{db: db}
+		candidate.healthy.Store(true)
+		repository.replicas = append(repository.replicas, candidate)
 	}
+
+	return repository
 }
 
-func (r *Repository) SelectByName(ctx context.Context, name string) (shorturl.SelectableShortURL, error) {
-	row := r.DB.QueryRowContext(ctx, `
-		SELECT id, link
-		FROM shorturls
-		WHERE name = $1
-			AND expires_at > NOW()
-		LIMIT 1
-	`, name)
+const selectColumns = `id, name, link, COALESCE(idempotency_key, ''), expires_at, created_at, COALESCE(owner, ''), COALESCE(title, ''), COALESCE(description, ''), tags, metadata`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
 
+func scanShortURL(row scanner) (shorturl.SelectableShortURL, error) {
 	var rawDBLink string
+	var rawMetadata []byte
 	var surl shorturl.SelectableShortURL
 
-	scanErr := row.Scan(&surl.ID, &rawDBLink)
+	scanErr := row.Scan(
+		&surl.ID,
+		&surl.Name,
+		&rawDBLink,
+		&surl.IdempotencyKey,
+		&surl.ExpiresAt,
+		&surl.CreatedAt,
+		&surl.Owner,
+		&surl.Title,
+		&surl.Description,
+		pq.Array(&surl.Tags),
+		&rawMetadata,
+	)
 	if scanErr != nil {
-		return surl, errs.NotFoundError.New(fmt.Sprintf("ByName: %v", scanErr))
+		return surl, scanErr
 	}
 
 	link, linkErr := shorturl.NewLink(rawDBLink)
 	if linkErr != nil {
-		return surl, errs.NotFoundError.New(fmt.Sprintf("ByName: %v", scanErr))
+		return surl, linkErr
 	}
-
 	surl.Link = link
 
+	if len(rawMetadata) > 0 {
+		if metadataErr := json.Unmarshal(rawMetadata, &surl.Metadata); metadataErr != nil {
+			return surl, metadataErr
+		}
+	}
+
 	return surl, nil
 }
 
-func (r *Repository) SelectByIdempotencyKey(ctx context.Context, idempotencyKey shorturl.IdempotencyKey) (shorturl.SelectableShortURL, error) {
-	row := r.DB.QueryRowContext(ctx, `
-		SELECT id, link
-		FROM shorturls
-		WHERE idempotency_key = $1
-			AND expires_at > NOW()
-		LIMIT 1
-	`, idempotencyKey)
-
-	var rawDBLink string
-	var surl shorturl.SelectableShortURL
+func scanShortURLs(rows *sql.Rows) ([]shorturl.SelectableShortURL, error) {
+	var surls []shorturl.SelectableShortURL
+	for rows.Next() {
+		surl, scanErr := scanShortURL(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
 
-	scanErr := row.Scan(&surl.ID, &rawDBLink)
-	if scanErr != nil {
-		return surl, errs.NotFoundError.New(fmt.Sprintf("ByIdempotencyKey: %v", scanErr))
+		surls = append(surls, surl)
 	}
 
-	link, linkErr := shorturl.NewLink(rawDBLink)
-	if linkErr != nil {
+	return surls, rows.Err()
+}
+
+func (r *Repository) SelectByName(ctx context.Context, name string) (shorturl.SelectableShortURL, error) {
+	var surl shorturl.SelectableShortURL
+	scanErr := r.read(ctx, "SelectByName", func(db *sql.DB) error {
+		row := db.QueryRowContext(ctx, `
+			SELECT `+selectColumns+`
+			FROM shorturls
+			WHERE name = $1
+				AND expires_at > NOW()
+			LIMIT 1
+		`, name)
+
+		var err error
+		surl, err = scanShortURL(row)
+		return err
+	})
+	if errors.Is(scanErr, sql.ErrNoRows) {
 		return surl, errs.NotFoundError.New(fmt.Sprintf("ByName: %v", scanErr))
 	}
+	if scanErr != nil {
+		return surl, scanErr
+	}
 
-	surl.Link = link
+	return surl, nil
+}
+
+func (r *Repository) SelectByIdempotencyKey(ctx context.Context, idempotencyKey shorturl.IdempotencyKey) (shorturl.SelectableShortURL, error) {
+	var surl shorturl.SelectableShortURL
+	scanErr := r.Retry.Do(ctx, "SelectByIdempotencyKey", func() error {
+		row := r.DB.QueryRowContext(ctx, `
+			SELECT `+selectColumns+`
+			FROM shorturls
+			WHERE idempotency_key = $1
+				AND expires_at > NOW()
+			LIMIT 1
+		`, idempotencyKey)
+
+		var err error
+		surl, err = scanShortURL(row)
+		return err
+	})
+	if errors.Is(scanErr, sql.ErrNoRows) {
+		return surl, errs.NotFoundError.New(fmt.Sprintf("ByIdempotencyKey: %v", scanErr))
+	}
+	if scanErr != nil {
+		return surl, scanErr
+	}
 
 	return surl, nil
 }
 
-func (r *Repository) Insert(ctx context.Context, id shorturl.ID, name string, link *shorturl.Link, idempotencyKey shorturl.IdempotencyKey) error {
-	_, insertionErr := r.DB.ExecContext(ctx, `
-		INSERT INTO shorturls
-		(id, name, link, idempotency_key)
-		VALUES
-		($1, $2, $3, $4)
-	`, id, name, link.String(), idempotencyKey,
-	)
+func (r *Repository) Insert(ctx context.Context, surl shorturl.ShortURL) error {
+	metadata, metadataErr := marshalMetadata(surl.Metadata)
+	if metadataErr != nil {
+		return errs.NotCreatedErr.New(metadataErr.Error())
+	}
+
+	var inserted int64
+	insertionErr := r.Retry.DoWrite(ctx, "Insert", func() error {
+		// The name check in Service.Create may read a lagging replica, so
+		// the primary checks it again: a name taken by an active link
+		// inserts nothing
+		result, err := r.DB.ExecContext(ctx, `
+			INSERT INTO shorturls
+			(id, name, link, link_host, link_normalized, idempotency_key, expires_at, owner, title, description, tags, metadata)
+			SELECT
+				$1::uuid, $2, $3, $4, $5, $6, COALESCE($7::timestamptz, NOW() + INTERVAL '1 day'),
+				NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), $11::text[], $12::jsonb
+			WHERE NOT EXISTS (
+				SELECT 1
+				FROM shorturls
+				WHERE name = $2
+					AND expires_at > NOW()
+			)
+		`, surl.ID, surl.Name, surl.Link.String(), surl.Link.Hostname(), surl.Link.Normalized(), surl.IdempotencyKey, nullTime(surl.ExpiresAt),
+			surl.Owner, surl.Title, surl.Description, pq.Array(tagsOrEmpty(surl.Tags)), metadata,
+		)
+		if err != nil {
+			return err
+		}
+		inserted, err = result.RowsAffected()
+		return err
+	})
+	if insertionErr != nil && isConnectionError(insertionErr) {
+		// The connection may have dropped after the commit
+		if stored, readErr := r.stored(ctx, []shorturl.ShortURL{surl}); readErr == nil && len(stored) == 1 {
+			return nil
+		}
+	}
 
 	if insertionErr != nil {
 		return errs.NotCreatedErr.New(insertionErr.Error())
 	}
+	if inserted == 0 {
+		// A retry after a commit whose reply was lost finds its own link
+		if stored, readErr := r.stored(ctx, []shorturl.ShortURL{surl}); readErr == nil && len(stored) == 1 {
+			return nil
+		}
+		return errs.AlreadyExistsError.New(fmt.Sprintf("cannot create a new URL with %q", surl.Name))
+	}
 
 	return nil
 }
+
+// stored reads back the IDs of surls that are in the table with their
+// name, to learn whether a write whose outcome is unknown went through
+func (r *Repository) stored(ctx context.Context, surls []shorturl.ShortURL) ([]shorturl.ID, error) {
+	ids := make([]string, len(surls))
+	names := make([]string, len(surls))
+	for i, surl := range surls {
+		ids[i] = string(surl.ID)
+		names[i] = surl.Name
+	}
+
+	var found []shorturl.ID
+	readErr := r.Retry.Do(ctx, "stored", func() error {
+		found = nil
+
+		rows, err := r.DB.QueryContext(ctx, `
+			SELECT s.id
+			FROM shorturls s
+			JOIN unnest($1::uuid[], $2::text[]) AS v(id, name)
+				ON s.id = v.id AND s.name = v.name
+		`, pq.Array(ids), pq.Array(names))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id shorturl.ID
+			if scanErr := rows.Scan(&id); scanErr != nil {
+				return scanErr
+			}
+			found = append(found, id)
+		}
+		return rows.Err()
+	})
+
+	return found, readErr
+}
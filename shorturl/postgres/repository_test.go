@@ -24,7 +24,7 @@ func TestSelect(t *testing.T) {
 		name := "RCovery"
 		link, _ := shorturl.NewLink("https://neocities.org")
 
-		insertErr := repo.Insert(ctx, id, name, link, idempotencyKey)
+		insertErr := repo.Insert(ctx, shorturl.ShortURL{ID: id, Name: name, Link: link, IdempotencyKey: idempotencyKey})
 		if insertErr != nil {
 			t.Fatalf("There was an Insert Error %q", insertErr.Error())
 		}
@@ -55,7 +55,7 @@ func TestSelect(t *testing.T) {
 		name := "RCovery"
 		link, _ := shorturl.NewLink("https://neocities.org")
 
-		insertErr := repo.Insert(ctx, id, name, link, idempotencyKey)
+		insertErr := repo.Insert(ctx, shorturl.ShortURL{ID: id, Name: name, Link: link, IdempotencyKey: idempotencyKey})
 		if insertErr != nil {
 			t.Fatalf("There was an Insert Error %q", insertErr.Error())
 		}
@@ -72,4 +72,43 @@ func TestSelect(t *testing.T) {
 			t.Errorf("want %q, got %q", link, foundShorturl.Link)
 		}
 	})
+
+	t.Run("Selecting details", func(t *testing.T) {
+		ctx := context.Background()
+
+		instance, postgresContainer := infra_postgres.SetupContainer(ctx, t)
+		defer infra_postgres.TerminateContainer(postgresContainer)
+
+		repo := postgres.NewRepository(instance)
+
+		id, _ := shorturl.NewID()
+		idempotencyKey, _ := shorturl.NewIdempotencyKey()
+		name := "q3-deck"
+		link, _ := shorturl.NewLink("https://example.com/q3.pdf")
+		details := shorturl.Details{
+			Title:    "Q3 deck",
+			Tags:     []string{"email", "launch"},
+			Metadata: shorturl.Metadata{"channel": "newsletter"},
+		}
+
+		insertErr := repo.Insert(ctx, shorturl.ShortURL{ID: id, Name: name, Link: link, IdempotencyKey: idempotencyKey, Details: details})
+		if insertErr != nil {
+			t.Fatalf("There was an Insert Error %q", insertErr.Error())
+		}
+
+		foundShorturl, err := repo.SelectByName(ctx, name)
+		if err != nil {
+			t.Fatalf("Cannot get URL by name, instead got %q", err)
+		}
+
+		if foundShorturl.Title != details.Title {
+			t.Errorf("want %q, got %q", details.Title, foundShorturl.Title)
+		}
+		if len(foundShorturl.Tags) != 2 || foundShorturl.Tags[0] != "email" {
+			t.Errorf("want %q, got %q", details.Tags, foundShorturl.Tags)
+		}
+		if foundShorturl.Metadata["channel"] != "newsletter" {
+			t.Errorf("want %q, got %q", "newsletter", foundShorturl.Metadata["channel"])
+		}
+	})
 }
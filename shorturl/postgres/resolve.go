@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+func (r *Repository) SelectByNames(ctx context.Context, names []string) ([]shorturl.SelectableShortURL, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var surls []shorturl.SelectableShortURL
+	readErr := r.read(ctx, "SelectByNames", func(db *sql.DB) error {
+		rows, queryErr := db.QueryContext(ctx, `
+			SELECT DISTINCT ON (name) `+selectColumns+`
+			FROM shorturls
+			WHERE name = ANY($1)
+			ORDER BY name, expires_at > NOW() DESC, expires_at DESC
+		`, pq.Array(names))
+		if queryErr != nil {
+			return queryErr
+		}
+		defer rows.Close()
+
+		var scanErr error
+		surls, scanErr = scanShortURLs(rows)
+		return scanErr
+	})
+
+	return surls, readErr
+}
@@ -0,0 +1,57 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	infra_postgres "github.com/rcovery/go-url-shortener/internal/infra/postgres"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/postgres"
+)
+
+func TestSelectByNames(t *testing.T) {
+	t.Run("should resolve active, expired and unknown names at once", func(t *testing.T) {
+		ctx := context.Background()
+
+		instance, postgresContainer := infra_postgres.SetupContainer(ctx, t)
+		defer infra_postgres.TerminateContainer(postgresContainer)
+
+		repo := postgres.NewRepository(instance)
+		service := shorturl.NewService(repo)
+
+		expiresAt := map[string]time.Time{
+			"active":  time.Now().Add(time.Hour),
+			"expired": time.Now().Add(-time.Hour),
+		}
+		for name, expiry := range expiresAt {
+			id, _ := shorturl.NewID()
+			idempotencyKey, _ := shorturl.NewIdempotencyKey()
+			link, _ := shorturl.NewLink("https://example.com/" + name)
+
+			insertErr := repo.Insert(ctx, shorturl.ShortURL{ID: id, Name: name, Link: link, IdempotencyKey: idempotencyKey, ExpiresAt: expiry})
+			if insertErr != nil {
+				t.Fatalf("There was an Insert Error %q", insertErr.Error())
+			}
+		}
+
+		resolutions, err := service.Resolve(ctx, []string{"expired", "unknown", "active", "expired"})
+		if err != nil {
+			t.Fatalf("Cannot resolve names, instead got %q", err)
+		}
+
+		want := []shorturl.ResolveStatus{shorturl.ResolveExpired, shorturl.ResolveNotFound, shorturl.ResolveActive}
+		if len(resolutions) != len(want) {
+			t.Fatalf("want %d resolutions, got %v", len(want), resolutions)
+		}
+		for i, status := range want {
+			if resolutions[i].Status != status {
+				t.Errorf("%s: want %q, got %q", resolutions[i].Name, status, resolutions[i].Status)
+			}
+		}
+		if resolutions[2].Link == nil || resolutions[2].Link.String() != "https://example.com/active" {
+			t.Errorf("want %q, got %q", "https://example.com/active", resolutions[2].Link)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:  3,
+	BaseDelay: 10 * time.Millisecond,
+	MaxDelay:  250 * time.Millisecond,
+}
+
+// RetryPolicy bounds how transient errors are retried
+type RetryPolicy struct {
+	// Attempts is how many times a call runs at most, the first included.
+	// 0 or 1 turns retries off
+	Attempts int
+	// BaseDelay is the longest wait before the first retry. It doubles for
+	// every retry after that, up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// retries is made from the global meter, which hands it over to the
+// provider main sets up later
+var retries, _ = otel.Meter("github.com/rcovery/go-url-shortener/shorturl/postgres").Int64Counter(
+	"shorturl.postgres.retries",
+	metric.WithDescription("Database calls retried after a transient error, by operation"),
+)
+
+// Do runs fn, and again after a jittered backoff while it fails with a
+// Transient error. A retry is only made when its wait ends before the
+// deadline of ctx; otherwise the last error is returned. Every retry is
+// counted and recorded as an event on the span in ctx
+func (p RetryPolicy) Do(ctx context.Context, operation string, fn func() error) error {
+	return p.do(ctx, operation, Transient, fn)
+}
+
+// DoWrite is Do for writes, which are only run again when the error shows
+// the first run had no effect. A connection lost midway may have been lost
+// after the commit, so that error is returned for the caller to check
+func (p RetryPolicy) DoWrite(ctx context.Context, operation string, fn func() error) error {
+	return p.do(ctx, operation, Unapplied, fn)
+}
+
+func (p RetryPolicy) do(ctx context.Context, operation string, retryable func(error) bool, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Attempts || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return err
+		}
+
+		retries.Add(ctx, 1, metric.WithAttributes(attribute.String("operation", operation)))
+		trace.SpanFromContext(ctx).AddEvent("db.retry", trace.WithAttributes(
+			attribute.String("db.operation", operation),
+			attribute.Int("retry.attempt", attempt),
+			attribute.Int64("retry.delay_ms", delay.Milliseconds()),
+			attribute.String("error", err.Error()),
+		))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff waits anywhere up to the doubled delay, so callers that failed
+// together don't retry together
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.MaxDelay
+	if shifted := p.BaseDelay << (attempt - 1); shifted > 0 && shifted < ceiling {
+		ceiling = shifted
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling)
+}
+
+// Transient tells errors that may go away on their own, like a dropped
+// connection or a transaction that lost a race, from errors that would
+// come back on every attempt
+func Transient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if isConnectionError(err) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// 40001 is a serialization failure, 40P01 a deadlock and 55P03 a
+		// lock that could not be taken
+		switch pqErr.Code {
+		case "40001", "40P01", "55P03":
+			return true
+		}
+	}
+
+	return false
+}
+
+// Unapplied tells errors that prove a write changed nothing: a connection
+// found broken before the statement was sent, or a transaction Postgres
+// rolled back to settle a serialization failure or a deadlock
+func Unapplied(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40001" || pqErr.Code == "40P01"
+	}
+
+	return false
+}
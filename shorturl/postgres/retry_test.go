@@ -0,0 +1,166 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/rcovery/go-url-shortener/shorturl/postgres"
+)
+
+func TestTransient(t *testing.T) {
+	cases := []struct {
+		err       error
+		transient bool
+	}{
+		{driver.ErrBadConn, true},
+		{fmt.Errorf("reading: %w", io.ErrUnexpectedEOF), true},
+		{&pq.Error{Code: "08006"}, true},
+		{&pq.Error{Code: "57P01"}, true},
+		{&pq.Error{Code: "40001"}, true},
+		{&pq.Error{Code: "40P01"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{&pq.Error{Code: "42601"}, false},
+		{sql.ErrNoRows, false},
+		{context.DeadlineExceeded, false},
+		{nil, false},
+	}
+
+	for _, c := range cases {
+		if got := postgres.Transient(c.err); got != c.transient {
+			t.Errorf("%v: want transient %t, got %t", c.err, c.transient, got)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+	policy := postgres.RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	serializationFailure := &pq.Error{Code: "40001"}
+
+	// failing returns fn, failing with err the first failures times
+	failing := func(failures int, err error) (func() error, *int) {
+		calls := 0
+		return func() error {
+			calls++
+			if calls <= failures {
+				return err
+			}
+			return nil
+		}, &calls
+	}
+
+	t.Run("should retry transient errors until one succeeds", func(t *testing.T) {
+		fn, calls := failing(2, serializationFailure)
+		if err := policy.Do(ctx, "test", fn); err != nil {
+			t.Errorf("want success on the third attempt, got %v", err)
+		}
+		if *calls != 3 {
+			t.Errorf("want 3 calls, got %d", *calls)
+		}
+	})
+
+	t.Run("should give up after the last attempt", func(t *testing.T) {
+		fn, calls := failing(5, serializationFailure)
+		if err := policy.Do(ctx, "test", fn); !errors.Is(err, serializationFailure) {
+			t.Errorf("want the last error, got %v", err)
+		}
+		if *calls != 3 {
+			t.Errorf("want 3 calls, got %d", *calls)
+		}
+	})
+
+	t.Run("should not retry permanent errors", func(t *testing.T) {
+		fn, calls := failing(1, &pq.Error{Code: "23505"})
+		policy.Do(ctx, "test", fn)
+		if *calls != 1 {
+			t.Errorf("want 1 call, got %d", *calls)
+		}
+	})
+
+	t.Run("should only retry writes that had no effect", func(t *testing.T) {
+		for _, c := range []struct {
+			err   error
+			calls int
+		}{
+			{serializationFailure, 2},
+			{&pq.Error{Code: "40P01"}, 2},
+			{driver.ErrBadConn, 2},
+			{io.ErrUnexpectedEOF, 1},
+			{&pq.Error{Code: "08006"}, 1},
+		} {
+			fn, calls := failing(1, c.err)
+			policy.DoWrite(ctx, "test", fn)
+			if *calls != c.calls {
+				t.Errorf("%v: want %d calls, got %d", c.err, c.calls, *calls)
+			}
+		}
+	})
+
+	t.Run("should not wait past the deadline", func(t *testing.T) {
+		slow := postgres.RetryPolicy{Attempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+		deadlineCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		fn, calls := failing(1, serializationFailure)
+		started := time.Now()
+		if err := slow.Do(deadlineCtx, "test", fn); !errors.Is(err, serializationFailure) {
+			t.Errorf("want the first error, got %v", err)
+		}
+		if *calls != 1 || time.Since(started) > time.Second {
+			t.Errorf("want a single call returning right away, got %d calls in %s", *calls, time.Since(started))
+		}
+	})
+
+	t.Run("should record retries as span events and metrics", func(t *testing.T) {
+		reader := sdkmetric.NewManualReader()
+		otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+		recorder := tracetest.NewSpanRecorder()
+		tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+		spanCtx, span := tracer.Start(ctx, "query")
+		fn, _ := failing(2, serializationFailure)
+		policy.Do(spanCtx, "SelectByName", fn)
+		span.End()
+
+		events := recorder.Ended()[0].Events()
+		if len(events) != 2 || events[0].Name != "db.retry" {
+			t.Errorf("want 2 db.retry events, got %v", events)
+		}
+
+		var collected metricdata.ResourceMetrics
+		if err := reader.Collect(ctx, &collected); err != nil {
+			t.Fatalf("cannot collect metrics: %v", err)
+		}
+
+		var retried int64
+		for _, scope := range collected.ScopeMetrics {
+			for _, m := range scope.Metrics {
+				sum, ok := m.Data.(metricdata.Sum[int64])
+				if m.Name != "shorturl.postgres.retries" || !ok {
+					continue
+				}
+				for _, point := range sum.DataPoints {
+					if operation, _ := point.Attributes.Value("operation"); operation.AsString() == "SelectByName" {
+						retried += point.Value
+					}
+				}
+			}
+		}
+		if retried != 2 {
+			t.Errorf("want 2 retries counted, got %d", retried)
+		}
+	})
+}
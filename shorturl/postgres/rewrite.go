@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+// UpdateLinks runs again from the start when its transaction is rolled
+// back by a serialization failure or a deadlock
+func (r *Repository) UpdateLinks(ctx context.Context, changes []shorturl.LinkChange, reason string) (int, error) {
+	var applied int
+	updateErr := r.Retry.DoWrite(ctx, "UpdateLinks", func() error {
+		var err error
+		applied, err = r.updateLinks(ctx, changes, reason)
+		return err
+	})
+
+	return applied, updateErr
+}
+
+func (r *Repository) updateLinks(ctx context.Context, changes []shorturl.LinkChange, reason string) (int, error) {
+	tx, txErr := r.DB.BeginTx(ctx, nil)
+	if txErr != nil {
+		return 0, txErr
+	}
+	defer tx.Rollback()
+
+	applied := 0
+	for _, change := range changes {
+		result, updateErr := tx.ExecContext(ctx, `
+			UPDATE shorturls
+			SET link = $1, link_host = $2, link_normalized = $3, updated_at = NOW()
+			WHERE id = $4
+				AND link = $5
+		`, change.To.String(), change.To.Hostname(), change.To.Normalized(), change.ID, change.From.String())
+		if updateErr != nil {
+			return 0, updateErr
+		}
+
+		updated, rowsErr := result.RowsAffected()
+		if rowsErr != nil {
+			return 0, rowsErr
+		}
+		if updated == 0 {
+			continue
+		}
+
+		_, historyErr := tx.ExecContext(ctx, `
+			INSERT INTO shorturl_link_history
+			(shorturl_id, old_link, new_link, reason)
+			VALUES
+			($1, $2, $3, NULLIF($4, ''))
+		`, change.ID, change.From.String(), change.To.String(), reason)
+		if historyErr != nil {
+			return 0, historyErr
+		}
+
+		applied++
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		return 0, commitErr
+	}
+
+	return applied, nil
+}
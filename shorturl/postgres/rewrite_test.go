@@ -0,0 +1,67 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/lib/pq"
+	infra_postgres "github.com/rcovery/go-url-shortener/internal/infra/postgres"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/postgres"
+)
+
+func TestUpdateLinks(t *testing.T) {
+	t.Run("should rewrite destinations and record history", func(t *testing.T) {
+		ctx := context.Background()
+
+		instance, postgresContainer := infra_postgres.SetupContainer(ctx, t)
+		defer infra_postgres.TerminateContainer(postgresContainer)
+
+		repo := postgres.NewRepository(instance)
+		service := shorturl.NewService(repo)
+
+		id, _ := shorturl.NewID()
+		idempotencyKey, _ := shorturl.NewIdempotencyKey()
+		link, _ := shorturl.NewLink("https://old-docs.example.com/guide")
+
+		insertErr := repo.Insert(ctx, shorturl.ShortURL{ID: id, Name: "guide", Link: link, IdempotencyKey: idempotencyKey})
+		if insertErr != nil {
+			t.Fatalf("There was an Insert Error %q", insertErr.Error())
+		}
+
+		rule, _ := shorturl.NewRewriteRule(shorturl.MatchHost, "old-docs.example.com", "docs.example.com/v2")
+
+		dryRun, err := service.Rewrite(ctx, rule, "docs migration", true, 0)
+		if err != nil {
+			t.Fatalf("Cannot dry-run rewrite, instead got %q", err)
+		}
+		if len(dryRun.Changes) != 1 || dryRun.Applied != 0 {
+			t.Errorf("want 1 change and nothing applied, got %+v", dryRun)
+		}
+
+		report, err := service.Rewrite(ctx, rule, "docs migration", false, 0)
+		if err != nil {
+			t.Fatalf("Cannot rewrite, instead got %q", err)
+		}
+		if report.Applied != 1 {
+			t.Errorf("want 1 applied, got %d", report.Applied)
+		}
+
+		foundShorturl, err := repo.SelectByName(ctx, "guide")
+		if err != nil {
+			t.Fatalf("Cannot get URL by name, instead got %q", err)
+		}
+		if foundShorturl.Link.String() != "https://docs.example.com/v2/guide" {
+			t.Errorf("want %q, got %q", "https://docs.example.com/v2/guide", foundShorturl.Link)
+		}
+
+		var historyCount int
+		countErr := instance.QueryRowContext(ctx, `SELECT COUNT(*) FROM shorturl_link_history WHERE shorturl_id = $1`, id).Scan(&historyCount)
+		if countErr != nil {
+			t.Fatalf("Cannot count history, instead got %q", countErr)
+		}
+		if historyCount != 1 {
+			t.Errorf("want 1 history entry, got %d", historyCount)
+		}
+	})
+}
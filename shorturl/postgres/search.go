@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"strings"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+const highlightOptions = `StartSel=<mark>, StopSel=</mark>, HighlightAll=true`
+
+// Search ranks active links by full-text relevance, falling back to trigram
+// similarity on name, title and destination so typos and partial words
+// still match
+func (r *Repository) Search(ctx context.Context, query string, limit int) ([]shorturl.SearchResult, error) {
+	var results []shorturl.SearchResult
+	readErr := r.read(ctx, "Search", func(db *sql.DB) error {
+		var err error
+		results, err = search(ctx, db, query, limit)
+		return err
+	})
+
+	return results, readErr
+}
+
+func search(ctx context.Context, db *sql.DB, query string, limit int) ([]shorturl.SearchResult, error) {
+	rows, queryErr := db.QueryContext(ctx, `
+		SELECT `+selectColumns+`,
+			ts_rank_cd(search_vector, tsq) + GREATEST(
+				similarity(name, $1),
+				similarity(COALESCE(title, ''), $1),
+				-- A destination match weighs less, as in search_vector
+				word_similarity($1, link_normalized) / 2
+			) AS rank,
+			ts_headline('simple', name, tsq, '`+highlightOptions+`'),
+			ts_headline('simple', COALESCE(title, ''), tsq, '`+highlightOptions+`'),
+			ts_headline('simple', link, tsq, '`+highlightOptions+`')
+		FROM shorturls, websearch_to_tsquery('simple', $1) AS tsq
+		WHERE (search_vector @@ tsq
+				OR name % $1
+				OR title % $1
+				OR $1 <% link_normalized)
+			AND expires_at > NOW()
+		ORDER BY rank DESC, id DESC
+		LIMIT $2
+	`, query, limit)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	var results []shorturl.SearchResult
+	for rows.Next() {
+		var result shorturl.SearchResult
+		var nameHighlight, titleHighlight, linkHighlight string
+
+		surl, scanErr := scanShortURL(scanWith(rows, &result.Rank, &nameHighlight, &titleHighlight, &linkHighlight))
+		if scanErr != nil {
+			return nil, scanErr
+		}
+
+		result.SelectableShortURL = surl
+		for field, highlight := range map[string]string{
+			"name":  nameHighlight,
+			"title": titleHighlight,
+			"link":  linkHighlight,
+		} {
+			if !strings.Contains(highlight, "<mark>") {
+				continue
+			}
+			if result.Highlights == nil {
+				result.Highlights = map[string]string{}
+			}
+			result.Highlights[field] = escapeHighlight(highlight)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+var unescapeMarks = strings.NewReplacer("&lt;mark&gt;", "<mark>", "&lt;/mark&gt;", "</mark>")
+
+// escapeHighlight makes a highlight safe to render as HTML, keeping only
+// the <mark> tags added by ts_headline
+func escapeHighlight(highlight string) string {
+	return unescapeMarks.Replace(html.EscapeString(highlight))
+}
+
+// extraScanner appends destinations after the ones scanShortURL reads,
+// for queries that select more than selectColumns
+type extraScanner struct {
+	row   scanner
+	extra []any
+}
+
+func scanWith(row scanner, extra ...any) scanner {
+	return extraScanner{row, extra}
+}
+
+func (s extraScanner) Scan(dest ...any) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
@@ -0,0 +1,83 @@
+package postgres_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	_ "github.com/lib/pq"
+	infra_postgres "github.com/rcovery/go-url-shortener/internal/infra/postgres"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/postgres"
+)
+
+func TestSearch(t *testing.T) {
+	t.Run("should rank and highlight matching links", func(t *testing.T) {
+		ctx := context.Background()
+
+		instance, postgresContainer := infra_postgres.SetupContainer(ctx, t)
+		defer infra_postgres.TerminateContainer(postgresContainer)
+
+		repo := postgres.NewRepository(instance)
+
+		links := []struct {
+			name    string
+			rawLink string
+			title   string
+		}{
+			{"q3", "https://drive.example.com/decks/q3-review.pdf", "Quarterly review deck"},
+			{"careers", "https://example.com/jobs", "We are hiring"},
+		}
+		for _, l := range links {
+			id, _ := shorturl.NewID()
+			idempotencyKey, _ := shorturl.NewIdempotencyKey()
+			link, _ := shorturl.NewLink(l.rawLink)
+
+			insertErr := repo.Insert(ctx, shorturl.ShortURL{
+				ID: id, Name: l.name, Link: link, IdempotencyKey: idempotencyKey,
+				Details: shorturl.Details{Title: l.title},
+			})
+			if insertErr != nil {
+				t.Fatalf("There was an Insert Error %q", insertErr.Error())
+			}
+		}
+
+		results, err := repo.Search(ctx, "review deck", 10)
+		if err != nil {
+			t.Fatalf("Cannot search URLs, instead got %q", err)
+		}
+
+		if len(results) != 1 || results[0].Name != "q3" {
+			t.Fatalf("want only %q, got %v", "q3", results)
+		}
+		if !strings.Contains(results[0].Highlights["title"], "<mark>deck</mark>") {
+			t.Errorf("want a highlighted title, got %q", results[0].Highlights["title"])
+		}
+	})
+
+	t.Run("should find names with typos", func(t *testing.T) {
+		ctx := context.Background()
+
+		instance, postgresContainer := infra_postgres.SetupContainer(ctx, t)
+		defer infra_postgres.TerminateContainer(postgresContainer)
+
+		repo := postgres.NewRepository(instance)
+
+		id, _ := shorturl.NewID()
+		idempotencyKey, _ := shorturl.NewIdempotencyKey()
+		link, _ := shorturl.NewLink("https://example.com/summer")
+
+		insertErr := repo.Insert(ctx, shorturl.ShortURL{ID: id, Name: "summer-campaign", Link: link, IdempotencyKey: idempotencyKey})
+		if insertErr != nil {
+			t.Fatalf("There was an Insert Error %q", insertErr.Error())
+		}
+
+		results, err := repo.Search(ctx, "sumer-campaign", 10)
+		if err != nil {
+			t.Fatalf("Cannot search URLs, instead got %q", err)
+		}
+		if len(results) != 1 {
+			t.Errorf("want 1 result, got %v", results)
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+// CopyIn stores links as they are, keeping their IDs and creation times,
+// along with the entries of history about them. Links already present are
+// left alone, history included, so an interrupted copy can rerun
+func (r *Repository) CopyIn(ctx context.Context, surls []shorturl.SelectableShortURL, history []shorturl.HistoryEntry) error {
+	if len(surls) == 0 {
+		return nil
+	}
+
+	inserts := make([]shorturl.ShortURL, len(surls))
+	createdAt := make([]string, len(surls))
+	for i, surl := range surls {
+		inserts[i] = shorturl.ShortURL{
+			ID:             surl.ID,
+			Link:           surl.Link,
+			Name:           surl.Name,
+			IdempotencyKey: surl.IdempotencyKey,
+			ExpiresAt:      surl.ExpiresAt,
+			Details:        surl.Details,
+		}
+		createdAt[i] = surl.CreatedAt.Format(time.RFC3339Nano)
+	}
+
+	columns, columnsErr := newBatchColumns(inserts)
+	if columnsErr != nil {
+		return columnsErr
+	}
+
+	// ON CONFLICT makes a retry after a lost connection harmless
+	return r.Retry.Do(ctx, "CopyIn", func() error {
+		return r.copyIn(ctx, append(columns.args(), pq.Array(createdAt)), history)
+	})
+}
+
+func (r *Repository) copyIn(ctx context.Context, args []any, history []shorturl.HistoryEntry) error {
+	tx, txErr := r.DB.BeginTx(ctx, nil)
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	rows, insertionErr := tx.QueryContext(ctx, `
+		INSERT INTO shorturls
+		(id, name, link, link_host, link_normalized, idempotency_key, expires_at, owner, title, description, tags, metadata, created_at)
+		SELECT
+			v.id::uuid, v.name, v.link, v.link_host, v.link_normalized, NULLIF(v.idempotency_key, ''),
+			v.expires_at::timestamptz,
+			NULLIF(v.owner, ''), NULLIF(v.title, ''), NULLIF(v.description, ''),
+			ARRAY(SELECT jsonb_array_elements_text(v.tags::jsonb)),
+			v.metadata::jsonb,
+			v.created_at::timestamptz
+		FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[], $8::text[], $9::text[], $10::text[], $11::text[], $12::text[], $13::text[])
+			AS v(id, name, link, link_host, link_normalized, idempotency_key, expires_at, owner, title, description, tags, metadata, created_at)
+		ON CONFLICT (id) DO NOTHING
+		RETURNING id
+	`, args...)
+	if insertionErr != nil {
+		return insertionErr
+	}
+	added := map[shorturl.ID]bool{}
+	for rows.Next() {
+		var id shorturl.ID
+		if scanErr := rows.Scan(&id); scanErr != nil {
+			rows.Close()
+			return scanErr
+		}
+		added[id] = true
+	}
+	rows.Close()
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return rowsErr
+	}
+
+	// Only the history of links inserted now, since the others brought
+	// theirs in an earlier run
+	var ids, oldLinks, newLinks, reasons, changedAt []string
+	for _, entry := range history {
+		if !added[entry.ID] {
+			continue
+		}
+		at := ""
+		if !entry.ChangedAt.IsZero() {
+			at = entry.ChangedAt.Format(time.RFC3339Nano)
+		}
+		ids = append(ids, string(entry.ID))
+		oldLinks = append(oldLinks, entry.OldLink)
+		newLinks = append(newLinks, entry.NewLink)
+		reasons = append(reasons, entry.Reason)
+		changedAt = append(changedAt, at)
+	}
+	if len(ids) > 0 {
+		_, historyErr := tx.ExecContext(ctx, `
+			INSERT INTO shorturl_link_history
+			(shorturl_id, old_link, new_link, reason, changed_at)
+			SELECT v.shorturl_id::uuid, v.old_link, v.new_link, NULLIF(v.reason, ''), NULLIF(v.changed_at, '')::timestamptz
+			FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[])
+				AS v(shorturl_id, old_link, new_link, reason, changed_at)
+		`, pq.Array(ids), pq.Array(oldLinks), pq.Array(newLinks), pq.Array(reasons), pq.Array(changedAt))
+		if historyErr != nil {
+			return historyErr
+		}
+	}
+
+	return tx.Commit()
+}
+
+// HistoryOf returns the link history of ids, oldest first. It reads the
+// primary, since the links are deleted once it is copied
+func (r *Repository) HistoryOf(ctx context.Context, ids []shorturl.ID) ([]shorturl.HistoryEntry, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = string(id)
+	}
+
+	var entries []shorturl.HistoryEntry
+	queryErr := r.Retry.Do(ctx, "HistoryOf", func() error {
+		entries = nil
+
+		rows, err := r.DB.QueryContext(ctx, `
+			SELECT shorturl_id, old_link, new_link, COALESCE(reason, ''), changed_at
+			FROM shorturl_link_history
+			WHERE shorturl_id = ANY($1::uuid[])
+			ORDER BY changed_at, id
+		`, pq.Array(values))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var entry shorturl.HistoryEntry
+			var changedAt sql.NullTime
+			if scanErr := rows.Scan(&entry.ID, &entry.OldLink, &entry.NewLink, &entry.Reason, &changedAt); scanErr != nil {
+				return scanErr
+			}
+			entry.ChangedAt = changedAt.Time
+			entries = append(entries, entry)
+		}
+		return rows.Err()
+	})
+
+	return entries, queryErr
+}
+
+// DeleteByIDs removes links, along with their link history
+func (r *Repository) DeleteByIDs(ctx context.Context, ids []shorturl.ID) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = string(id)
+	}
+
+	var deleted int64
+	deleteErr := r.Retry.DoWrite(ctx, "DeleteByIDs", func() error {
+		result, err := r.DB.ExecContext(ctx, `
+			DELETE FROM shorturls
+			WHERE id = ANY($1::uuid[])
+		`, pq.Array(values))
+		if err != nil {
+			return err
+		}
+
+		deleted, err = result.RowsAffected()
+		return err
+	})
+
+	return int(deleted), deleteErr
+}
+
+// PutKeyRoutes records the name each idempotency key was used for
+func (r *Repository) PutKeyRoutes(ctx context.Context, routes map[shorturl.IdempotencyKey]string) error {
+	if len(routes) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(routes))
+	names := make([]string, 0, len(routes))
+	for key, name := range routes {
+		keys = append(keys, string(key))
+		names = append(names, name)
+	}
+
+	// An upsert, so running it twice is harmless
+	return r.Retry.Do(ctx, "PutKeyRoutes", func() error {
+		_, upsertErr := r.DB.ExecContext(ctx, `
+			INSERT INTO shorturl_key_routes (idempotency_key, name)
+			SELECT * FROM unnest($1::text[], $2::text[])
+			ON CONFLICT (idempotency_key) DO UPDATE SET name = EXCLUDED.name
+		`, pq.Array(keys), pq.Array(names))
+		return upsertErr
+	})
+}
+
+// KeyRoutes returns the name recorded for each of keys that has one
+func (r *Repository) KeyRoutes(ctx context.Context, keys []shorturl.IdempotencyKey) (map[shorturl.IdempotencyKey]string, error) {
+	routes := map[shorturl.IdempotencyKey]string{}
+	if len(keys) == 0 {
+		return routes, nil
+	}
+
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = string(key)
+	}
+
+	// Written together with links, so read from the primary like the
+	// idempotency lookups it serves
+	queryErr := r.Retry.Do(ctx, "KeyRoutes", func() error {
+		rows, err := r.DB.QueryContext(ctx, `
+			SELECT idempotency_key, name
+			FROM shorturl_key_routes
+			WHERE idempotency_key = ANY($1)
+		`, pq.Array(values))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var key shorturl.IdempotencyKey
+			var name string
+			if scanErr := rows.Scan(&key, &name); scanErr != nil {
+				return scanErr
+			}
+			routes[key] = name
+		}
+		return rows.Err()
+	})
+	if queryErr != nil {
+		return nil, queryErr
+	}
+
+	return routes, nil
+}
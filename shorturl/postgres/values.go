@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+func marshalMetadata(metadata shorturl.Metadata) ([]byte, error) {
+	if metadata == nil {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(metadata)
+}
+
+func tagsOrEmpty(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+
+	return tags
+}
+
+// nullTime lets the column default (or a COALESCE) apply when no time was given
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,119 @@
+package redirects
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type renderer struct {
+	// header is a format string receiving the generation time and the
+	// time until which every rendered link stays active
+	header string
+	footer string
+	indent string
+	// rule renders one redirect, or reports it cannot
+	rule func(name, destination string) (string, bool)
+}
+
+func (r renderer) render(name, destination string) (string, bool) {
+	if !safeName(name) {
+		return "", false
+	}
+
+	return r.rule(name, destination)
+}
+
+func newRenderer(format Format) (renderer, error) {
+	switch format {
+	case Nginx:
+		return renderer{
+			header: "# Generated by go-url-shortener at %s, valid until %s\n" +
+				"# Include in the http block, then in a server block:\n" +
+				"#   if ($shorturl_redirect) { return 302 $shorturl_redirect; }\n" +
+				"map $uri $shorturl_redirect {\n" +
+				"  default \"\";\n",
+			footer: "}\n",
+			indent: "  ",
+			rule:   nginxRule,
+		}, nil
+	case Apache:
+		return renderer{
+			header: "# Generated by go-url-shortener at %s, valid until %s\n" +
+				"RewriteEngine On\n",
+			rule: apacheRule,
+		}, nil
+	case Netlify:
+		return renderer{
+			header: "# Generated by go-url-shortener at %s, valid until %s\n",
+			rule:   netlifyRule,
+		}, nil
+	case Caddy:
+		return renderer{
+			header: "# Generated by go-url-shortener at %s, valid until %s\n" +
+				"# Import inside a site block\n",
+			rule: caddyRule,
+		}, nil
+	}
+
+	return renderer{}, fmt.Errorf("unknown redirect format %q", format)
+}
+
+// percentEncode replaces characters a format would otherwise interpret
+// with their URL escapes, which leaves the destination equivalent
+func percentEncode(destination string, chars string) string {
+	var encoded strings.Builder
+	for _, r := range destination {
+		if strings.ContainsRune(chars, r) {
+			fmt.Fprintf(&encoded, "%%%02X", r)
+			continue
+		}
+		encoded.WriteRune(r)
+	}
+	return encoded.String()
+}
+
+// quote wraps s in double quotes, escaping backslashes and quotes
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// nginx expands variables in map values, and has no escape for "$"
+func nginxRule(name, destination string) (string, bool) {
+	if strings.Contains(name, "$") {
+		return "", false
+	}
+
+	return fmt.Sprintf("  %s %s;", quote("/"+name), quote(percentEncode(destination, `$ `))), true
+}
+
+// Apache matches a regular expression, and expands $N and %{VAR} in the substitution
+func apacheRule(name, destination string) (string, bool) {
+	pattern := "^/?" + regexp.QuoteMeta(name) + "$"
+	substitution := strings.NewReplacer(`$`, `\$`, `%`, `\%`).Replace(percentEncode(destination, ` "`))
+
+	// Apache only unescapes \" inside quotes; other backslashes reach mod_rewrite
+	apacheQuote := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	}
+	return fmt.Sprintf("RewriteRule %s %s [R=302,L,NE]", apacheQuote(pattern), apacheQuote(substitution)), true
+}
+
+// Netlify splits lines on whitespace, treats "#" at a field start as a
+// comment, ":" at a segment start as a placeholder and "*" as a splat
+func netlifyRule(name, destination string) (string, bool) {
+	if strings.HasPrefix(name, "#") || strings.HasPrefix(name, ":") || strings.Contains(name, "*") {
+		return "", false
+	}
+
+	return fmt.Sprintf("/%s  %s  302", name, percentEncode(destination, " \t")), true
+}
+
+// Caddy expands {placeholders} and matches "*" as a wildcard in paths
+func caddyRule(name, destination string) (string, bool) {
+	if strings.ContainsAny(name, "*{}") {
+		return "", false
+	}
+
+	return fmt.Sprintf("redir %s %s 302", quote("/"+name), quote(percentEncode(destination, "{} "))), true
+}
@@ -0,0 +1,104 @@
+// Package redirects renders active links as static redirect configuration
+// for web servers and hosts, so redirects can be served without this service
+package redirects
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+type Format string
+
+const (
+	Nginx   Format = "nginx"
+	Apache  Format = "apache"
+	Netlify Format = "netlify"
+	Caddy   Format = "caddy"
+)
+
+const pageSize = 500
+
+func ParseFormat(raw string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(raw))); format {
+	case Nginx, Apache, Netlify, Caddy:
+		return format, nil
+	}
+
+	return "", errs.InvalidError.New(fmt.Sprintf("unknown redirect format %q", raw))
+}
+
+type Summary struct {
+	Written int
+	// Skipped are names that cannot be expressed safely in the format
+	Skipped []string
+}
+
+// Write renders every link still active at now+validFor. validFor leaves
+// out links that would expire while the rendered file is in use
+func Write(ctx context.Context, w io.Writer, format Format, service *shorturl.Service, now time.Time, validFor time.Duration) (Summary, error) {
+	renderer, rendererErr := newRenderer(format)
+	if rendererErr != nil {
+		return Summary{}, rendererErr
+	}
+
+	buffered := bufio.NewWriter(w)
+	validUntil := now.Add(validFor)
+	if _, err := fmt.Fprintf(buffered, renderer.header, now.UTC().Format(time.RFC3339), validUntil.UTC().Format(time.RFC3339)); err != nil {
+		return Summary{}, err
+	}
+
+	var summary Summary
+	filter := shorturl.ListFilter{ExpiresAfter: validUntil, Sort: shorturl.SortName, Limit: pageSize}
+	walkErr := service.Walk(ctx, filter, func(surl shorturl.SelectableShortURL) error {
+		line, ok := renderer.render(surl.Name, surl.Link.String())
+		if !ok {
+			summary.Skipped = append(summary.Skipped, surl.Name)
+			return nil
+		}
+
+		summary.Written++
+		_, err := fmt.Fprintf(buffered, "%s# expires %s\n%s\n", renderer.indent, surl.ExpiresAt.UTC().Format(time.RFC3339), line)
+		return err
+	})
+	if walkErr != nil {
+		return summary, walkErr
+	}
+
+	if _, err := io.WriteString(buffered, renderer.footer); err != nil {
+		return summary, err
+	}
+	return summary, buffered.Flush()
+}
+
+// Rule renders the redirect of one link, or reports false when the name
+// cannot be expressed safely in the format
+func Rule(format Format, name, destination string) (string, bool) {
+	renderer, rendererErr := newRenderer(format)
+	if rendererErr != nil {
+		return "", false
+	}
+
+	return renderer.render(name, destination)
+}
+
+// safeName rejects names that would need escaping in a path in some
+// format: whitespace, control characters and path separators
+func safeName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || r == '/' || r == '\\' {
+			return false
+		}
+	}
+	return true
+}
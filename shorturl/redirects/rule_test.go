@@ -0,0 +1,79 @@
+package redirects_test
+
+import (
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/shorturl/redirects"
+)
+
+func TestRule(t *testing.T) {
+	cases := []struct {
+		should      string
+		format      redirects.Format
+		name        string
+		destination string
+		want        string
+	}{
+		{
+			should:      "should quote nginx map entries and encode variables",
+			format:      redirects.Nginx,
+			name:        `q"3`,
+			destination: "https://example.com/a$b?c=\"d\"",
+			want:        `  "/q\"3" "https://example.com/a%24b?c=\"d\"";`,
+		},
+		{
+			should:      "should escape regexp metacharacters and back-references for Apache",
+			format:      redirects.Apache,
+			name:        "v1.0+beta",
+			destination: "https://example.com/100%25?x=$1",
+			want:        `RewriteRule "^/?v1\.0\+beta$" "https://example.com/100\%25?x=\$1" [R=302,L,NE]`,
+		},
+		{
+			should:      "should encode whitespace in Netlify destinations",
+			format:      redirects.Netlify,
+			name:        "docs",
+			destination: "https://example.com/a b",
+			want:        "/docs  https://example.com/a%20b  302",
+		},
+		{
+			should:      "should encode Caddy placeholders",
+			format:      redirects.Caddy,
+			name:        "docs",
+			destination: "https://example.com/{path}",
+			want:        `redir "/docs" "https://example.com/%7Bpath%7D" 302`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.should, func(t *testing.T) {
+			got, ok := redirects.Rule(c.format, c.name, c.destination)
+			if !ok {
+				t.Fatalf("want the rule to render")
+			}
+			if got != c.want {
+				t.Errorf("want %q, got %q", c.want, got)
+			}
+		})
+	}
+
+	t.Run("should skip names a format cannot express", func(t *testing.T) {
+		skipped := []struct {
+			format redirects.Format
+			name   string
+		}{
+			{redirects.Nginx, "a$b"},
+			{redirects.Netlify, "#top"},
+			{redirects.Netlify, ":id"},
+			{redirects.Netlify, "a*"},
+			{redirects.Caddy, "a*"},
+			{redirects.Apache, "a b"},
+			{redirects.Nginx, "a/b"},
+		}
+
+		for _, s := range skipped {
+			if rule, ok := redirects.Rule(s.format, s.name, "https://example.com"); ok {
+				t.Errorf("want %q skipped for %s, got %q", s.name, s.format, rule)
+			}
+		}
+	})
+}
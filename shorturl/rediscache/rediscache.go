@@ -0,0 +1,355 @@
+// Package rediscache keeps resolved links in a cache shared by every
+// server, speaking the Redis protocol, so a new server doesn't start cold
+package rediscache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+const (
+	DefaultPrefix      = "shorturl:name:"
+	DefaultTTL         = 10 * time.Minute
+	DefaultNegativeTTL = 5 * time.Second
+)
+
+// Mode is what writes do to the cache
+type Mode string
+
+const (
+	// ReadThrough drops the names a write touches; the next read fills them
+	ReadThrough Mode = "read-through"
+	// WriteThrough stores the links a write leaves behind right away
+	WriteThrough Mode = "write-through"
+)
+
+func ParseMode(raw string) (Mode, error) {
+	switch mode := Mode(raw); mode {
+	case ReadThrough, WriteThrough:
+		return mode, nil
+	case "":
+		return ReadThrough, nil
+	}
+	return "", errs.InvalidError.New(fmt.Sprintf("unknown cache mode %q, want read-through or write-through", raw))
+}
+
+type Options struct {
+	Mode Mode
+	// Prefix namespaces the keys, one per link name
+	Prefix string
+	// TTL bounds how long a link is cached. It is cut short when the link
+	// expires sooner, so Redis drops it on its own
+	TTL time.Duration
+	// NegativeTTL is how long a name that was not found stays not found
+	NegativeTTL time.Duration
+
+	// Meter records hits and misses, the global meter provider's by default
+	Meter metric.Meter
+}
+
+// notFound is cached for names without an active link
+const notFound = "-"
+
+// Each name is a hash holding the cached link and a version that every
+// invalidation bumps. A lookup only fills the cache if the version it saw
+// before reading the repository is still current, so a link read before a
+// write can't be stored after the write dropped it
+const (
+	linkField    = "link"
+	versionField = "version"
+)
+
+// fill sets the link of KEYS[1] to ARGV[2] for ARGV[3] milliseconds, if
+// its version is still ARGV[1]
+var fill = redis.NewScript(`
+local version = redis.call('HGET', KEYS[1], 'version') or ''
+if version ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'link', ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+// Repository answers SelectByName from Redis before asking repo. Redis
+// failures are logged and the lookup falls back to repo, since the cache
+// only makes redirects faster
+type Repository struct {
+	shorturl.Repository
+	client  redis.UniversalClient
+	options Options
+
+	lookups metric.Int64Counter
+}
+
+var (
+	hitAttributes      = metric.WithAttributes(attribute.String("result", "hit"))
+	negativeAttributes = metric.WithAttributes(attribute.String("result", "negative_hit"))
+	missAttributes     = metric.WithAttributes(attribute.String("result", "miss"))
+	errorAttributes    = metric.WithAttributes(attribute.String("result", "error"))
+)
+
+func New(client redis.UniversalClient, repo shorturl.Repository, options Options) (*Repository, error) {
+	if options.Mode == "" {
+		options.Mode = ReadThrough
+	}
+	if options.Prefix == "" {
+		options.Prefix = DefaultPrefix
+	}
+	if options.TTL <= 0 {
+		options.TTL = DefaultTTL
+	}
+	if options.NegativeTTL <= 0 {
+		options.NegativeTTL = DefaultNegativeTTL
+	}
+	if options.Meter == nil {
+		options.Meter = otel.Meter("github.com/rcovery/go-url-shortener/shorturl/rediscache")
+	}
+
+	lookups, counterErr := options.Meter.Int64Counter(
+		"shorturl.rediscache.lookups",
+		metric.WithDescription("Name lookups answered by the shared link cache, by result"),
+	)
+	if counterErr != nil {
+		return nil, counterErr
+	}
+
+	return &Repository{Repository: repo, client: client, options: options, lookups: lookups}, nil
+}
+
+func (r *Repository) key(name string) string {
+	return r.options.Prefix + name
+}
+
+func (r *Repository) SelectByName(ctx context.Context, name string) (shorturl.SelectableShortURL, error) {
+	fields, getErr := r.client.HMGet(ctx, r.key(name), linkField, versionField).Result()
+	var cached, version string
+	if getErr == nil {
+		cached, _ = fields[0].(string)
+		version, _ = fields[1].(string)
+		// A fresh lookup refills the name, since the link cached may be
+		// older than the write that asked for it
+		if cached == "" || shorturl.IsFresh(ctx) {
+			getErr = redis.Nil
+		}
+	}
+
+	switch {
+	case getErr == nil && cached == notFound:
+		r.lookups.Add(ctx, 1, negativeAttributes)
+		return shorturl.SelectableShortURL{}, errs.NotFoundError.New(fmt.Sprintf("ByName: %q", name))
+	case getErr == nil:
+		var surl shorturl.SelectableShortURL
+		if decodeErr := json.Unmarshal([]byte(cached), &surl); decodeErr == nil {
+			r.lookups.Add(ctx, 1, hitAttributes)
+			return surl, nil
+		}
+		log.Printf("cannot decode cached link %q, reading it again", name)
+		r.lookups.Add(ctx, 1, errorAttributes)
+	case errors.Is(getErr, redis.Nil):
+		r.lookups.Add(ctx, 1, missAttributes)
+	default:
+		log.Println("shared cache lookup failed:", getErr)
+		r.lookups.Add(ctx, 1, errorAttributes)
+	}
+
+	// A cache that can't be read isn't filled either, lacking the version
+	if getErr != nil && !errors.Is(getErr, redis.Nil) {
+		return r.Repository.SelectByName(ctx, name)
+	}
+
+	surl, err := r.Repository.SelectByName(ctx, name)
+	switch {
+	case err == nil:
+		r.store(ctx, surl, version)
+	case errors.Is(err, errs.NotFoundError):
+		if fillErr := r.fill(ctx, name, notFound, r.options.NegativeTTL, version); fillErr != nil {
+			log.Println("cannot cache a missing link:", fillErr)
+		}
+	}
+
+	return surl, err
+}
+
+// store caches surl until it expires, or for the TTL if that comes first,
+// unless name was invalidated since version
+func (r *Repository) store(ctx context.Context, surl shorturl.SelectableShortURL, version string) {
+	ttl := min(r.options.TTL, time.Until(surl.ExpiresAt))
+	if ttl <= 0 {
+		return
+	}
+
+	encoded, encodeErr := json.Marshal(surl)
+	if encodeErr != nil {
+		log.Println("cannot encode a link for the shared cache:", encodeErr)
+		return
+	}
+	if fillErr := r.fill(ctx, surl.Name, string(encoded), ttl, version); fillErr != nil {
+		log.Println("cannot cache a link:", fillErr)
+	}
+}
+
+func (r *Repository) fill(ctx context.Context, name, value string, ttl time.Duration, version string) error {
+	return fill.Run(ctx, r.client, []string{r.key(name)}, version, value, ttl.Milliseconds()).Err()
+}
+
+// Invalidate drops names from the cache, for writes made around the
+// repository, such as deletes
+func (r *Repository) Invalidate(ctx context.Context, names ...string) error {
+	_, invalidateErr := r.invalidate(ctx, names)
+	return invalidateErr
+}
+
+// invalidate returns the new version of each name. The version is bumped
+// before the link is dropped, so a lookup can't fill the name in between
+func (r *Repository) invalidate(ctx context.Context, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	versions := make([]*redis.IntCmd, len(names))
+	_, pipeErr := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, name := range names {
+			key := r.key(name)
+			versions[i] = pipe.HIncrBy(ctx, key, versionField, 1)
+			pipe.HDel(ctx, key, linkField)
+			// Kept while lookups that saw the old version may still fill
+			pipe.PExpire(ctx, key, r.options.TTL)
+		}
+		return nil
+	})
+	if pipeErr != nil {
+		return nil, pipeErr
+	}
+
+	current := make([]string, len(names))
+	for i, version := range versions {
+		current[i] = strconv.FormatInt(version.Val(), 10)
+	}
+	return current, nil
+}
+
+func (r *Repository) Insert(ctx context.Context, surl shorturl.ShortURL) error {
+	if insertErr := r.Repository.Insert(ctx, surl); insertErr != nil {
+		return insertErr
+	}
+
+	r.written(ctx, []string{surl.Name}, []shorturl.ID{surl.ID}, []shorturl.IdempotencyKey{surl.IdempotencyKey})
+	return nil
+}
+
+func (r *Repository) InsertMany(ctx context.Context, surls []shorturl.ShortURL) ([]shorturl.ID, error) {
+	inserted, insertErr := r.Repository.InsertMany(ctx, surls)
+	if insertErr != nil {
+		return inserted, insertErr
+	}
+
+	names := make([]string, len(surls))
+	keys := make([]shorturl.IdempotencyKey, len(surls))
+	for i, surl := range surls {
+		names[i] = surl.Name
+		keys[i] = surl.IdempotencyKey
+	}
+	r.written(ctx, names, inserted, keys)
+	return inserted, nil
+}
+
+func (r *Repository) UpdateLinks(ctx context.Context, changes []shorturl.LinkChange, reason string) (int, error) {
+	applied, updateErr := r.Repository.UpdateLinks(ctx, changes, reason)
+
+	// Even a failed update may have been applied before the error surfaced
+	names := make([]string, len(changes))
+	ids := make([]shorturl.ID, len(changes))
+	for i, change := range changes {
+		names[i] = change.Name
+		ids[i] = change.ID
+	}
+	var keys []shorturl.IdempotencyKey
+	if r.options.Mode == WriteThrough {
+		keys = r.keysOf(ctx, names, ids)
+	}
+	r.written(ctx, names, ids, keys)
+
+	return applied, updateErr
+}
+
+// keysOf returns the idempotency keys of the links with ids. Keys never
+// change, so a lagging read still finds them
+func (r *Repository) keysOf(ctx context.Context, names []string, ids []shorturl.ID) []shorturl.IdempotencyKey {
+	found, selectErr := r.Repository.SelectByNames(ctx, names)
+	if selectErr != nil {
+		log.Println("cannot read links back for the shared cache:", selectErr)
+		return nil
+	}
+
+	changed := idSet(ids)
+	var keys []shorturl.IdempotencyKey
+	for _, surl := range found {
+		if changed[surl.ID] {
+			keys = append(keys, surl.IdempotencyKey)
+		}
+	}
+	return keys
+}
+
+// written refreshes names after a write. Write-through stores the links
+// with ids as they are now, read back by their idempotency keys, which
+// are looked up where writes are visible at once, unlike names that may
+// be read from a lagging replica. Read-through drops them. Either way the
+// names are dropped first, so a failure never leaves the old link cached
+func (r *Repository) written(ctx context.Context, names []string, ids []shorturl.ID, keys []shorturl.IdempotencyKey) {
+	versions, invalidateErr := r.invalidate(ctx, names)
+	if invalidateErr != nil {
+		log.Println("cannot invalidate the shared cache:", invalidateErr)
+		return
+	}
+	if r.options.Mode != WriteThrough {
+		return
+	}
+
+	// Links written without a key are left for the next read to fill
+	keys = slices.DeleteFunc(slices.Clone(keys), func(key shorturl.IdempotencyKey) bool { return key == "" })
+	if len(keys) == 0 {
+		return
+	}
+	found, selectErr := r.Repository.SelectByIdempotencyKeys(ctx, keys)
+	if selectErr != nil {
+		log.Println("cannot read links back for the shared cache:", selectErr)
+		return
+	}
+
+	// A later write to the same name bumps its version again, so whichever
+	// write stores last can't leave an older link behind
+	versionOf := make(map[string]string, len(names))
+	for i, name := range names {
+		versionOf[name] = versions[i]
+	}
+	isWritten := idSet(ids)
+	for _, surl := range found {
+		if version, ok := versionOf[surl.Name]; ok && isWritten[surl.ID] {
+			r.store(ctx, surl, version)
+		}
+	}
+}
+
+func idSet(ids []shorturl.ID) map[shorturl.ID]bool {
+	set := make(map[shorturl.ID]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
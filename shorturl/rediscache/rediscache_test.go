@@ -0,0 +1,259 @@
+package rediscache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+	"github.com/rcovery/go-url-shortener/shorturl/rediscache"
+	"github.com/rcovery/go-url-shortener/shorturl/repotest"
+)
+
+// racing runs during after reading a link, before the cache can store it
+type racing struct {
+	*repotest.CountingRepository
+	during func()
+}
+
+func (r *racing) SelectByName(ctx context.Context, name string) (shorturl.SelectableShortURL, error) {
+	surl, err := r.CountingRepository.SelectByName(ctx, name)
+	if r.during != nil {
+		r.during()
+	}
+	return surl, err
+}
+
+// lagging answers SelectByNames with the links it held when frozen, like
+// a replica behind the primary
+type lagging struct {
+	*repotest.CountingRepository
+	frozen []shorturl.SelectableShortURL
+}
+
+func (r *lagging) SelectByNames(ctx context.Context, names []string) ([]shorturl.SelectableShortURL, error) {
+	return r.frozen, nil
+}
+
+func newCache(t *testing.T, mode rediscache.Mode) (*rediscache.Repository, *repotest.CountingRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+
+	next := repotest.NewCountingRepository()
+	repo, err := rediscache.New(client, next, rediscache.Options{Mode: mode, TTL: time.Hour, NegativeTTL: time.Second})
+	if err != nil {
+		t.Fatalf("cannot build the cache: %v", err)
+	}
+	return repo, next, server
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should read through and serve the link from the cache", func(t *testing.T) {
+		repo, next, server := newCache(t, rediscache.ReadThrough)
+		surl := repotest.NewShortURL(t, "cached", time.Now().Add(time.Hour))
+		surl.Details = shorturl.Details{Title: "A link", Tags: []string{"a"}, Metadata: shorturl.Metadata{"team": "growth"}}
+		next.Insert(ctx, surl)
+
+		for range 3 {
+			found, err := repo.SelectByName(ctx, "cached")
+			if err != nil {
+				t.Fatalf("SelectByName failed unexpectedly: %v", err)
+			}
+			if found.ID != surl.ID || !found.Link.Equals(surl.Link) || found.Title != "A link" || found.Metadata["team"] != "growth" {
+				t.Errorf("want the stored link back, got %+v", found)
+			}
+		}
+		if next.Lookups.Load() != 1 {
+			t.Errorf("want 1 repository lookup, got %d", next.Lookups.Load())
+		}
+		if !server.Exists(rediscache.DefaultPrefix + "cached") {
+			t.Errorf("want the link stored under its name")
+		}
+	})
+
+	t.Run("should expire the entry with the link", func(t *testing.T) {
+		repo, next, server := newCache(t, rediscache.ReadThrough)
+		next.Insert(ctx, repotest.NewShortURL(t, "short-lived", time.Now().Add(time.Minute)))
+
+		repo.SelectByName(ctx, "short-lived")
+		ttl := server.TTL(rediscache.DefaultPrefix + "short-lived")
+		if ttl <= 0 || ttl > time.Minute {
+			t.Errorf("want the TTL capped at the link's expiry, got %s", ttl)
+		}
+	})
+
+	t.Run("should cache misses for the negative TTL", func(t *testing.T) {
+		repo, next, server := newCache(t, rediscache.ReadThrough)
+
+		for range 2 {
+			if _, err := repo.SelectByName(ctx, "missing"); !errors.Is(err, errs.NotFoundError) {
+				t.Fatalf("want not found, got %v", err)
+			}
+		}
+		if next.Lookups.Load() != 1 {
+			t.Errorf("want 1 lookup while the miss is cached, got %d", next.Lookups.Load())
+		}
+
+		server.FastForward(2 * time.Second)
+		repo.SelectByName(ctx, "missing")
+		if next.Lookups.Load() != 2 {
+			t.Errorf("want the miss looked up again after the negative TTL, got %d", next.Lookups.Load())
+		}
+	})
+
+	t.Run("should drop names on writes in read-through mode", func(t *testing.T) {
+		repo, next, server := newCache(t, rediscache.ReadThrough)
+
+		repo.SelectByName(ctx, "soon")
+		if err := repo.Insert(ctx, repotest.NewShortURL(t, "soon", time.Now().Add(time.Hour))); err != nil {
+			t.Fatalf("Insert failed unexpectedly: %v", err)
+		}
+		if server.HGet(rediscache.DefaultPrefix+"soon", "link") != "" {
+			t.Errorf("want the cached miss dropped")
+		}
+
+		if _, err := repo.SelectByName(ctx, "soon"); err != nil {
+			t.Errorf("want the new link, got %v", err)
+		}
+		if next.Lookups.Load() != 2 {
+			t.Errorf("want the name read again, got %d lookups", next.Lookups.Load())
+		}
+	})
+
+	t.Run("should store written links in write-through mode", func(t *testing.T) {
+		repo, next, _ := newCache(t, rediscache.WriteThrough)
+		surl := repotest.NewShortURL(t, "written", time.Now().Add(time.Hour))
+
+		if err := repo.Insert(ctx, surl); err != nil {
+			t.Fatalf("Insert failed unexpectedly: %v", err)
+		}
+		found, err := repo.SelectByName(ctx, "written")
+		if err != nil || found.ID != surl.ID {
+			t.Fatalf("want the written link, got %q (%v)", found.ID, err)
+		}
+
+		moved, _ := shorturl.NewLink("https://example.org/moved")
+		applied, updateErr := repo.UpdateLinks(ctx, []shorturl.LinkChange{{ID: surl.ID, Name: "written", From: surl.Link, To: moved}}, "test")
+		if updateErr != nil || applied != 1 {
+			t.Fatalf("want the link updated, got %d (%v)", applied, updateErr)
+		}
+
+		found, _ = repo.SelectByName(ctx, "written")
+		if !found.Link.Equals(moved) {
+			t.Errorf("want %q, got %q", moved, found.Link)
+		}
+		if next.Lookups.Load() != 0 {
+			t.Errorf("want every lookup served from the cache, got %d", next.Lookups.Load())
+		}
+	})
+
+	t.Run("should store written links in write-through mode while reads lag", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+		t.Cleanup(func() { client.Close() })
+
+		next := &lagging{CountingRepository: repotest.NewCountingRepository()}
+		repo, err := rediscache.New(client, next, rediscache.Options{Mode: rediscache.WriteThrough, TTL: time.Hour})
+		if err != nil {
+			t.Fatalf("cannot build the cache: %v", err)
+		}
+
+		surl := repotest.NewShortURL(t, "lagged", time.Now().Add(time.Hour))
+		if err := repo.Insert(ctx, surl); err != nil {
+			t.Fatalf("Insert failed unexpectedly: %v", err)
+		}
+		old, _ := next.SelectByName(ctx, "lagged")
+		next.frozen = []shorturl.SelectableShortURL{old}
+		next.Lookups.Store(0)
+
+		moved, _ := shorturl.NewLink("https://example.org/moved")
+		applied, updateErr := repo.UpdateLinks(ctx, []shorturl.LinkChange{{ID: surl.ID, Name: "lagged", From: surl.Link, To: moved}}, "test")
+		if updateErr != nil || applied != 1 {
+			t.Fatalf("want the link updated, got %d (%v)", applied, updateErr)
+		}
+
+		found, _ := repo.SelectByName(ctx, "lagged")
+		if !found.Link.Equals(moved) {
+			t.Errorf("want %q, got %q", moved, found.Link)
+		}
+		if next.Lookups.Load() != 0 {
+			t.Errorf("want the new link served from the cache, got %d lookups", next.Lookups.Load())
+		}
+	})
+
+	t.Run("should invalidate names", func(t *testing.T) {
+		repo, next, server := newCache(t, rediscache.ReadThrough)
+		next.Insert(ctx, repotest.NewShortURL(t, "gone", time.Now().Add(time.Hour)))
+		repo.SelectByName(ctx, "gone")
+
+		if err := repo.Invalidate(ctx, "gone"); err != nil {
+			t.Fatalf("Invalidate failed unexpectedly: %v", err)
+		}
+		if server.HGet(rediscache.DefaultPrefix+"gone", "link") != "" {
+			t.Errorf("want the name dropped")
+		}
+	})
+
+	t.Run("should not store a link read before an invalidation", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+		t.Cleanup(func() { client.Close() })
+
+		next := &racing{CountingRepository: repotest.NewCountingRepository()}
+		repo, err := rediscache.New(client, next, rediscache.Options{TTL: time.Hour})
+		if err != nil {
+			t.Fatalf("cannot build the cache: %v", err)
+		}
+		next.Insert(ctx, repotest.NewShortURL(t, "raced", time.Now().Add(time.Hour)))
+
+		// A write lands between the repository read and the cache fill
+		next.during = func() { repo.Invalidate(ctx, "raced") }
+		repo.SelectByName(ctx, "raced")
+		next.during = nil
+
+		repo.SelectByName(ctx, "raced")
+		if next.Lookups.Load() != 2 {
+			t.Errorf("want the link read before the write left out of the cache, got %d lookups", next.Lookups.Load())
+		}
+	})
+
+	t.Run("should read fresh lookups through and refill the name", func(t *testing.T) {
+		repo, next, _ := newCache(t, rediscache.ReadThrough)
+		surl := repotest.NewShortURL(t, "notified", time.Now().Add(time.Hour))
+		next.Insert(ctx, surl)
+		repo.SelectByName(ctx, "notified")
+
+		// Changed around the cache, which hasn't been told yet
+		moved, _ := shorturl.NewLink("https://example.org/moved")
+		next.UpdateLinks(ctx, []shorturl.LinkChange{{ID: surl.ID, Name: "notified", From: surl.Link, To: moved}}, "test")
+
+		found, err := repo.SelectByName(shorturl.Fresh(ctx), "notified")
+		if err != nil || !found.Link.Equals(moved) {
+			t.Fatalf("want %q, got %q (%v)", moved, found.Link, err)
+		}
+		found, _ = repo.SelectByName(ctx, "notified")
+		if !found.Link.Equals(moved) || next.Lookups.Load() != 2 {
+			t.Errorf("want %q cached by the fresh lookup, got %q after %d lookups", moved, found.Link, next.Lookups.Load())
+		}
+	})
+
+	t.Run("should fall back to the repository when the cache is down", func(t *testing.T) {
+		repo, next, server := newCache(t, rediscache.ReadThrough)
+		next.Insert(ctx, repotest.NewShortURL(t, "resilient", time.Now().Add(time.Hour)))
+		server.Close()
+
+		if _, err := repo.SelectByName(ctx, "resilient"); err != nil {
+			t.Errorf("want the link from the repository, got %v", err)
+		}
+	})
+}
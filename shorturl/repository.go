@@ -5,10 +5,29 @@ import "context"
 type Reader interface {
 	SelectByName(ctx context.Context, name string) (SelectableShortURL, error)
 	SelectByIdempotencyKey(ctx context.Context, idempotencyKey IdempotencyKey) (SelectableShortURL, error)
+	// List returns up to filter.Limit links matching filter, in filter.Sort
+	// order, starting right after filter.After
+	List(ctx context.Context, filter ListFilter) ([]SelectableShortURL, error)
+	// SelectByDestination returns up to query.Limit links pointing at
+	// query.Target, in ID order, starting right after query.AfterID
+	SelectByDestination(ctx context.Context, query DestinationQuery) ([]SelectableShortURL, error)
+	// SelectByIdempotencyKeys returns the active links created with any of idempotencyKeys
+	SelectByIdempotencyKeys(ctx context.Context, idempotencyKeys []IdempotencyKey) ([]SelectableShortURL, error)
+	// SelectByNames returns, for each of names, its active link or else
+	// its most recently expired one. Unknown names are left out
+	SelectByNames(ctx context.Context, names []string) ([]SelectableShortURL, error)
 }
 
 type Writer interface {
-	Insert(ctx context.Context, id ID, name string, link *Link, idempotencyKey IdempotencyKey) error
+	Insert(ctx context.Context, surl ShortURL) error
+	// InsertMany inserts every link whose name is not taken by an active
+	// link and whose ID is not stored yet, and returns the IDs of the
+	// inserted ones
+	InsertMany(ctx context.Context, surls []ShortURL) ([]ID, error)
+	// UpdateLinks applies changes in a single transaction, recording each in
+	// the link history with reason. Links whose destination is no longer
+	// change.From are left untouched. It returns how many were applied
+	UpdateLinks(ctx context.Context, changes []LinkChange, reason string) (int, error)
 }
 
 type Repository interface {
@@ -17,3 +36,8 @@ type Repository interface {
 
 	// Close(ctx context.Context) error
 }
+
+// Searcher is implemented by stores that can rank links against a free-text query
+type Searcher interface {
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+}
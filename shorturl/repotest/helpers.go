@@ -0,0 +1,65 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
+)
+
+// NewShortURL builds a link named name that points to https://example.com/<name>
+func NewShortURL(t *testing.T, name string, expiresAt time.Time) shorturl.ShortURL {
+	t.Helper()
+	return NewShortURLTo(t, name, "https://example.com/"+name, expiresAt)
+}
+
+// NewShortURLTo builds a link named name that points to rawLink
+func NewShortURLTo(t *testing.T, name, rawLink string, expiresAt time.Time) shorturl.ShortURL {
+	t.Helper()
+
+	id, idErr := shorturl.NewID()
+	idempotencyKey, keyErr := shorturl.NewIdempotencyKey()
+	link, linkErr := shorturl.NewLink(rawLink)
+	if err := errors.Join(idErr, keyErr, linkErr); err != nil {
+		t.Fatalf("cannot build a short URL: %v", err)
+	}
+
+	return shorturl.ShortURL{ID: id, Name: name, Link: link, IdempotencyKey: idempotencyKey, ExpiresAt: expiresAt}
+}
+
+// Insert stores surls in order and stops the test at the first failure
+func Insert(t *testing.T, repo shorturl.Writer, surls ...shorturl.ShortURL) {
+	t.Helper()
+
+	for _, surl := range surls {
+		if err := repo.Insert(context.Background(), surl); err != nil {
+			t.Fatalf("Insert(%q) %v", surl.Name, err)
+		}
+	}
+}
+
+// CountingRepository is an in-memory repository that counts the name
+// lookups reaching it, for testing the layers meant to absorb them. Setting
+// Err fails every lookup with it
+type CountingRepository struct {
+	*memory.Repository
+	// Lookups is atomic, since coalescing tests look up from many goroutines
+	Lookups atomic.Int64
+	Err     error
+}
+
+func NewCountingRepository() *CountingRepository {
+	return &CountingRepository{Repository: memory.NewRepository()}
+}
+
+func (r *CountingRepository) SelectByName(ctx context.Context, name string) (shorturl.SelectableShortURL, error) {
+	r.Lookups.Add(1)
+	if r.Err != nil {
+		return shorturl.SelectableShortURL{}, r.Err
+	}
+	return r.Repository.SelectByName(ctx, name)
+}
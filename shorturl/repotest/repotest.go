@@ -0,0 +1,438 @@
+// Package repotest is a conformance suite for shorturl.Repository
+// implementations, so every backend behaves like the Postgres one. It also
+// holds the fixtures the backends' and decorators' own tests share
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+// Factory returns an empty repository. It is called once per subtest
+type Factory func(t *testing.T) shorturl.Repository
+
+// Options relaxes checks a backend cannot meet by design
+type Options struct {
+	// PartitionedIDs is for stores that only refuse a duplicate ID among
+	// links routed to the same partition
+	PartitionedIDs bool
+}
+
+// Run checks the behavior every shorturl.Repository must share
+func Run(t *testing.T, newRepository Factory) {
+	RunWith(t, newRepository, Options{})
+}
+
+func RunWith(t *testing.T, newRepository Factory, options Options) {
+	t.Run("expiry", func(t *testing.T) { testExpiry(t, newRepository) })
+	t.Run("duplicate names", func(t *testing.T) { testDuplicateNames(t, newRepository, options) })
+	t.Run("idempotency", func(t *testing.T) { testIdempotency(t, newRepository) })
+	t.Run("not found", func(t *testing.T) { testNotFound(t, newRepository) })
+	t.Run("context cancellation", func(t *testing.T) { testCancellation(t, newRepository) })
+	t.Run("concurrent inserts", func(t *testing.T) { testConcurrentInserts(t, newRepository) })
+	t.Run("details", func(t *testing.T) { testDetails(t, newRepository) })
+	t.Run("listing", func(t *testing.T) { testListing(t, newRepository) })
+	t.Run("link changes", func(t *testing.T) { testLinkChanges(t, newRepository) })
+}
+
+func testExpiry(t *testing.T, newRepository Factory) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+
+	t.Run("should not select expired links by name or idempotency key", func(t *testing.T) {
+		repo := newRepository(t)
+		expired := NewShortURL(t, "expired", past)
+		Insert(t, repo, expired)
+
+		if _, err := repo.SelectByName(ctx, expired.Name); !errors.Is(err, errs.NotFoundError) {
+			t.Errorf("SelectByName: want %v, got %v", errs.NotFoundError, err)
+		}
+		if _, err := repo.SelectByIdempotencyKey(ctx, expired.IdempotencyKey); !errors.Is(err, errs.NotFoundError) {
+			t.Errorf("SelectByIdempotencyKey: want %v, got %v", errs.NotFoundError, err)
+		}
+
+		found, err := repo.SelectByIdempotencyKeys(ctx, []shorturl.IdempotencyKey{expired.IdempotencyKey})
+		if err != nil || len(found) != 0 {
+			t.Errorf("SelectByIdempotencyKeys: want nothing, got %v %v", found, err)
+		}
+	})
+
+	t.Run("should not search expired links", func(t *testing.T) {
+		repo := newRepository(t)
+		searcher, ok := repo.(shorturl.Searcher)
+		if !ok {
+			t.Skip("the repository cannot search")
+		}
+		Insert(t, repo, NewShortURL(t, "expired-deck", past))
+
+		found, err := searcher.Search(ctx, "expired", 10)
+		if err != nil || len(found) != 0 {
+			t.Errorf("Search: want nothing, got %v %v", found, err)
+		}
+	})
+
+	t.Run("should default the expiry to a day", func(t *testing.T) {
+		repo := newRepository(t)
+		Insert(t, repo, NewShortURL(t, "default", time.Time{}))
+
+		found, err := repo.SelectByName(ctx, "default")
+		if err != nil {
+			t.Fatalf("SelectByName() %v", err)
+		}
+		if until := time.Until(found.ExpiresAt); until < 23*time.Hour || until > 25*time.Hour {
+			t.Errorf("want an expiry in a day, got %v", found.ExpiresAt)
+		}
+	})
+
+	t.Run("should keep the given expiry", func(t *testing.T) {
+		repo := newRepository(t)
+		expiresAt := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+		Insert(t, repo, NewShortURL(t, "explicit", expiresAt))
+
+		found, err := repo.SelectByName(ctx, "explicit")
+		if err != nil {
+			t.Fatalf("SelectByName() %v", err)
+		}
+		if !found.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("want %v, got %v", expiresAt, found.ExpiresAt)
+		}
+	})
+
+	t.Run("should prefer the active link in SelectByNames", func(t *testing.T) {
+		repo := newRepository(t)
+		older := NewShortURL(t, "reused", past.Add(-time.Hour))
+		newer := NewShortURL(t, "reused", past)
+		Insert(t, repo, older, newer, NewShortURL(t, "gone", past))
+
+		found, err := repo.SelectByNames(ctx, []string{"reused", "gone"})
+		if err != nil {
+			t.Fatalf("SelectByNames() %v", err)
+		}
+		byName := map[string]shorturl.ID{}
+		for _, surl := range found {
+			byName[surl.Name] = surl.ID
+		}
+		if len(found) != 2 || byName["reused"] != newer.ID {
+			t.Errorf("want the most recently expired link, got %v", found)
+		}
+
+		active := NewShortURL(t, "reused", time.Time{})
+		Insert(t, repo, active)
+		found, err = repo.SelectByNames(ctx, []string{"reused"})
+		if err != nil || len(found) != 1 || found[0].ID != active.ID {
+			t.Errorf("want the active link %q, got %v %v", active.ID, found, err)
+		}
+	})
+
+	t.Run("should filter listings by expiry", func(t *testing.T) {
+		repo := newRepository(t)
+		Insert(t, repo, NewShortURL(t, "expired", past), NewShortURL(t, "active", time.Time{}))
+
+		found, err := repo.List(ctx, shorturl.ListFilter{ExpiresAfter: time.Now(), Sort: shorturl.SortName, Limit: 10})
+		if err != nil {
+			t.Fatalf("List() %v", err)
+		}
+		if len(found) != 1 || found[0].Name != "active" {
+			t.Errorf("want only active, got %v", found)
+		}
+	})
+}
+
+func testDuplicateNames(t *testing.T, newRepository Factory, options Options) {
+	ctx := context.Background()
+
+	t.Run("should refuse to create a taken name", func(t *testing.T) {
+		service := shorturl.NewService(newRepository(t))
+		first := NewShortURL(t, "taken", time.Time{})
+		second := NewShortURL(t, "taken", time.Time{})
+
+		if _, err := service.Create(ctx, first.ID, first.IdempotencyKey, first.Name, first.Link, shorturl.Details{}); err != nil {
+			t.Fatalf("Create() %v", err)
+		}
+		if _, err := service.Create(ctx, second.ID, second.IdempotencyKey, second.Name, second.Link, shorturl.Details{}); !errors.Is(err, errs.AlreadyExistsError) {
+			t.Errorf("want %v, got %v", errs.AlreadyExistsError, err)
+		}
+	})
+
+	t.Run("should let an expired name be reused", func(t *testing.T) {
+		repo := newRepository(t)
+		Insert(t, repo, NewShortURL(t, "reused", time.Now().Add(-time.Hour)))
+
+		active := NewShortURL(t, "reused", time.Time{})
+		inserted, err := repo.InsertMany(ctx, []shorturl.ShortURL{active})
+		if err != nil || len(inserted) != 1 {
+			t.Fatalf("want the name reused, got %v %v", inserted, err)
+		}
+
+		found, err := repo.SelectByName(ctx, "reused")
+		if err != nil || found.ID != active.ID {
+			t.Errorf("want %q, got %+v %v", active.ID, found, err)
+		}
+	})
+
+	t.Run("should skip active names in InsertMany", func(t *testing.T) {
+		repo := newRepository(t)
+		Insert(t, repo, NewShortURL(t, "taken", time.Time{}))
+
+		free := NewShortURL(t, "free", time.Time{})
+		inserted, err := repo.InsertMany(ctx, []shorturl.ShortURL{NewShortURL(t, "taken", time.Time{}), free})
+		if err != nil {
+			t.Fatalf("InsertMany() %v", err)
+		}
+		if len(inserted) != 1 || inserted[0] != free.ID {
+			t.Errorf("want only %q inserted, got %v", free.ID, inserted)
+		}
+	})
+
+	t.Run("should refuse a duplicate ID", func(t *testing.T) {
+		if options.PartitionedIDs {
+			t.Skip("IDs are only checked within a partition")
+		}
+
+		repo := newRepository(t)
+		surl := NewShortURL(t, "first", time.Time{})
+		Insert(t, repo, surl)
+
+		surl.Name = "second"
+		if err := repo.Insert(ctx, surl); err == nil {
+			t.Errorf("want an error inserting %q twice", surl.ID)
+		}
+	})
+}
+
+func testIdempotency(t *testing.T, newRepository Factory) {
+	ctx := context.Background()
+
+	t.Run("should select a link by its idempotency key", func(t *testing.T) {
+		repo := newRepository(t)
+		surl := NewShortURL(t, "keyed", time.Time{})
+		Insert(t, repo, surl, NewShortURL(t, "other", time.Time{}))
+
+		found, err := repo.SelectByIdempotencyKey(ctx, surl.IdempotencyKey)
+		if err != nil || found.ID != surl.ID || found.IdempotencyKey != surl.IdempotencyKey {
+			t.Errorf("want %q, got %+v %v", surl.ID, found, err)
+		}
+
+		many, err := repo.SelectByIdempotencyKeys(ctx, []shorturl.IdempotencyKey{surl.IdempotencyKey, "unknown"})
+		if err != nil || len(many) != 1 || many[0].ID != surl.ID {
+			t.Errorf("want only %q, got %v %v", surl.ID, many, err)
+		}
+	})
+
+	t.Run("should return the first link when creating twice with a key", func(t *testing.T) {
+		repo := newRepository(t)
+		service := shorturl.NewService(repo)
+		first := NewShortURL(t, "first", time.Time{})
+		retry := NewShortURL(t, "retry", time.Time{})
+
+		if _, err := service.Create(ctx, first.ID, first.IdempotencyKey, first.Name, first.Link, shorturl.Details{}); err != nil {
+			t.Fatalf("Create() %v", err)
+		}
+		link, err := service.Create(ctx, retry.ID, first.IdempotencyKey, retry.Name, retry.Link, shorturl.Details{})
+		if err != nil || !link.Equals(first.Link) {
+			t.Errorf("want %q, got %q %v", first.Link, link, err)
+		}
+		if _, err := repo.SelectByName(ctx, retry.Name); !errors.Is(err, errs.NotFoundError) {
+			t.Errorf("want no link created for the retry, got %v", err)
+		}
+	})
+}
+
+func testNotFound(t *testing.T, newRepository Factory) {
+	ctx := context.Background()
+	repo := newRepository(t)
+
+	if _, err := repo.SelectByName(ctx, "unknown"); !errors.Is(err, errs.NotFoundError) {
+		t.Errorf("SelectByName: want %v, got %v", errs.NotFoundError, err)
+	}
+	if _, err := repo.SelectByIdempotencyKey(ctx, "unknown"); !errors.Is(err, errs.NotFoundError) {
+		t.Errorf("SelectByIdempotencyKey: want %v, got %v", errs.NotFoundError, err)
+	}
+	if found, err := repo.SelectByNames(ctx, []string{"unknown"}); err != nil || len(found) != 0 {
+		t.Errorf("SelectByNames: want nothing, got %v %v", found, err)
+	}
+	if found, err := repo.List(ctx, shorturl.ListFilter{Sort: shorturl.SortCreated, Limit: 10}); err != nil || len(found) != 0 {
+		t.Errorf("List: want nothing, got %v %v", found, err)
+	}
+}
+
+func testCancellation(t *testing.T, newRepository Factory) {
+	repo := newRepository(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	surl := NewShortURL(t, "cancelled", time.Time{})
+	if err := repo.Insert(ctx, surl); err == nil {
+		t.Errorf("Insert: want an error")
+	}
+	if _, err := repo.InsertMany(ctx, []shorturl.ShortURL{NewShortURL(t, "cancelled-many", time.Time{})}); err == nil {
+		t.Errorf("InsertMany: want an error")
+	}
+	if _, err := repo.SelectByName(ctx, surl.Name); err == nil {
+		t.Errorf("SelectByName: want an error")
+	}
+	if _, err := repo.List(ctx, shorturl.ListFilter{Sort: shorturl.SortCreated, Limit: 10}); err == nil {
+		t.Errorf("List: want an error")
+	}
+
+	found, err := repo.SelectByNames(context.Background(), []string{"cancelled", "cancelled-many"})
+	if err != nil || len(found) != 0 {
+		t.Errorf("want nothing stored, got %v %v", found, err)
+	}
+}
+
+func testConcurrentInserts(t *testing.T, newRepository Factory) {
+	ctx := context.Background()
+	repo := newRepository(t)
+
+	const inserts = 50
+	var wg sync.WaitGroup
+	for i := range inserts {
+		wg.Go(func() {
+			surl := NewShortURL(t, fmt.Sprintf("concurrent-%02d", i), time.Time{})
+			if err := repo.Insert(ctx, surl); err != nil {
+				t.Errorf("Insert(%q) %v", surl.Name, err)
+				return
+			}
+			if _, err := repo.SelectByName(ctx, surl.Name); err != nil {
+				t.Errorf("SelectByName(%q) %v", surl.Name, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	found, err := repo.List(ctx, shorturl.ListFilter{Sort: shorturl.SortName, Limit: 2 * inserts})
+	if err != nil {
+		t.Fatalf("List() %v", err)
+	}
+	if len(found) != inserts {
+		t.Errorf("want %d links, got %d", inserts, len(found))
+	}
+}
+
+func testDetails(t *testing.T, newRepository Factory) {
+	ctx := context.Background()
+	repo := newRepository(t)
+
+	surl := NewShortURL(t, "detailed", time.Time{})
+	surl.Details = shorturl.Details{
+		Owner:       "marketing",
+		Title:       "Q3 deck",
+		Description: "Slides for the launch",
+		Tags:        []string{"launch", "email"},
+		Metadata:    shorturl.Metadata{"channel": "email", "budget": 12.5, "nested": map[string]any{"ok": true}},
+	}
+	Insert(t, repo, surl)
+
+	found, err := repo.SelectByName(ctx, surl.Name)
+	if err != nil {
+		t.Fatalf("SelectByName() %v", err)
+	}
+	if found.Owner != "marketing" || found.Title != "Q3 deck" || found.Description != "Slides for the launch" {
+		t.Errorf("unexpected details %+v", found.Details)
+	}
+	if fmt.Sprint(found.Tags) != "[launch email]" {
+		t.Errorf("want [launch email], got %v", found.Tags)
+	}
+	if found.Metadata["channel"] != "email" || found.Metadata["budget"] != 12.5 || fmt.Sprint(found.Metadata["nested"]) != "map[ok:true]" {
+		t.Errorf("unexpected metadata %v", found.Metadata)
+	}
+	if found.CreatedAt.IsZero() || time.Since(found.CreatedAt) > time.Minute {
+		t.Errorf("want a recent creation time, got %v", found.CreatedAt)
+	}
+
+	bare := NewShortURL(t, "bare", time.Time{})
+	Insert(t, repo, bare)
+	found, err = repo.SelectByName(ctx, bare.Name)
+	if err != nil {
+		t.Fatalf("SelectByName() %v", err)
+	}
+	if found.Owner != "" || len(found.Tags) != 0 || len(found.Metadata) != 0 {
+		t.Errorf("want empty details, got %+v", found.Details)
+	}
+}
+
+func testListing(t *testing.T, newRepository Factory) {
+	ctx := context.Background()
+	repo := newRepository(t)
+	service := shorturl.NewService(repo)
+
+	for i, name := range []string{"b", "c", "a"} {
+		surl := NewShortURL(t, name, time.Now().Add(time.Duration(i+1)*time.Hour).Truncate(time.Second))
+		surl.Owner = "team-" + name
+		surl.Tags = []string{"docs"}
+		Insert(t, repo, surl)
+	}
+
+	walk := func(t *testing.T, filter shorturl.ListFilter) string {
+		t.Helper()
+
+		var names []string
+		err := service.Walk(ctx, filter, func(surl shorturl.SelectableShortURL) error {
+			names = append(names, surl.Name)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk() %v", err)
+		}
+		return fmt.Sprint(names)
+	}
+
+	tests := []struct {
+		filter shorturl.ListFilter
+		want   string
+	}{
+		{shorturl.ListFilter{Sort: shorturl.SortCreated, Limit: 1}, "[b c a]"},
+		{shorturl.ListFilter{Sort: shorturl.SortCreatedDesc, Limit: 2}, "[a c b]"},
+		{shorturl.ListFilter{Sort: shorturl.SortName, Limit: 1}, "[a b c]"},
+		{shorturl.ListFilter{Sort: shorturl.SortNameDesc, Limit: 2}, "[c b a]"},
+		{shorturl.ListFilter{Sort: shorturl.SortExpires, Limit: 1}, "[b c a]"},
+		{shorturl.ListFilter{Sort: shorturl.SortExpiresDesc, Limit: 1}, "[a c b]"},
+		{shorturl.ListFilter{Owner: "team-c", Tags: []string{"docs"}, Host: "EXAMPLE.com", Sort: shorturl.SortName}, "[c]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("should list %s by %s", tt.want, tt.filter.Sort), func(t *testing.T) {
+			if got := walk(t, tt.filter); got != tt.want {
+				t.Errorf("want %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func testLinkChanges(t *testing.T, newRepository Factory) {
+	ctx := context.Background()
+	repo := newRepository(t)
+
+	surl := NewShortURL(t, "moved", time.Time{})
+	Insert(t, repo, surl)
+	to, _ := shorturl.NewLink("https://new.example.org/moved")
+	stale, _ := shorturl.NewLink("https://example.com/stale")
+
+	applied, err := repo.UpdateLinks(ctx, []shorturl.LinkChange{
+		{ID: surl.ID, Name: surl.Name, From: stale, To: to},
+		{ID: surl.ID, Name: surl.Name, From: surl.Link, To: to},
+	}, "test")
+	if err != nil || applied != 1 {
+		t.Fatalf("want only the up-to-date change applied, got %d %v", applied, err)
+	}
+
+	found, err := repo.SelectByName(ctx, surl.Name)
+	if err != nil || !found.Link.Equals(to) {
+		t.Errorf("want %q, got %+v %v", to, found.Link, err)
+	}
+
+	query, _ := shorturl.NewDestinationQuery(shorturl.MatchHost, "new.example.org")
+	query.Limit = 10
+	byHost, err := repo.SelectByDestination(ctx, query)
+	if err != nil || len(byHost) != 1 || byHost[0].ID != surl.ID {
+		t.Errorf("want %q by its new host, got %v %v", surl.ID, byHost, err)
+	}
+}
@@ -0,0 +1,72 @@
+package shorturl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+const MaxResolveNames = 1000
+
+type ResolveStatus string
+
+const (
+	ResolveActive   ResolveStatus = "active"
+	ResolveExpired  ResolveStatus = "expired"
+	ResolveNotFound ResolveStatus = "not_found"
+)
+
+type Resolution struct {
+	Name      string        `json:"name"`
+	Status    ResolveStatus `json:"status"`
+	Link      *Link         `json:"link,omitempty"`
+	ExpiresAt time.Time     `json:"expiresAt,omitzero"`
+}
+
+// Resolve looks up many names in a single query. It returns one
+// resolution per distinct name, in the order they were first given
+func (s *Service) Resolve(ctx context.Context, names []string) ([]Resolution, error) {
+	if len(names) == 0 {
+		return nil, errs.InvalidError.New("no names to resolve")
+	}
+
+	distinct := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		distinct = append(distinct, name)
+	}
+	if len(distinct) > MaxResolveNames {
+		return nil, errs.InvalidError.New(fmt.Sprintf("can resolve at most %d names at once", MaxResolveNames))
+	}
+
+	found, selectErr := s.repo.SelectByNames(ctx, distinct)
+	if selectErr != nil {
+		return nil, selectErr
+	}
+	byName := make(map[string]SelectableShortURL, len(found))
+	for _, surl := range found {
+		byName[surl.Name] = surl
+	}
+
+	now := time.Now()
+	resolutions := make([]Resolution, len(distinct))
+	for i, name := range distinct {
+		surl, ok := byName[name]
+		switch {
+		case !ok:
+			resolutions[i] = Resolution{Name: name, Status: ResolveNotFound}
+		case surl.ExpiresAt.After(now):
+			resolutions[i] = Resolution{Name: name, Status: ResolveActive, Link: surl.Link, ExpiresAt: surl.ExpiresAt}
+		default:
+			resolutions[i] = Resolution{Name: name, Status: ResolveExpired, ExpiresAt: surl.ExpiresAt}
+		}
+	}
+
+	return resolutions, nil
+}
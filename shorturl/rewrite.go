@@ -0,0 +1,161 @@
+package shorturl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+const (
+	DefaultRewriteBatchSize = 500
+	MaxRewriteBatchSize     = 5000
+)
+
+// RewriteRule moves destinations from one host or URL prefix to another
+type RewriteRule struct {
+	Match DestinationMatch
+	// From is a lowercase host for MatchHost, a normalized URL prefix for MatchPrefix
+	From string
+	// To replaces From. For MatchHost it is a base URL whose path is
+	// prepended to the rewritten paths
+	To *url.URL
+}
+
+// NewRewriteRule accepts a host or a URL prefix to move from, and a
+// host, optionally with a path, or a URL to move to. With MatchHost,
+// "old-docs.example.com" -> "docs.example.com/v2" rewrites
+// https://old-docs.example.com/guide?x=1 to https://docs.example.com/v2/guide?x=1
+func NewRewriteRule(match DestinationMatch, from, to string) (RewriteRule, error) {
+	if match != MatchHost && match != MatchPrefix {
+		return RewriteRule{}, errs.InvalidError.New(fmt.Sprintf("cannot rewrite %q matches", match))
+	}
+
+	query, queryErr := NewDestinationQuery(match, from)
+	if queryErr != nil {
+		return RewriteRule{}, queryErr
+	}
+
+	to = strings.TrimSpace(to)
+	if match == MatchHost && !strings.Contains(to, "://") {
+		to = "https://" + to
+	}
+	target, targetErr := url.Parse(to)
+	if targetErr != nil || target.Host == "" || target.Scheme == "" {
+		return RewriteRule{}, errs.InvalidError.New(fmt.Sprintf("invalid rewrite target %q", to))
+	}
+	if match == MatchHost && (target.RawQuery != "" || target.Fragment != "") {
+		return RewriteRule{}, errs.InvalidError.New("a host rewrite target cannot have a query or fragment")
+	}
+
+	return RewriteRule{Match: match, From: query.Target, To: target}, nil
+}
+
+// Apply returns the rewritten destination of link. The link must match the rule
+func (rule RewriteRule) Apply(link *Link) (*Link, error) {
+	switch rule.Match {
+	case MatchHost:
+		rewritten := *(*url.URL)(link)
+		rewritten.Scheme = rule.To.Scheme
+		rewritten.Host = rule.To.Host
+		rewritten.Path = strings.TrimSuffix(rule.To.Path, "/") + "/" + strings.TrimPrefix(rewritten.Path, "/")
+		rewritten.RawPath = ""
+		return NewLink(rewritten.String())
+	case MatchPrefix:
+		normalized := link.Normalized()
+		if !strings.HasPrefix(normalized, rule.From) {
+			return nil, fmt.Errorf("%q does not start with %q", normalized, rule.From)
+		}
+
+		rewritten := rule.To.String() + strings.TrimPrefix(normalized, rule.From)
+		if link.Fragment != "" {
+			rewritten += "#" + (*url.URL)(link).EscapedFragment()
+		}
+		return NewLink(rewritten)
+	}
+
+	return nil, errs.InvalidError.New(fmt.Sprintf("cannot rewrite %q matches", rule.Match))
+}
+
+// LinkChange moves one short link from one destination to another
+type LinkChange struct {
+	ID   ID     `json:"id"`
+	Name string `json:"name"`
+	From *Link  `json:"from"`
+	To   *Link  `json:"to"`
+}
+
+// HistoryEntry is a destination change recorded by UpdateLinks
+type HistoryEntry struct {
+	ID        ID        `json:"id"`
+	OldLink   string    `json:"oldLink"`
+	NewLink   string    `json:"newLink"`
+	Reason    string    `json:"reason,omitempty"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+type RewriteReport struct {
+	DryRun  bool         `json:"dryRun"`
+	Matched int          `json:"matched"`
+	Applied int          `json:"applied"`
+	Changes []LinkChange `json:"changes"`
+	// Skipped are links that could not be rewritten, by name
+	Skipped map[string]string `json:"skipped,omitempty"`
+}
+
+// Rewrite moves every link matching rule to its new destination, in
+// transactions of batchSize links. Each change is recorded in the link
+// history with reason. With dryRun, changes are only reported
+func (s *Service) Rewrite(ctx context.Context, rule RewriteRule, reason string, dryRun bool, batchSize int) (RewriteReport, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultRewriteBatchSize
+	}
+	if batchSize > MaxRewriteBatchSize {
+		batchSize = MaxRewriteBatchSize
+	}
+
+	report := RewriteReport{DryRun: dryRun, Changes: []LinkChange{}}
+	query := DestinationQuery{Match: rule.Match, Target: rule.From, Limit: batchSize}
+	for {
+		page, lookupErr := s.repo.SelectByDestination(ctx, query)
+		if lookupErr != nil {
+			return report, lookupErr
+		}
+
+		changes := make([]LinkChange, 0, len(page))
+		for _, surl := range page {
+			rewritten, rewriteErr := rule.Apply(surl.Link)
+			if rewriteErr != nil {
+				if report.Skipped == nil {
+					report.Skipped = map[string]string{}
+				}
+				report.Skipped[surl.Name] = rewriteErr.Error()
+				continue
+			}
+			if rewritten.Equals(surl.Link) {
+				continue
+			}
+
+			changes = append(changes, LinkChange{ID: surl.ID, Name: surl.Name, From: surl.Link, To: rewritten})
+		}
+
+		report.Matched += len(page)
+		report.Changes = append(report.Changes, changes...)
+
+		if !dryRun && len(changes) > 0 {
+			applied, updateErr := s.repo.UpdateLinks(ctx, changes, reason)
+			report.Applied += applied
+			if updateErr != nil {
+				return report, updateErr
+			}
+		}
+
+		if len(page) < batchSize {
+			return report, nil
+		}
+		query.AfterID = page[len(page)-1].ID
+	}
+}
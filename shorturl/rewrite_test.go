@@ -0,0 +1,48 @@
+package shorturl_test
+
+import (
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+func TestRewriteRuleApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		match shorturl.DestinationMatch
+		from  string
+		to    string
+		link  string
+		want  string
+	}{
+		{"should move a host under a new base path", shorturl.MatchHost, "old-docs.example.com", "docs.example.com/v2", "https://old-docs.example.com/guide?x=1", "https://docs.example.com/v2/guide?x=1"},
+		{"should move a bare host", shorturl.MatchHost, "old-docs.example.com", "docs.example.com", "https://OLD-docs.example.com", "https://docs.example.com/"},
+		{"should replace a URL prefix", shorturl.MatchPrefix, "https://example.com/old/", "https://example.com/new/", "https://example.com/old/a/b#c", "https://example.com/new/a/b#c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := shorturl.NewRewriteRule(tt.match, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("NewRewriteRule() %v", err)
+			}
+			link, _ := shorturl.NewLink(tt.link)
+
+			rewritten, err := rule.Apply(link)
+			if err != nil {
+				t.Fatalf("Apply() %v", err)
+			}
+
+			if rewritten.String() != tt.want {
+				t.Errorf("want %q, got %q", tt.want, rewritten)
+			}
+		})
+	}
+
+	t.Run("should reject exact matches", func(t *testing.T) {
+		_, err := shorturl.NewRewriteRule(shorturl.MatchExact, "https://example.com/a", "https://example.com/b")
+		if err == nil {
+			t.Errorf("expected an error for an exact rewrite, got nil")
+		}
+	})
+}
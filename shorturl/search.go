@@ -0,0 +1,58 @@
+package shorturl
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+	MaxSearchQueryLen  = 256
+)
+
+type SearchResult struct {
+	SelectableShortURL
+	Rank float64 `json:"rank"`
+	// Highlights maps a field (name, title, link) to its text with the
+	// matched terms wrapped in <mark></mark>
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+type SearchService struct {
+	searcher Searcher
+}
+
+func NewSearchService(searcher Searcher) *SearchService {
+	return &SearchService{
+		searcher: searcher,
+	}
+}
+
+func (s *SearchService) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errs.InvalidError.New("empty search query")
+	}
+	if len(query) > MaxSearchQueryLen {
+		return nil, errs.InvalidError.New("search query is too long")
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	results, searchErr := s.searcher.Search(ctx, query, limit)
+	if searchErr != nil {
+		return nil, searchErr
+	}
+	if results == nil {
+		results = []SearchResult{}
+	}
+
+	return results, nil
+}
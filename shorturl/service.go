@@ -18,7 +18,7 @@ func NewService(repo Repository) *Service {
 	}
 }
 
-func (s *Service) Create(ctx context.Context, id ID, idempotencyKey IdempotencyKey, name string, link *Link) (*Link, error) {
+func (s *Service) Create(ctx context.Context, id ID, idempotencyKey IdempotencyKey, name string, link *Link, details Details) (*Link, error) {
 	urlFound, urlError := s.repo.SelectByIdempotencyKey(ctx, idempotencyKey)
 	if urlError != nil && !errors.Is(urlError, errs.NotFoundError) {
 		return nil, urlError
@@ -32,10 +32,16 @@ func (s *Service) Create(ctx context.Context, id ID, idempotencyKey IdempotencyK
 		return nil, urlError
 	}
 	if urlFound.ID != "" {
-		return nil, fmt.Errorf("cannot create a new URL with %q", name)
+		return nil, errs.AlreadyExistsError.New(fmt.Sprintf("cannot create a new URL with %q", name))
 	}
 
-	insertedErr := s.repo.Insert(ctx, id, name, link, idempotencyKey)
+	insertedErr := s.repo.Insert(ctx, ShortURL{
+		ID:             id,
+		Link:           link,
+		Name:           name,
+		IdempotencyKey: idempotencyKey,
+		Details:        details.Normalize(),
+	})
 	if insertedErr != nil {
 		return nil, insertedErr
 	}
@@ -45,7 +51,7 @@ func (s *Service) Create(ctx context.Context, id ID, idempotencyKey IdempotencyK
 
 func (s *Service) Select(ctx context.Context, name string) (*Link, error) {
 	urlFound, urlError := s.repo.SelectByName(ctx, name)
-	if urlError != nil && errors.Is(urlError, errs.NotFoundError) {
+	if urlError != nil {
 		return nil, urlError
 	}
 	if urlFound.ID == "" {
@@ -54,3 +60,82 @@ func (s *Service) Select(ctx context.Context, name string) (*Link, error) {
 
 	return urlFound.Link, nil
 }
+
+func (s *Service) Find(ctx context.Context, name string) (SelectableShortURL, error) {
+	urlFound, urlError := s.repo.SelectByName(ctx, name)
+	if urlError != nil {
+		return urlFound, urlError
+	}
+	if urlFound.ID == "" {
+		return urlFound, fmt.Errorf("cannot retrieve this URL with %q", name)
+	}
+
+	return urlFound, nil
+}
+
+func (s *Service) List(ctx context.Context, filter ListFilter) (ListPage, error) {
+	if filter.Sort == "" {
+		filter.Sort = SortCreated
+	}
+	if !filter.Sort.Valid() {
+		return ListPage{}, errs.InvalidError.New(fmt.Sprintf("cannot sort by %q", filter.Sort))
+	}
+	if filter.After != nil && filter.After.Sort != filter.Sort {
+		return ListPage{}, errs.InvalidError.New("cursor belongs to another sort order")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultListLimit
+	}
+	// Stored tags are normalized, so ?tag=Email has to match "email"
+	filter.Tags = normalizeTags(filter.Tags)
+	if filter.Limit > MaxListLimit {
+		filter.Limit = MaxListLimit
+	}
+
+	limit := filter.Limit
+	// One extra row tells whether there is a next page
+	filter.Limit++
+
+	items, listErr := s.repo.List(ctx, filter)
+	if listErr != nil {
+		return ListPage{}, listErr
+	}
+
+	if items == nil {
+		items = []SelectableShortURL{}
+	}
+
+	page := ListPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = NewCursor(filter.Sort, page.Items[limit-1]).String()
+	}
+
+	return page, nil
+}
+
+// Walk calls fn for every link matching filter, one page at a time.
+// filter.Limit is the page size
+func (s *Service) Walk(ctx context.Context, filter ListFilter, fn func(SelectableShortURL) error) error {
+	for {
+		page, listErr := s.List(ctx, filter)
+		if listErr != nil {
+			return listErr
+		}
+
+		for _, surl := range page.Items {
+			if fnErr := fn(surl); fnErr != nil {
+				return fnErr
+			}
+		}
+
+		if page.NextCursor == "" {
+			return nil
+		}
+		cursor, cursorErr := ParseCursor(page.NextCursor)
+		if cursorErr != nil {
+			return cursorErr
+		}
+		filter.After = &cursor
+	}
+}
@@ -4,9 +4,8 @@ import (
 	"context"
 	"testing"
 
-	infra_postgres "github.com/rcovery/go-url-shortener/internal/infra/postgres"
 	"github.com/rcovery/go-url-shortener/shorturl"
-	"github.com/rcovery/go-url-shortener/shorturl/postgres"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
 )
 
 func TestCreate(t *testing.T) {
@@ -17,13 +16,10 @@ func TestCreate(t *testing.T) {
 		link, _ := shorturl.NewLink("https://google.com")
 
 		ctx := context.Background()
-		instance, postgresContainer := infra_postgres.SetupContainer(ctx, t)
-		defer infra_postgres.TerminateContainer(postgresContainer)
-
-		repo := postgres.NewRepository(instance)
+		repo := memory.NewRepository()
 		service := shorturl.NewService(repo)
 
-		createdShorturl, creationErr := service.Create(ctx, id, idempotencyKey, name, link)
+		createdShorturl, creationErr := service.Create(ctx, id, idempotencyKey, name, link, shorturl.Details{})
 		if creationErr != nil {
 			t.Errorf("cannot create a short URL %q", creationErr)
 		}
@@ -34,7 +30,7 @@ func TestCreate(t *testing.T) {
 		id2, _ := shorturl.NewID()
 		idempotencyKey2, _ := shorturl.NewIdempotencyKey()
 
-		duplicatedURL, _ := service.Create(ctx, id2, idempotencyKey2, name, link)
+		duplicatedURL, _ := service.Create(ctx, id2, idempotencyKey2, name, link, shorturl.Details{})
 		if createdShorturl == duplicatedURL {
 			t.Errorf("created a duplicated URL %q", duplicatedURL)
 		}
@@ -42,10 +38,7 @@ func TestCreate(t *testing.T) {
 
 	t.Run("should return error when name already exists", func(t *testing.T) {
 		ctx := context.Background()
-		instance, postgresContainer := infra_postgres.SetupContainer(ctx, t)
-		defer infra_postgres.TerminateContainer(postgresContainer)
-
-		repo := postgres.NewRepository(instance)
+		repo := memory.NewRepository()
 		service := shorturl.NewService(repo)
 
 		id1, _ := shorturl.NewID()
@@ -53,7 +46,7 @@ func TestCreate(t *testing.T) {
 		name := "taken-name"
 		link, _ := shorturl.NewLink("https://example.com")
 
-		_, firstErr := service.Create(ctx, id1, idempotencyKey1, name, link)
+		_, firstErr := service.Create(ctx, id1, idempotencyKey1, name, link, shorturl.Details{})
 		if firstErr != nil {
 			t.Fatalf("first Create failed unexpectedly: %v", firstErr)
 		}
@@ -62,7 +55,7 @@ func TestCreate(t *testing.T) {
 		idempotencyKey2, _ := shorturl.NewIdempotencyKey()
 		link2, _ := shorturl.NewLink("https://other.com")
 
-		result, secondErr := service.Create(ctx, id2, idempotencyKey2, name, link2)
+		result, secondErr := service.Create(ctx, id2, idempotencyKey2, name, link2, shorturl.Details{})
 		if secondErr == nil {
 			t.Errorf("expected an error when creating with duplicate name, got nil")
 		}
@@ -73,10 +66,7 @@ func TestCreate(t *testing.T) {
 
 	t.Run("should return existing link for same idempotency key", func(t *testing.T) {
 		ctx := context.Background()
-		instance, postgresContainer := infra_postgres.SetupContainer(ctx, t)
-		defer infra_postgres.TerminateContainer(postgresContainer)
-
-		repo := postgres.NewRepository(instance)
+		repo := memory.NewRepository()
 		service := shorturl.NewService(repo)
 
 		id1, _ := shorturl.NewID()
@@ -84,14 +74,14 @@ func TestCreate(t *testing.T) {
 		name := "idempotent-link"
 		link, _ := shorturl.NewLink("https://example.com/original")
 
-		firstResult, firstErr := service.Create(ctx, id1, idempotencyKey, name, link)
+		firstResult, firstErr := service.Create(ctx, id1, idempotencyKey, name, link, shorturl.Details{})
 		if firstErr != nil {
 			t.Fatalf("first Create failed unexpectedly: %v", firstErr)
 		}
 
 		id2, _ := shorturl.NewID()
 
-		secondResult, secondErr := service.Create(ctx, id2, idempotencyKey, name, link)
+		secondResult, secondErr := service.Create(ctx, id2, idempotencyKey, name, link, shorturl.Details{})
 		if secondErr != nil {
 			t.Errorf("expected no error for idempotent creation, got %v", secondErr)
 		}
@@ -102,10 +92,7 @@ func TestCreate(t *testing.T) {
 
 	t.Run("should get a link by his name", func(t *testing.T) {
 		ctx := context.Background()
-		instance, postgresContainer := infra_postgres.SetupContainer(ctx, t)
-		defer infra_postgres.TerminateContainer(postgresContainer)
-
-		repo := postgres.NewRepository(instance)
+		repo := memory.NewRepository()
 		service := shorturl.NewService(repo)
 
 		id1, _ := shorturl.NewID()
@@ -113,7 +100,7 @@ func TestCreate(t *testing.T) {
 		name := "googlewebsitey2k"
 		link, _ := shorturl.NewLink("https://google.com")
 
-		createdLink, creationErr := service.Create(ctx, id1, idempotencyKey, name, link)
+		createdLink, creationErr := service.Create(ctx, id1, idempotencyKey, name, link, shorturl.Details{})
 		if creationErr != nil {
 			t.Fatalf("first Create failed unexpectedly: %v", creationErr)
 		}
@@ -127,3 +114,26 @@ func TestCreate(t *testing.T) {
 		}
 	})
 }
+
+func TestList(t *testing.T) {
+	t.Run("should match tags however they were typed", func(t *testing.T) {
+		ctx := context.Background()
+		repo := memory.NewRepository()
+		service := shorturl.NewService(repo)
+
+		id, _ := shorturl.NewID()
+		idempotencyKey, _ := shorturl.NewIdempotencyKey()
+		link, _ := shorturl.NewLink("https://example.com")
+		if _, err := service.Create(ctx, id, idempotencyKey, "newsletter", link, shorturl.Details{Tags: []string{"Email"}}); err != nil {
+			t.Fatalf("Create failed unexpectedly: %v", err)
+		}
+
+		page, err := service.List(ctx, shorturl.ListFilter{Tags: []string{" EMAIL "}})
+		if err != nil {
+			t.Fatalf("List failed unexpectedly: %v", err)
+		}
+		if len(page.Items) != 1 {
+			t.Errorf("want the tagged link, got %d links", len(page.Items))
+		}
+	})
+}
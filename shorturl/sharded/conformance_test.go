@@ -0,0 +1,35 @@
+package sharded_test
+
+import (
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
+	"github.com/rcovery/go-url-shortener/shorturl/repotest"
+	"github.com/rcovery/go-url-shortener/shorturl/sharded"
+)
+
+func newSharded(t *testing.T, shards ...string) (*sharded.Repository, map[string]*memory.Repository) {
+	t.Helper()
+
+	stores := map[string]*memory.Repository{}
+	routed := map[string]sharded.Shard{}
+	for _, name := range shards {
+		stores[name] = memory.NewRepository()
+		routed[name] = stores[name]
+	}
+
+	repo, err := sharded.NewRepository(routed, stores[shards[0]])
+	if err != nil {
+		t.Fatalf("cannot build a sharded repository: %v", err)
+	}
+	return repo, stores
+}
+
+func TestConformance(t *testing.T) {
+	// Only a link's own shard checks its ID, and UUIDv7 IDs don't collide
+	repotest.RunWith(t, func(t *testing.T) shorturl.Repository {
+		repo, _ := newSharded(t, "a", "b", "c")
+		return repo
+	}, repotest.Options{PartitionedIDs: true})
+}
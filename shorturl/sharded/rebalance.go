@@ -0,0 +1,84 @@
+package sharded
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+const rebalancePageSize = 500
+
+type RebalanceReport struct {
+	DryRun  bool `json:"dryRun"`
+	Scanned int  `json:"scanned"`
+	Moved   int  `json:"moved"`
+	// Moves counts moved links by "from->to" shard pair
+	Moves map[string]int `json:"moves,omitempty"`
+}
+
+// Rebalance moves every link, expired ones included, to the shard its name
+// hashes to. Each page is copied to its new shard before it is deleted
+// from the old one, so an interrupted run loses nothing and can be rerun.
+// Run it with Rebalancing set on every server, and unset it afterwards.
+// Link history moves along with its link
+func (r *Repository) Rebalance(ctx context.Context, dryRun bool) (RebalanceReport, error) {
+	report := RebalanceReport{DryRun: dryRun, Moves: map[string]int{}}
+
+	for _, from := range r.names {
+		source := r.shards[from]
+		filter := shorturl.ListFilter{Sort: shorturl.SortCreated, Limit: rebalancePageSize}
+
+		for {
+			page, listErr := source.List(ctx, filter)
+			if listErr != nil {
+				return report, fmt.Errorf("shard %s: %w", from, listErr)
+			}
+			report.Scanned += len(page)
+
+			moving := map[string][]shorturl.SelectableShortURL{}
+			for _, surl := range page {
+				if to := r.ring.Locate(surl.Name); to != from {
+					moving[to] = append(moving[to], surl)
+				}
+			}
+
+			for to, surls := range moving {
+				if !dryRun {
+					if moveErr := move(ctx, source, r.shards[to], surls); moveErr != nil {
+						return report, fmt.Errorf("moving from shard %s to %s: %w", from, to, moveErr)
+					}
+				}
+				report.Moved += len(surls)
+				report.Moves[from+"->"+to] += len(surls)
+			}
+
+			if len(page) < filter.Limit {
+				break
+			}
+			cursor := shorturl.NewCursor(filter.Sort, page[len(page)-1])
+			filter.After = &cursor
+		}
+	}
+
+	return report, nil
+}
+
+func move(ctx context.Context, source, target Shard, surls []shorturl.SelectableShortURL) error {
+	ids := make([]shorturl.ID, len(surls))
+	for i, surl := range surls {
+		ids[i] = surl.ID
+	}
+
+	// Deleting a link deletes its history, so the history is copied first
+	history, historyErr := source.HistoryOf(ctx, ids)
+	if historyErr != nil {
+		return historyErr
+	}
+	if copyErr := target.CopyIn(ctx, surls, history); copyErr != nil {
+		return copyErr
+	}
+
+	_, deleteErr := source.DeleteByIDs(ctx, ids)
+	return deleteErr
+}
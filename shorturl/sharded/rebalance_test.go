@@ -0,0 +1,143 @@
+package sharded_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
+	"github.com/rcovery/go-url-shortener/shorturl/sharded"
+)
+
+func TestRebalance(t *testing.T) {
+	t.Run("should move links to a new shard and keep them reachable", func(t *testing.T) {
+		ctx := context.Background()
+		old, stores := newSharded(t, "a", "b")
+
+		keys := map[string]shorturl.IdempotencyKey{}
+		for i := range 200 {
+			name := fmt.Sprintf("link-%d", i)
+			id, _ := shorturl.NewID()
+			keys[name], _ = shorturl.NewIdempotencyKey()
+			link, _ := shorturl.NewLink("https://example.com/" + name)
+
+			expiresAt := time.Now().Add(time.Hour)
+			if i%10 == 0 {
+				expiresAt = time.Now().Add(-time.Hour)
+			}
+			insertErr := old.Insert(ctx, shorturl.ShortURL{ID: id, Name: name, Link: link, IdempotencyKey: keys[name], ExpiresAt: expiresAt})
+			if insertErr != nil {
+				t.Fatalf("Insert failed unexpectedly: %v", insertErr)
+			}
+		}
+
+		routed := map[string]sharded.Shard{"a": stores["a"], "b": stores["b"], "c": memory.NewRepository()}
+		grown, err := sharded.NewRepository(routed, stores["a"])
+		if err != nil {
+			t.Fatalf("cannot build a sharded repository: %v", err)
+		}
+		grown.Rebalancing = true
+
+		dryRun, dryRunErr := grown.Rebalance(ctx, true)
+		if dryRunErr != nil {
+			t.Fatalf("dry run failed unexpectedly: %v", dryRunErr)
+		}
+		if dryRun.Scanned != 200 || dryRun.Moved == 0 {
+			t.Fatalf("want all 200 links scanned and some to move, got %+v", dryRun)
+		}
+
+		// Links still on their old shard are found while rebalancing
+		if _, selectErr := grown.SelectByName(ctx, movingName(t, grown, old)); selectErr != nil {
+			t.Errorf("want a link not yet moved to be found, got %v", selectErr)
+		}
+
+		report, rebalanceErr := grown.Rebalance(ctx, false)
+		if rebalanceErr != nil {
+			t.Fatalf("Rebalance failed unexpectedly: %v", rebalanceErr)
+		}
+		if report.Moved != dryRun.Moved {
+			t.Errorf("want %d links moved, got %d", dryRun.Moved, report.Moved)
+		}
+
+		again, _ := grown.Rebalance(ctx, true)
+		if again.Moved != 0 || again.Scanned != 200 {
+			t.Errorf("want nothing left to move and no link lost, got %+v", again)
+		}
+
+		grown.Rebalancing = false
+		for i := 1; i < 200; i += 7 {
+			name := fmt.Sprintf("link-%d", i)
+			if i%10 == 0 {
+				continue
+			}
+
+			if _, selectErr := grown.SelectByName(ctx, name); selectErr != nil {
+				t.Errorf("cannot select %q after rebalancing: %v", name, selectErr)
+			}
+			surl, keyErr := grown.SelectByIdempotencyKey(ctx, keys[name])
+			if keyErr != nil || surl.Name != name {
+				t.Errorf("want %q by its idempotency key, got %q (%v)", name, surl.Name, keyErr)
+			}
+		}
+	})
+
+	t.Run("should move link history along with its link", func(t *testing.T) {
+		ctx := context.Background()
+		old, stores := newSharded(t, "a", "b")
+
+		for i := range 200 {
+			name := fmt.Sprintf("link-%d", i)
+			id, _ := shorturl.NewID()
+			link, _ := shorturl.NewLink("https://example.com/" + name)
+			if insertErr := old.Insert(ctx, shorturl.ShortURL{ID: id, Name: name, Link: link}); insertErr != nil {
+				t.Fatalf("Insert failed unexpectedly: %v", insertErr)
+			}
+		}
+
+		c := memory.NewRepository()
+		routed := map[string]sharded.Shard{"a": stores["a"], "b": stores["b"], "c": c}
+		grown, err := sharded.NewRepository(routed, stores["a"])
+		if err != nil {
+			t.Fatalf("cannot build a sharded repository: %v", err)
+		}
+
+		name := movingName(t, grown, old)
+		surl, selectErr := old.SelectByName(ctx, name)
+		if selectErr != nil {
+			t.Fatalf("SelectByName failed unexpectedly: %v", selectErr)
+		}
+		to, _ := shorturl.NewLink("https://example.org/" + name)
+		change := shorturl.LinkChange{ID: surl.ID, Name: name, From: surl.Link, To: to}
+		if applied, updateErr := old.UpdateLinks(ctx, []shorturl.LinkChange{change}, "moved host"); updateErr != nil || applied != 1 {
+			t.Fatalf("UpdateLinks failed unexpectedly: %d %v", applied, updateErr)
+		}
+
+		if _, rebalanceErr := grown.Rebalance(ctx, false); rebalanceErr != nil {
+			t.Fatalf("Rebalance failed unexpectedly: %v", rebalanceErr)
+		}
+
+		history, historyErr := routed[grown.ShardOf(name)].HistoryOf(ctx, []shorturl.ID{surl.ID})
+		if historyErr != nil || len(history) != 1 {
+			t.Fatalf("want one history entry on the new shard, got %v %v", history, historyErr)
+		}
+		if history[0].NewLink != to.String() || history[0].Reason != "moved host" {
+			t.Errorf("want the change to %q kept, got %+v", to, history[0])
+		}
+	})
+}
+
+// movingName returns a name whose shard changes once "c" is added
+func movingName(t *testing.T, grown, old *sharded.Repository) string {
+	t.Helper()
+
+	for i := range 200 {
+		name := fmt.Sprintf("link-%d", i)
+		if i%10 != 0 && grown.ShardOf(name) != old.ShardOf(name) {
+			return name
+		}
+	}
+	t.Fatalf("no link moves to the new shard")
+	return ""
+}
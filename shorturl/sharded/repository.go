@@ -0,0 +1,405 @@
+// Package sharded spreads links over several stores by a consistent hash
+// of their name. Lookups by name go to one shard, listings and searches
+// ask every shard and merge the answers
+package sharded
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+// Shard is a store holding part of the links
+type Shard interface {
+	shorturl.Repository
+	shorturl.Searcher
+	// CopyIn stores links as they are with their history, leaving alone
+	// those already present
+	CopyIn(ctx context.Context, surls []shorturl.SelectableShortURL, history []shorturl.HistoryEntry) error
+	// HistoryOf returns the link history of ids, oldest first
+	HistoryOf(ctx context.Context, ids []shorturl.ID) ([]shorturl.HistoryEntry, error)
+	DeleteByIDs(ctx context.Context, ids []shorturl.ID) (int, error)
+}
+
+// KeyIndex maps idempotency keys to the name of the link created with
+// them. Names, not shards, are recorded, so rebalancing leaves it alone
+type KeyIndex interface {
+	PutKeyRoutes(ctx context.Context, routes map[shorturl.IdempotencyKey]string) error
+	KeyRoutes(ctx context.Context, keys []shorturl.IdempotencyKey) (map[shorturl.IdempotencyKey]string, error)
+}
+
+type Repository struct {
+	ring   *Ring
+	names  []string
+	shards map[string]Shard
+	index  KeyIndex
+
+	// Rebalancing makes lookups that miss on a name's shard try the
+	// others, so links not yet moved by Rebalance stay reachable
+	Rebalancing bool
+}
+
+// NewRepository routes over shards by their name, which must stay the same
+// for a shard across restarts. index is usually one of the shards
+func NewRepository(shards map[string]Shard, index KeyIndex) (*Repository, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("sharded storage needs at least one shard")
+	}
+	if index == nil {
+		return nil, errors.New("sharded storage needs an idempotency key index")
+	}
+
+	names := make([]string, 0, len(shards))
+	for name := range shards {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return &Repository{ring: NewRing(names), names: names, shards: shards, index: index}, nil
+}
+
+// ShardOf returns the name of the shard a link name belongs to
+func (r *Repository) ShardOf(name string) string {
+	return r.ring.Locate(name)
+}
+
+func (r *Repository) shardOf(name string) Shard {
+	return r.shards[r.ring.Locate(name)]
+}
+
+// lookup asks the shard of name, then, while rebalancing, every other shard
+func (r *Repository) lookup(ctx context.Context, name string, fn func(Shard) (shorturl.SelectableShortURL, error)) (shorturl.SelectableShortURL, error) {
+	home := r.ring.Locate(name)
+	surl, err := fn(r.shards[home])
+	if !r.Rebalancing || !errors.Is(err, errs.NotFoundError) {
+		return surl, err
+	}
+
+	for _, shardName := range r.names {
+		if shardName == home {
+			continue
+		}
+		if found, otherErr := fn(r.shards[shardName]); !errors.Is(otherErr, errs.NotFoundError) {
+			return found, otherErr
+		}
+	}
+
+	return surl, err
+}
+
+func (r *Repository) SelectByName(ctx context.Context, name string) (shorturl.SelectableShortURL, error) {
+	return r.lookup(ctx, name, func(shard Shard) (shorturl.SelectableShortURL, error) {
+		return shard.SelectByName(ctx, name)
+	})
+}
+
+func (r *Repository) SelectByIdempotencyKey(ctx context.Context, idempotencyKey shorturl.IdempotencyKey) (shorturl.SelectableShortURL, error) {
+	notFound := errs.NotFoundError.New(fmt.Sprintf("ByIdempotencyKey: %q", idempotencyKey))
+	if idempotencyKey == "" {
+		return shorturl.SelectableShortURL{}, notFound
+	}
+
+	routes, routesErr := r.index.KeyRoutes(ctx, []shorturl.IdempotencyKey{idempotencyKey})
+	if routesErr != nil {
+		return shorturl.SelectableShortURL{}, routesErr
+	}
+	name, routed := routes[idempotencyKey]
+	if !routed {
+		return shorturl.SelectableShortURL{}, notFound
+	}
+
+	return r.lookup(ctx, name, func(shard Shard) (shorturl.SelectableShortURL, error) {
+		return shard.SelectByIdempotencyKey(ctx, idempotencyKey)
+	})
+}
+
+func (r *Repository) SelectByIdempotencyKeys(ctx context.Context, idempotencyKeys []shorturl.IdempotencyKey) ([]shorturl.SelectableShortURL, error) {
+	if len(idempotencyKeys) == 0 {
+		return nil, nil
+	}
+
+	routes, routesErr := r.index.KeyRoutes(ctx, idempotencyKeys)
+	if routesErr != nil {
+		return nil, routesErr
+	}
+
+	byShard := map[string][]shorturl.IdempotencyKey{}
+	for key, name := range routes {
+		shardName := r.ring.Locate(name)
+		byShard[shardName] = append(byShard[shardName], key)
+	}
+	if r.Rebalancing {
+		keys := slices.Collect(maps.Keys(routes))
+		for _, shardName := range r.names {
+			byShard[shardName] = keys
+		}
+	}
+
+	found, selectErr := scatter(ctx, r, func(ctx context.Context, shardName string, shard Shard) ([]shorturl.SelectableShortURL, error) {
+		if len(byShard[shardName]) == 0 {
+			return nil, nil
+		}
+		return shard.SelectByIdempotencyKeys(ctx, byShard[shardName])
+	})
+	if selectErr != nil {
+		return nil, selectErr
+	}
+
+	return uniqueByID(found), nil
+}
+
+func (r *Repository) SelectByNames(ctx context.Context, names []string) ([]shorturl.SelectableShortURL, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	byShard := map[string][]string{}
+	for _, name := range names {
+		shardName := r.ring.Locate(name)
+		byShard[shardName] = append(byShard[shardName], name)
+	}
+	if r.Rebalancing {
+		for _, shardName := range r.names {
+			byShard[shardName] = names
+		}
+	}
+
+	found, selectErr := scatter(ctx, r, func(ctx context.Context, shardName string, shard Shard) ([]shorturl.SelectableShortURL, error) {
+		if len(byShard[shardName]) == 0 {
+			return nil, nil
+		}
+		return shard.SelectByNames(ctx, byShard[shardName])
+	})
+	if selectErr != nil {
+		return nil, selectErr
+	}
+
+	// A name found on several shards mid-rebalance keeps its active link,
+	// or else its most recently expired one, like a single store would
+	now := time.Now()
+	best := map[string]shorturl.SelectableShortURL{}
+	for _, surl := range found {
+		current, seen := best[surl.Name]
+		if !seen || preferred(surl, current, now) {
+			best[surl.Name] = surl
+		}
+	}
+
+	var selected []shorturl.SelectableShortURL
+	for _, name := range names {
+		if surl, ok := best[name]; ok {
+			selected = append(selected, surl)
+			delete(best, name)
+		}
+	}
+	return selected, nil
+}
+
+func preferred(candidate, current shorturl.SelectableShortURL, now time.Time) bool {
+	candidateActive := candidate.ExpiresAt.After(now)
+	if currentActive := current.ExpiresAt.After(now); candidateActive != currentActive {
+		return candidateActive
+	}
+	return candidate.ExpiresAt.After(current.ExpiresAt)
+}
+
+// List asks every shard for a full page and keeps the first filter.Limit
+// of the merged pages
+func (r *Repository) List(ctx context.Context, filter shorturl.ListFilter) ([]shorturl.SelectableShortURL, error) {
+	found, listErr := scatter(ctx, r, func(ctx context.Context, _ string, shard Shard) ([]shorturl.SelectableShortURL, error) {
+		return shard.List(ctx, filter)
+	})
+	if listErr != nil {
+		return nil, listErr
+	}
+
+	found = uniqueByID(found)
+	slices.SortFunc(found, func(a, b shorturl.SelectableShortURL) int {
+		if filter.Sort.Less(a, b) {
+			return -1
+		}
+		return 1
+	})
+	if filter.Limit >= 0 && len(found) > filter.Limit {
+		found = found[:filter.Limit]
+	}
+
+	return found, nil
+}
+
+func (r *Repository) SelectByDestination(ctx context.Context, query shorturl.DestinationQuery) ([]shorturl.SelectableShortURL, error) {
+	found, selectErr := scatter(ctx, r, func(ctx context.Context, _ string, shard Shard) ([]shorturl.SelectableShortURL, error) {
+		return shard.SelectByDestination(ctx, query)
+	})
+	if selectErr != nil {
+		return nil, selectErr
+	}
+
+	found = uniqueByID(found)
+	slices.SortFunc(found, func(a, b shorturl.SelectableShortURL) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if query.Limit >= 0 && len(found) > query.Limit {
+		found = found[:query.Limit]
+	}
+
+	return found, nil
+}
+
+// Search merges every shard's best matches by rank. Ranks are computed per
+// shard, which is close enough when names are spread evenly
+func (r *Repository) Search(ctx context.Context, query string, limit int) ([]shorturl.SearchResult, error) {
+	results, searchErr := scatter(ctx, r, func(ctx context.Context, _ string, shard Shard) ([]shorturl.SearchResult, error) {
+		return shard.Search(ctx, query, limit)
+	})
+	if searchErr != nil {
+		return nil, searchErr
+	}
+
+	seen := map[shorturl.ID]bool{}
+	results = slices.DeleteFunc(results, func(result shorturl.SearchResult) bool {
+		duplicate := seen[result.ID]
+		seen[result.ID] = true
+		return duplicate
+	})
+	slices.SortStableFunc(results, func(a, b shorturl.SearchResult) int {
+		if order := cmp.Compare(b.Rank, a.Rank); order != 0 {
+			return order
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// Insert records the idempotency key route before the link, so a link can
+// always be found by its key once it exists
+func (r *Repository) Insert(ctx context.Context, surl shorturl.ShortURL) error {
+	if surl.IdempotencyKey != "" {
+		routes := map[shorturl.IdempotencyKey]string{surl.IdempotencyKey: surl.Name}
+		if routeErr := r.index.PutKeyRoutes(ctx, routes); routeErr != nil {
+			return errs.NotCreatedErr.New(routeErr.Error())
+		}
+	}
+
+	return r.shardOf(surl.Name).Insert(ctx, surl)
+}
+
+// InsertMany is atomic per shard only: a failing shard leaves the links
+// already inserted on the others
+func (r *Repository) InsertMany(ctx context.Context, surls []shorturl.ShortURL) ([]shorturl.ID, error) {
+	if len(surls) == 0 {
+		return nil, nil
+	}
+
+	routes := map[shorturl.IdempotencyKey]string{}
+	byShard := map[string][]shorturl.ShortURL{}
+	for _, surl := range surls {
+		if surl.IdempotencyKey != "" {
+			routes[surl.IdempotencyKey] = surl.Name
+		}
+		shardName := r.ring.Locate(surl.Name)
+		byShard[shardName] = append(byShard[shardName], surl)
+	}
+
+	if routeErr := r.index.PutKeyRoutes(ctx, routes); routeErr != nil {
+		return nil, errs.NotCreatedErr.New(routeErr.Error())
+	}
+
+	var inserted []shorturl.ID
+	for _, shardName := range r.names {
+		if len(byShard[shardName]) == 0 {
+			continue
+		}
+
+		ids, insertErr := r.shards[shardName].InsertMany(ctx, byShard[shardName])
+		if insertErr != nil {
+			return nil, insertErr
+		}
+		inserted = append(inserted, ids...)
+	}
+
+	return inserted, nil
+}
+
+// UpdateLinks is atomic per shard only, like InsertMany
+func (r *Repository) UpdateLinks(ctx context.Context, changes []shorturl.LinkChange, reason string) (int, error) {
+	byShard := map[string][]shorturl.LinkChange{}
+	for _, change := range changes {
+		shardName := r.ring.Locate(change.Name)
+		byShard[shardName] = append(byShard[shardName], change)
+	}
+	if r.Rebalancing {
+		for _, shardName := range r.names {
+			byShard[shardName] = changes
+		}
+	}
+
+	applied := 0
+	for _, shardName := range r.names {
+		if len(byShard[shardName]) == 0 {
+			continue
+		}
+
+		shardApplied, updateErr := r.shards[shardName].UpdateLinks(ctx, byShard[shardName], reason)
+		if updateErr != nil {
+			return applied, updateErr
+		}
+		applied += shardApplied
+	}
+
+	return applied, nil
+}
+
+// scatter runs fn on every shard at once and gathers what they return.
+// The first failure cancels the other shards' calls
+func scatter[T any](ctx context.Context, r *Repository, fn func(ctx context.Context, shardName string, shard Shard) ([]T, error)) ([]T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]T, len(r.names))
+
+	var once sync.Once
+	var failure error
+	var wg sync.WaitGroup
+	for i, shardName := range r.names {
+		wg.Go(func() {
+			found, err := fn(ctx, shardName, r.shards[shardName])
+			if err != nil {
+				once.Do(func() {
+					failure = fmt.Errorf("shard %s: %w", shardName, err)
+					cancel()
+				})
+				return
+			}
+			results[i] = found
+		})
+	}
+	wg.Wait()
+
+	if failure != nil {
+		return nil, failure
+	}
+	return slices.Concat(results...), nil
+}
+
+// uniqueByID drops copies of a link found on two shards mid-rebalance
+func uniqueByID(surls []shorturl.SelectableShortURL) []shorturl.SelectableShortURL {
+	seen := map[shorturl.ID]bool{}
+	return slices.DeleteFunc(surls, func(surl shorturl.SelectableShortURL) bool {
+		duplicate := seen[surl.ID]
+		seen[surl.ID] = true
+		return duplicate
+	})
+}
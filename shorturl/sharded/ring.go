@@ -0,0 +1,87 @@
+package sharded
+
+import (
+	"hash/fnv"
+	"slices"
+	"strconv"
+)
+
+// VirtualNodes is how many points each shard gets on the ring. More points
+// spread names more evenly, and adding a shard takes about 1/n of the names
+// from every other shard rather than all of one neighbour's
+const VirtualNodes = 128
+
+type point struct {
+	hash  uint64
+	shard string
+}
+
+// Ring maps names to shards by consistent hashing, so adding a shard only
+// moves the names that now land on it
+type Ring struct {
+	points []point
+}
+
+func NewRing(shards []string) *Ring {
+	ring := &Ring{points: make([]point, 0, len(shards)*VirtualNodes)}
+	for _, shard := range shards {
+		for i := range VirtualNodes {
+			ring.points = append(ring.points, point{hash: hash(shard + "#" + strconv.Itoa(i)), shard: shard})
+		}
+	}
+
+	slices.SortFunc(ring.points, func(a, b point) int {
+		switch {
+		case a.hash < b.hash:
+			return -1
+		case a.hash > b.hash:
+			return 1
+		}
+		// Ties are broken by shard name, so every process builds the same ring
+		if a.shard < b.shard {
+			return -1
+		}
+		return 1
+	})
+
+	return ring
+}
+
+// Locate returns the shard a name belongs to: the first point clockwise
+// from the name's hash
+func (r *Ring) Locate(name string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+
+	h := hash(name)
+	i, _ := slices.BinarySearchFunc(r.points, h, func(p point, target uint64) int {
+		switch {
+		case p.hash < target:
+			return -1
+		case p.hash > target:
+			return 1
+		}
+		return 0
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+
+	return r.points[i].shard
+}
+
+// hash is FNV-1a with a final mix, since FNV alone leaves names that only
+// differ in their last bytes close together on the ring
+func hash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,51 @@
+package sharded_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/shorturl/sharded"
+)
+
+func TestRing(t *testing.T) {
+	names := make([]string, 10000)
+	for i := range names {
+		names[i] = fmt.Sprintf("link-%d", i)
+	}
+
+	t.Run("should spread names evenly", func(t *testing.T) {
+		ring := sharded.NewRing([]string{"a", "b", "c", "d"})
+
+		counts := map[string]int{}
+		for _, name := range names {
+			counts[ring.Locate(name)]++
+		}
+
+		for _, shard := range []string{"a", "b", "c", "d"} {
+			if counts[shard] < 1500 || counts[shard] > 3500 {
+				t.Errorf("want about 2500 names on shard %s, got %d", shard, counts[shard])
+			}
+		}
+	})
+
+	t.Run("should only move names to an added shard", func(t *testing.T) {
+		before := sharded.NewRing([]string{"a", "b", "c"})
+		after := sharded.NewRing([]string{"a", "b", "c", "d"})
+
+		moved := 0
+		for _, name := range names {
+			from, to := before.Locate(name), after.Locate(name)
+			if from == to {
+				continue
+			}
+			if to != "d" {
+				t.Fatalf("want %q to move to the new shard, moved from %s to %s", name, from, to)
+			}
+			moved++
+		}
+
+		if moved < 1500 || moved > 3500 {
+			t.Errorf("want about a quarter of the names moved, got %d", moved)
+		}
+	})
+}
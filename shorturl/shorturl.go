@@ -1,14 +1,63 @@
 package shorturl
 
+import (
+	"strings"
+	"time"
+)
+
 type ShortURL struct {
-	ID             ID
-	Link           *Link
-	Name           string
-	IdempotencyKey IdempotencyKey
-	ExpiresAt      string
+	ID             ID             `json:"id"`
+	Link           *Link          `json:"link"`
+	Name           string         `json:"name"`
+	IdempotencyKey IdempotencyKey `json:"idempotencyKey"`
+	ExpiresAt      time.Time      `json:"expiresAt,omitzero"`
+	Details
 }
 
 type SelectableShortURL struct {
-	ID   ID
-	Link *Link
+	ID             ID             `json:"id"`
+	Name           string         `json:"name"`
+	Link           *Link          `json:"link"`
+	IdempotencyKey IdempotencyKey `json:"idempotencyKey,omitempty"`
+	ExpiresAt      time.Time      `json:"expiresAt,omitzero"`
+	CreatedAt      time.Time      `json:"createdAt,omitzero"`
+	Details
+}
+
+// Details are the optional, user-provided labels stored with a link
+type Details struct {
+	Owner       string   `json:"owner,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Metadata    Metadata `json:"metadata,omitempty"`
+}
+
+// Metadata is a free-form JSON object, stored as JSONB
+type Metadata map[string]any
+
+// Normalize trims and lowercases tags, dropping empty and repeated ones,
+// so filtering by tag doesn't depend on how each link was labeled
+func (d Details) Normalize() Details {
+	d.Owner = strings.TrimSpace(d.Owner)
+	d.Title = strings.TrimSpace(d.Title)
+	d.Description = strings.TrimSpace(d.Description)
+	d.Tags = normalizeTags(d.Tags)
+	return d
+}
+
+func normalizeTags(raw []string) []string {
+	tags := make([]string, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for _, tag := range raw {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return tags
 }
@@ -0,0 +1,27 @@
+package shorturl_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+func TestDetailsNormalize(t *testing.T) {
+	t.Run("should lowercase, trim and deduplicate tags", func(t *testing.T) {
+		details := shorturl.Details{
+			Title: "  Q3 deck ",
+			Tags:  []string{"Email", " email", "", "Launch-2026 "},
+		}
+
+		normalized := details.Normalize()
+
+		want := []string{"email", "launch-2026"}
+		if !slices.Equal(normalized.Tags, want) {
+			t.Errorf("want %q, got %q", want, normalized.Tags)
+		}
+		if normalized.Title != "Q3 deck" {
+			t.Errorf("want %q, got %q", "Q3 deck", normalized.Title)
+		}
+	})
+}
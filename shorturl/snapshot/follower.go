@@ -0,0 +1,84 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DeltaOverlap is subtracted from the last snapshot date when asking for
+// a delta, so links committed by transactions that started earlier are
+// not missed. Entries received twice are simply applied again
+const DeltaOverlap = time.Minute
+
+// maxSnapshotSize guards followers against a misbehaving leader
+const maxSnapshotSize = 1 << 30
+
+// Follower keeps a Store up to date from a leader's GET /api/snapshot
+type Follower struct {
+	leader   string
+	interval time.Duration
+	store    *Store
+	client   *http.Client
+}
+
+func NewFollower(leader string, interval time.Duration, store *Store) *Follower {
+	return &Follower{
+		leader:   leader,
+		interval: interval,
+		store:    store,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// Sync fetches a full snapshot the first time, and deltas after that
+func (f *Follower) Sync(ctx context.Context) error {
+	query := url.Values{}
+	if generatedAt := f.store.GeneratedAt(); !generatedAt.IsZero() {
+		query.Set("since", generatedAt.Add(-DeltaOverlap).Format(time.RFC3339Nano))
+	}
+
+	request, requestErr := http.NewRequestWithContext(ctx, http.MethodGet, f.leader+"/api/snapshot?"+query.Encode(), nil)
+	if requestErr != nil {
+		return requestErr
+	}
+
+	response, responseErr := f.client.Do(request)
+	if responseErr != nil {
+		return responseErr
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("snapshot from %s: status %d", f.leader, response.StatusCode)
+	}
+
+	snap, decodeErr := Decode(io.LimitReader(response.Body, maxSnapshotSize))
+	if decodeErr != nil {
+		return decodeErr
+	}
+
+	return f.store.Apply(snap, time.Now())
+}
+
+// Run syncs every interval until ctx is done. A failed sync leaves the
+// store as it was, so the follower keeps serving what it already has
+func (f *Follower) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := f.Sync(ctx); err != nil {
+				log.Println("snapshot sync failed:", err)
+			}
+		}
+	}
+}
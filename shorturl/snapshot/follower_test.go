@@ -0,0 +1,50 @@
+package snapshot_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl/snapshot"
+)
+
+func TestFollower(t *testing.T) {
+	t.Run("should ask for a full snapshot first and deltas after", func(t *testing.T) {
+		now := time.Now().UTC()
+		var sinces []string
+
+		leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			since := r.URL.Query().Get("since")
+			sinces = append(sinces, since)
+
+			snap := snapshot.Snapshot{GeneratedAt: now}
+			if since == "" {
+				snap.Entries = []snapshot.Entry{{Name: "q3", Link: "https://example.com/q3", ExpiresAt: now.Add(time.Hour)}}
+			} else {
+				snap.Since, _ = time.Parse(time.RFC3339Nano, since)
+				snap.GeneratedAt = now.Add(time.Second)
+				snap.Entries = []snapshot.Entry{{Name: "q4", Link: "https://example.com/q4", ExpiresAt: now.Add(time.Hour)}}
+			}
+			snapshot.Encode(w, snap)
+		}))
+		defer leader.Close()
+
+		store := snapshot.NewStore()
+		follower := snapshot.NewFollower(leader.URL, time.Hour, store)
+		for range 2 {
+			if err := follower.Sync(context.Background()); err != nil {
+				t.Fatalf("Sync() %v", err)
+			}
+		}
+
+		want := now.Add(-snapshot.DeltaOverlap).Format(time.RFC3339Nano)
+		if len(sinces) != 2 || sinces[0] != "" || sinces[1] != want {
+			t.Errorf("want requests since %q and %q, got %q", "", want, sinces)
+		}
+		if store.Len() != 2 {
+			t.Errorf("want 2 links, got %d", store.Len())
+		}
+	})
+}
@@ -0,0 +1,243 @@
+// Package snapshot serializes active links into a compact binary file, and
+// lets read-only followers serve redirects from memory by loading it
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+// Version is bumped whenever the layout below changes
+//
+//	magic       "SURLSNAP"
+//	version     uint8
+//	generatedAt int64, unix nanoseconds
+//	since       int64, unix nanoseconds, 0 for a full snapshot
+//	pages       each a count (uvarint) followed by count entries: name
+//	            (uvarint length, bytes), link (uvarint length, bytes),
+//	            expiresAt (varint unix seconds)
+//	end         a zero count
+//	checksum    uint32, CRC-32C of everything before it
+//
+// Entries come in pages so a snapshot can be written while the links are
+// still being read, without knowing how many there are
+const Version uint8 = 2
+
+const magic = "SURLSNAP"
+
+const pageSize = 500
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	ErrInvalid  = errors.New("invalid snapshot")
+	ErrChecksum = errors.New("snapshot checksum mismatch")
+	ErrVersion  = errors.New("unsupported snapshot version")
+)
+
+type Entry struct {
+	Name      string
+	Link      string
+	ExpiresAt time.Time
+}
+
+// Snapshot holds either every active link, or with Since set, only the
+// links changed since then
+type Snapshot struct {
+	GeneratedAt time.Time
+	Since       time.Time
+	Entries     []Entry
+}
+
+// Full reports whether the snapshot replaces, rather than updates, what a follower holds
+func (s Snapshot) Full() bool {
+	return s.Since.IsZero()
+}
+
+// Write encodes the links active at now, only those changed since since
+// when it is set, one page at a time as they are read, so the snapshot is
+// never held in memory. It is dated now, so followers can ask for the next
+// delta from there. When reading fails partway, the checksum is left out
+// and the snapshot won't decode
+func Write(ctx context.Context, w io.Writer, service *shorturl.Service, now, since time.Time) error {
+	enc := newEncoder(w, now, since)
+
+	page := make([]Entry, 0, pageSize)
+	filter := shorturl.ListFilter{ExpiresAfter: now, ChangedAfter: since, Limit: pageSize}
+	walkErr := service.Walk(ctx, filter, func(surl shorturl.SelectableShortURL) error {
+		page = append(page, Entry{Name: surl.Name, Link: surl.Link.String(), ExpiresAt: surl.ExpiresAt})
+		if len(page) < pageSize {
+			return nil
+		}
+
+		pageErr := enc.page(page)
+		page = page[:0]
+		return pageErr
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+
+	if pageErr := enc.page(page); pageErr != nil {
+		return pageErr
+	}
+	return enc.close()
+}
+
+func Encode(w io.Writer, snap Snapshot) error {
+	enc := newEncoder(w, snap.GeneratedAt, snap.Since)
+	if err := enc.page(snap.Entries); err != nil {
+		return err
+	}
+	return enc.close()
+}
+
+type encoder struct {
+	w        io.Writer
+	checksum hash.Hash32
+	buffered *bufio.Writer
+	scratch  [binary.MaxVarintLen64]byte
+}
+
+func newEncoder(w io.Writer, generatedAt, since time.Time) *encoder {
+	checksum := crc32.New(castagnoli)
+	enc := &encoder{w: w, checksum: checksum, buffered: bufio.NewWriter(io.MultiWriter(w, checksum))}
+
+	enc.buffered.WriteString(magic)
+	enc.buffered.WriteByte(Version)
+	binary.Write(enc.buffered, binary.BigEndian, unixNano(generatedAt))
+	binary.Write(enc.buffered, binary.BigEndian, unixNano(since))
+	return enc
+}
+
+func (e *encoder) writeUvarint(value uint64) {
+	e.buffered.Write(e.scratch[:binary.PutUvarint(e.scratch[:], value)])
+}
+
+func (e *encoder) writeString(value string) {
+	e.writeUvarint(uint64(len(value)))
+	e.buffered.WriteString(value)
+}
+
+// page writes entries, and reports the first error of any write so far.
+// An empty page is skipped, since a zero count ends the snapshot
+func (e *encoder) page(entries []Entry) error {
+	if len(entries) > 0 {
+		e.writeUvarint(uint64(len(entries)))
+		for _, entry := range entries {
+			e.writeString(entry.Name)
+			e.writeString(entry.Link)
+			e.buffered.Write(e.scratch[:binary.PutVarint(e.scratch[:], entry.ExpiresAt.Unix())])
+		}
+	}
+
+	// bufio.Writer keeps the first error, and returns it from any later write
+	_, err := e.buffered.Write(nil)
+	return err
+}
+
+func (e *encoder) close() error {
+	e.writeUvarint(0)
+	if err := e.buffered.Flush(); err != nil {
+		return err
+	}
+	return binary.Write(e.w, binary.BigEndian, e.checksum.Sum32())
+}
+
+func Decode(r io.Reader) (Snapshot, error) {
+	var snap Snapshot
+
+	content, readErr := io.ReadAll(r)
+	if readErr != nil {
+		return snap, readErr
+	}
+	if len(content) < len(magic)+1+8+8+1+4 || string(content[:len(magic)]) != magic {
+		return snap, ErrInvalid
+	}
+
+	body, trailer := content[:len(content)-4], content[len(content)-4:]
+	if crc32.Checksum(body, castagnoli) != binary.BigEndian.Uint32(trailer) {
+		return snap, ErrChecksum
+	}
+
+	reader := bytes.NewReader(body[len(magic):])
+	version, _ := reader.ReadByte()
+	if version != Version {
+		return snap, fmt.Errorf("%w %d", ErrVersion, version)
+	}
+
+	var generatedAt, since int64
+	binary.Read(reader, binary.BigEndian, &generatedAt)
+	binary.Read(reader, binary.BigEndian, &since)
+	snap.GeneratedAt = fromUnixNano(generatedAt)
+	snap.Since = fromUnixNano(since)
+
+	readString := func() (string, error) {
+		length, err := binary.ReadUvarint(reader)
+		if err != nil || length > uint64(reader.Len()) {
+			return "", ErrInvalid
+		}
+		value := make([]byte, length)
+		reader.Read(value)
+		return string(value), nil
+	}
+
+	for {
+		count, countErr := binary.ReadUvarint(reader)
+		// Every entry takes at least three bytes, which bounds the allocation below
+		if countErr != nil || count > uint64(reader.Len()/3) {
+			return snap, ErrInvalid
+		}
+		if count == 0 {
+			break
+		}
+
+		snap.Entries = slices.Grow(snap.Entries, int(count))
+		for range count {
+			name, nameErr := readString()
+			if nameErr != nil {
+				return snap, nameErr
+			}
+			link, linkErr := readString()
+			if linkErr != nil {
+				return snap, linkErr
+			}
+			expiresAt, expiresErr := binary.ReadVarint(reader)
+			if expiresErr != nil {
+				return snap, ErrInvalid
+			}
+
+			snap.Entries = append(snap.Entries, Entry{Name: name, Link: link, ExpiresAt: time.Unix(expiresAt, 0).UTC()})
+		}
+	}
+	if reader.Len() != 0 {
+		return snap, ErrInvalid
+	}
+
+	return snap, nil
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos).UTC()
+}
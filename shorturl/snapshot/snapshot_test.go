@@ -0,0 +1,126 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
+	"github.com/rcovery/go-url-shortener/shorturl/repotest"
+	"github.com/rcovery/go-url-shortener/shorturl/snapshot"
+)
+
+func encode(t *testing.T, snap snapshot.Snapshot) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	if err := snapshot.Encode(&buffer, snap); err != nil {
+		t.Fatalf("Encode() %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func TestEncodeDecode(t *testing.T) {
+	generatedAt := time.Date(2030, 1, 2, 3, 4, 5, 6, time.UTC)
+	snap := snapshot.Snapshot{
+		GeneratedAt: generatedAt,
+		Since:       generatedAt.Add(-time.Hour),
+		Entries: []snapshot.Entry{
+			{Name: "q3", Link: "https://example.com/q3", ExpiresAt: generatedAt.Add(24 * time.Hour).Truncate(time.Second)},
+			{Name: "ünïcode", Link: "https://example.com/ü", ExpiresAt: generatedAt.Add(time.Hour).Truncate(time.Second)},
+		},
+	}
+
+	t.Run("should read back what was written", func(t *testing.T) {
+		decoded, err := snapshot.Decode(bytes.NewReader(encode(t, snap)))
+		if err != nil {
+			t.Fatalf("Decode() %v", err)
+		}
+
+		if !decoded.GeneratedAt.Equal(snap.GeneratedAt) || !decoded.Since.Equal(snap.Since) {
+			t.Errorf("want dates %v %v, got %v %v", snap.GeneratedAt, snap.Since, decoded.GeneratedAt, decoded.Since)
+		}
+		if len(decoded.Entries) != len(snap.Entries) {
+			t.Fatalf("want %d entries, got %d", len(snap.Entries), len(decoded.Entries))
+		}
+		for i, entry := range decoded.Entries {
+			want := snap.Entries[i]
+			if entry.Name != want.Name || entry.Link != want.Link || !entry.ExpiresAt.Equal(want.ExpiresAt) {
+				t.Errorf("want %+v, got %+v", want, entry)
+			}
+		}
+	})
+
+	t.Run("should keep full snapshots full", func(t *testing.T) {
+		decoded, err := snapshot.Decode(bytes.NewReader(encode(t, snapshot.Snapshot{GeneratedAt: generatedAt})))
+		if err != nil {
+			t.Fatalf("Decode() %v", err)
+		}
+		if !decoded.Full() || len(decoded.Entries) != 0 {
+			t.Errorf("want an empty full snapshot, got %+v", decoded)
+		}
+	})
+
+	t.Run("should reject corrupted snapshots", func(t *testing.T) {
+		encoded := encode(t, snap)
+		encoded[len(encoded)/2] ^= 0xFF
+
+		if _, err := snapshot.Decode(bytes.NewReader(encoded)); !errors.Is(err, snapshot.ErrChecksum) {
+			t.Errorf("want %v, got %v", snapshot.ErrChecksum, err)
+		}
+	})
+
+	t.Run("should reject truncated snapshots", func(t *testing.T) {
+		encoded := encode(t, snap)
+
+		if _, err := snapshot.Decode(bytes.NewReader(encoded[:len(encoded)-10])); err == nil {
+			t.Errorf("want an error for a truncated snapshot")
+		}
+	})
+
+	t.Run("should reject other versions", func(t *testing.T) {
+		encoded := encode(t, snap)
+		encoded[len("SURLSNAP")] = snapshot.Version + 1
+
+		// Keep the checksum valid, so only the version is wrong
+		body := encoded[:len(encoded)-4]
+		binary.BigEndian.PutUint32(encoded[len(body):], crc32.Checksum(body, crc32.MakeTable(crc32.Castagnoli)))
+
+		if _, err := snapshot.Decode(bytes.NewReader(encoded)); !errors.Is(err, snapshot.ErrVersion) {
+			t.Errorf("want %v, got %v", snapshot.ErrVersion, err)
+		}
+	})
+}
+
+func TestWrite(t *testing.T) {
+	t.Run("should write every active link across pages", func(t *testing.T) {
+		repo := memory.NewRepository()
+		now := time.Now()
+		for i := range 1200 {
+			repotest.Insert(t, repo, repotest.NewShortURL(t, fmt.Sprintf("link-%d", i), now.Add(time.Hour)))
+		}
+		repotest.Insert(t, repo, repotest.NewShortURL(t, "expired", now.Add(-time.Hour)))
+
+		var buffer bytes.Buffer
+		if err := snapshot.Write(context.Background(), &buffer, shorturl.NewService(repo), now, time.Time{}); err != nil {
+			t.Fatalf("Write() %v", err)
+		}
+
+		decoded, err := snapshot.Decode(&buffer)
+		if err != nil {
+			t.Fatalf("Decode() %v", err)
+		}
+		if !decoded.GeneratedAt.Equal(now) || !decoded.Full() {
+			t.Errorf("want a full snapshot dated %v, got %v since %v", now, decoded.GeneratedAt, decoded.Since)
+		}
+		if len(decoded.Entries) != 1200 {
+			t.Errorf("want 1200 entries, got %d", len(decoded.Entries))
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+type storedLink struct {
+	link      *shorturl.Link
+	location  shorturl.Location
+	expiresAt time.Time
+}
+
+// Store serves redirects from the snapshots applied to it
+type Store struct {
+	mu          sync.RWMutex
+	links       map[string]storedLink
+	generatedAt time.Time
+}
+
+func NewStore() *Store {
+	return &Store{links: map[string]storedLink{}}
+}
+
+// Apply replaces the store's content with a full snapshot, or merges a
+// delta into it. A delta must start at or before the last applied
+// snapshot, otherwise changes in between would be lost
+func (s *Store) Apply(snap Snapshot, now time.Time) error {
+	links := make(map[string]storedLink, len(snap.Entries))
+	for _, entry := range snap.Entries {
+		link, linkErr := shorturl.NewLink(entry.Link)
+		if linkErr != nil {
+			return fmt.Errorf("%w: link of %q: %v", ErrInvalid, entry.Name, linkErr)
+		}
+		links[entry.Name] = storedLink{link: link, location: shorturl.NewLocation(link), expiresAt: entry.ExpiresAt}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !snap.Full() {
+		if s.generatedAt.IsZero() || snap.Since.After(s.generatedAt) {
+			return fmt.Errorf("%w: delta since %s does not follow %s", ErrInvalid, snap.Since, s.generatedAt)
+		}
+
+		for name, stored := range s.links {
+			if _, ok := links[name]; !ok && stored.expiresAt.After(now) {
+				links[name] = stored
+			}
+		}
+	}
+
+	s.links = links
+	s.generatedAt = snap.GeneratedAt
+	return nil
+}
+
+// GeneratedAt is the date of the last applied snapshot, zero before the first
+func (s *Store) GeneratedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.generatedAt
+}
+
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.links)
+}
+
+// Select has the same meaning as shorturl.Service.Select
+func (s *Store) Select(ctx context.Context, name string) (*shorturl.Link, error) {
+	s.mu.RLock()
+	stored, ok := s.links[name]
+	s.mu.RUnlock()
+
+	if !ok || !stored.expiresAt.After(time.Now()) {
+		return nil, errs.NotFoundError.New(fmt.Sprintf("snapshot: %q", name))
+	}
+
+	return stored.link, nil
+}
+
+// CachedLocation answers every redirect, since the store holds all links
+func (s *Store) CachedLocation(name string) (shorturl.Location, bool) {
+	s.mu.RLock()
+	stored, ok := s.links[name]
+	s.mu.RUnlock()
+
+	if !ok || !stored.expiresAt.After(time.Now()) {
+		return nil, true
+	}
+	return stored.location, true
+}
+
+// Location has the same meaning as shorturl.Service.Location
+func (s *Store) Location(ctx context.Context, name string) (shorturl.Location, error) {
+	location, _ := s.CachedLocation(name)
+	if location == nil {
+		return nil, errs.NotFoundError.New(fmt.Sprintf("snapshot: %q", name))
+	}
+	return location, nil
+}
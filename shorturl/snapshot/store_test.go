@@ -0,0 +1,100 @@
+package snapshot_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+	"github.com/rcovery/go-url-shortener/shorturl/snapshot"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	tomorrow := now.Add(24 * time.Hour)
+
+	full := snapshot.Snapshot{
+		GeneratedAt: now,
+		Entries: []snapshot.Entry{
+			{Name: "q3", Link: "https://example.com/q3", ExpiresAt: tomorrow},
+			{Name: "q4", Link: "https://example.com/q4", ExpiresAt: tomorrow},
+		},
+	}
+
+	t.Run("should merge deltas into a full snapshot", func(t *testing.T) {
+		store := snapshot.NewStore()
+		if err := store.Apply(full, now); err != nil {
+			t.Fatalf("Apply() %v", err)
+		}
+
+		delta := snapshot.Snapshot{
+			GeneratedAt: now.Add(time.Minute),
+			Since:       now.Add(-time.Minute),
+			Entries: []snapshot.Entry{
+				{Name: "q3", Link: "https://example.com/q3-v2", ExpiresAt: tomorrow},
+				{Name: "q5", Link: "https://example.com/q5", ExpiresAt: tomorrow},
+			},
+		}
+		if err := store.Apply(delta, now); err != nil {
+			t.Fatalf("Apply() %v", err)
+		}
+
+		want := map[string]string{"q3": "https://example.com/q3-v2", "q4": "https://example.com/q4", "q5": "https://example.com/q5"}
+		for name, link := range want {
+			got, err := store.Select(ctx, name)
+			if err != nil {
+				t.Fatalf("Select(%q) %v", name, err)
+			}
+			if got.String() != link {
+				t.Errorf("want %q, got %q", link, got)
+			}
+		}
+		if !store.GeneratedAt().Equal(delta.GeneratedAt) {
+			t.Errorf("want %v, got %v", delta.GeneratedAt, store.GeneratedAt())
+		}
+	})
+
+	t.Run("should refuse a delta leaving a gap", func(t *testing.T) {
+		store := snapshot.NewStore()
+		if err := store.Apply(full, now); err != nil {
+			t.Fatalf("Apply() %v", err)
+		}
+
+		delta := snapshot.Snapshot{GeneratedAt: now.Add(time.Hour), Since: now.Add(time.Minute)}
+		if err := store.Apply(delta, now); !errors.Is(err, snapshot.ErrInvalid) {
+			t.Errorf("want %v, got %v", snapshot.ErrInvalid, err)
+		}
+	})
+
+	t.Run("should not serve expired links", func(t *testing.T) {
+		store := snapshot.NewStore()
+		expired := snapshot.Snapshot{
+			GeneratedAt: now,
+			Entries:     []snapshot.Entry{{Name: "old", Link: "https://example.com/old", ExpiresAt: now.Add(-time.Second)}},
+		}
+		if err := store.Apply(expired, now); err != nil {
+			t.Fatalf("Apply() %v", err)
+		}
+
+		if _, err := store.Select(ctx, "old"); !errors.Is(err, errs.NotFoundError) {
+			t.Errorf("want %v, got %v", errs.NotFoundError, err)
+		}
+	})
+
+	t.Run("should resolve redirects to a Location header", func(t *testing.T) {
+		store := snapshot.NewStore()
+		if err := store.Apply(full, now); err != nil {
+			t.Fatalf("Apply() %v", err)
+		}
+
+		location, err := store.Location(ctx, "q3")
+		if err != nil || len(location) != 1 || location[0] != "https://example.com/q3" {
+			t.Errorf("want %q, got %q (%v)", "https://example.com/q3", location, err)
+		}
+		if _, err := store.Location(ctx, "missing"); !errors.Is(err, errs.NotFoundError) {
+			t.Errorf("want %v, got %v", errs.NotFoundError, err)
+		}
+	})
+}
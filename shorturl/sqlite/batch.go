@@ -0,0 +1,128 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+// jsonArray passes a list as one parameter, expanded in SQL with json_each
+func jsonArray[T any](values []T) (string, error) {
+	encoded, err := json.Marshal(values)
+	return string(encoded), err
+}
+
+func (r *Repository) SelectByIdempotencyKeys(ctx context.Context, idempotencyKeys []shorturl.IdempotencyKey) ([]shorturl.SelectableShortURL, error) {
+	if len(idempotencyKeys) == 0 {
+		return nil, nil
+	}
+
+	keys, keysErr := jsonArray(idempotencyKeys)
+	if keysErr != nil {
+		return nil, keysErr
+	}
+
+	rows, queryErr := r.DB.QueryContext(ctx, `
+		SELECT `+selectColumns+`
+		FROM shorturls
+		WHERE idempotency_key IN (SELECT value FROM json_each(?))
+			AND expires_at > ?
+	`, keys, micros(time.Now()))
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	return scanShortURLs(rows)
+}
+
+// InsertMany runs in one transaction. Like the Postgres repository, names
+// are checked against the links stored before the batch, and rows reusing
+// a stored ID are skipped
+func (r *Repository) InsertMany(ctx context.Context, surls []shorturl.ShortURL) ([]shorturl.ID, error) {
+	if len(surls) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	rows := make([]row, 0, len(surls))
+	names := make([]string, 0, len(surls))
+	for _, surl := range surls {
+		if surl.Link == nil {
+			return nil, errs.NotCreatedErr.New("missing link")
+		}
+		rw, rowErr := newRow(surl, now)
+		if rowErr != nil {
+			return nil, rowErr
+		}
+		rows = append(rows, rw)
+		names = append(names, surl.Name)
+	}
+
+	tx, txErr := r.DB.BeginTx(ctx, nil)
+	if txErr != nil {
+		return nil, errs.NotCreatedErr.New(txErr.Error())
+	}
+	defer tx.Rollback()
+
+	encodedNames, namesErr := jsonArray(names)
+	if namesErr != nil {
+		return nil, errs.NotCreatedErr.New(namesErr.Error())
+	}
+	takenRows, takenErr := tx.QueryContext(ctx, `
+		SELECT DISTINCT name
+		FROM shorturls
+		WHERE name IN (SELECT value FROM json_each(?))
+			AND expires_at > ?
+	`, encodedNames, micros(now))
+	if takenErr != nil {
+		return nil, errs.NotCreatedErr.New(takenErr.Error())
+	}
+	taken := map[string]bool{}
+	for takenRows.Next() {
+		var name string
+		if scanErr := takenRows.Scan(&name); scanErr != nil {
+			takenRows.Close()
+			return nil, scanErr
+		}
+		taken[name] = true
+	}
+	takenRows.Close()
+	if rowsErr := takenRows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+
+	statement, prepareErr := tx.PrepareContext(ctx, insertRow+" ON CONFLICT (id) DO NOTHING")
+	if prepareErr != nil {
+		return nil, errs.NotCreatedErr.New(prepareErr.Error())
+	}
+	defer statement.Close()
+
+	var inserted []shorturl.ID
+	for _, rw := range rows {
+		if taken[rw.name] {
+			continue
+		}
+		result, insertionErr := statement.ExecContext(ctx, rw.args()...)
+		if insertionErr != nil {
+			return nil, errs.NotCreatedErr.New(insertionErr.Error())
+		}
+		affected, affectedErr := result.RowsAffected()
+		if affectedErr != nil {
+			return nil, errs.NotCreatedErr.New(affectedErr.Error())
+		}
+		if affected == 0 {
+			continue
+		}
+		inserted = append(inserted, shorturl.ID(rw.id))
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		return nil, errs.NotCreatedErr.New(commitErr.Error())
+	}
+
+	return inserted, nil
+}
@@ -0,0 +1,17 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+
+	infra_sqlite "github.com/rcovery/go-url-shortener/internal/infra/sqlite"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/repotest"
+	"github.com/rcovery/go-url-shortener/shorturl/sqlite"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) shorturl.Repository {
+		return sqlite.NewRepository(infra_sqlite.SetupDatabase(context.Background(), t))
+	})
+}
@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+func (r *Repository) SelectByDestination(ctx context.Context, destination shorturl.DestinationQuery) ([]shorturl.SelectableShortURL, error) {
+	var q query
+
+	switch destination.Match {
+	case shorturl.MatchExact:
+		q.where("link_normalized = " + q.arg(destination.Target))
+	case shorturl.MatchPrefix:
+		// LIKE ignores ASCII case in SQLite, so compare the prefix itself
+		target := q.arg(destination.Target)
+		q.where("substr(link_normalized, 1, length(" + target + ")) = " + target)
+	case shorturl.MatchHost:
+		q.where("link_host = " + q.arg(destination.Target))
+	default:
+		return nil, errs.InvalidError.New(fmt.Sprintf("unknown match %q", destination.Match))
+	}
+
+	if destination.AfterID != "" {
+		q.where("id > " + q.arg(destination.AfterID))
+	}
+
+	rows, queryErr := r.DB.QueryContext(ctx, `
+		SELECT `+selectColumns+`
+		FROM shorturls
+		`+q.whereClause()+`
+		ORDER BY id
+		LIMIT `+q.arg(destination.Limit),
+		q.args...,
+	)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	return scanShortURLs(rows)
+}
@@ -0,0 +1,202 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+// query accumulates WHERE conditions and their numbered arguments
+type query struct {
+	conditions []string
+	args       []any
+}
+
+func (q *query) arg(value any) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("?%d", len(q.args))
+}
+
+func (q *query) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+func (q *query) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *Repository) List(ctx context.Context, filter shorturl.ListFilter) ([]shorturl.SelectableShortURL, error) {
+	var q query
+
+	if filter.Owner != "" {
+		q.where("owner = " + q.arg(filter.Owner))
+	}
+	if len(filter.Tags) > 0 {
+		tags, tagsErr := jsonArray(filter.Tags)
+		if tagsErr != nil {
+			return nil, errs.InvalidError.New(tagsErr.Error())
+		}
+		q.where("NOT EXISTS (SELECT 1 FROM json_each(" + q.arg(tags) + ") AS wanted WHERE wanted.value NOT IN (SELECT value FROM json_each(tags)))")
+	}
+	if len(filter.Metadata) > 0 {
+		// Round-trip through JSON, so values compare as they were stored
+		var metadata map[string]any
+		encoded, metadataErr := json.Marshal(filter.Metadata)
+		if metadataErr != nil {
+			return nil, errs.InvalidError.New(metadataErr.Error())
+		}
+		json.Unmarshal(encoded, &metadata)
+
+		if containsErr := whereContains(&q, "$", metadata); containsErr != nil {
+			return nil, containsErr
+		}
+	}
+	if filter.Host != "" {
+		q.where("link_host = " + q.arg(strings.ToLower(filter.Host)))
+	}
+	if filter.Domain != "" {
+		domain := strings.ToLower(filter.Domain)
+		q.where("(link_host = " + q.arg(domain) + " OR link_host LIKE " + q.arg("%."+likeEscaper.Replace(domain)) + ` ESCAPE '\')`)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		q.where("created_at >= " + q.arg(micros(filter.CreatedAfter)))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		q.where("created_at < " + q.arg(micros(filter.CreatedBefore)))
+	}
+	if !filter.ExpiresAfter.IsZero() {
+		q.where("expires_at >= " + q.arg(micros(filter.ExpiresAfter)))
+	}
+	if !filter.ExpiresBefore.IsZero() {
+		q.where("expires_at < " + q.arg(micros(filter.ExpiresBefore)))
+	}
+	if !filter.ChangedAfter.IsZero() {
+		q.where("updated_at >= " + q.arg(micros(filter.ChangedAfter)))
+	}
+
+	column, direction, comparison := "", "ASC", ">"
+	if filter.Sort.Descending() {
+		direction, comparison = "DESC", "<"
+	}
+	switch filter.Sort {
+	case shorturl.SortName, shorturl.SortNameDesc:
+		column = "name"
+	case shorturl.SortExpires, shorturl.SortExpiresDesc:
+		column = "expires_at"
+	}
+
+	if filter.After != nil {
+		var key any = filter.After.Key
+		if column == "expires_at" {
+			expiresAt, parseErr := time.Parse(time.RFC3339Nano, filter.After.Key)
+			if parseErr != nil {
+				return nil, errs.InvalidError.New(fmt.Sprintf("invalid cursor key %q", filter.After.Key))
+			}
+			key = micros(expiresAt)
+		}
+
+		if column == "" {
+			q.where("id " + comparison + " " + q.arg(filter.After.ID))
+		} else {
+			q.where("(" + column + ", id) " + comparison + " (" + q.arg(key) + ", " + q.arg(filter.After.ID) + ")")
+		}
+	}
+
+	orderBy := "id " + direction
+	if column != "" {
+		orderBy = column + " " + direction + ", " + orderBy
+	}
+
+	rows, queryErr := r.DB.QueryContext(ctx, `
+		SELECT `+selectColumns+`
+		FROM shorturls
+		`+q.whereClause()+`
+		ORDER BY `+orderBy+`
+		LIMIT `+q.arg(filter.Limit),
+		q.args...,
+	)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	return scanShortURLs(rows)
+}
+
+// whereContains adds conditions matching the JSONB @> operator on the
+// metadata column: every key of want must be present at path with a
+// contained value. Arrays may only hold scalars
+func whereContains(q *query, path string, want any) error {
+	switch want := want.(type) {
+	case map[string]any:
+		if len(want) == 0 {
+			q.where("json_type(metadata, " + q.arg(path) + ") = 'object'")
+			return nil
+		}
+
+		keys := make([]string, 0, len(want))
+		for key := range want {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if strings.ContainsAny(key, `"`) {
+				return errs.InvalidError.New(fmt.Sprintf("unsupported metadata key %q", key))
+			}
+			if err := whereContains(q, path+`."`+key+`"`, want[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		q.where("json_type(metadata, " + q.arg(path) + ") = 'array'")
+		for _, element := range want {
+			condition, conditionErr := scalarCondition(q, "element.type", "element.value", element)
+			if conditionErr != nil {
+				return conditionErr
+			}
+			q.where("EXISTS (SELECT 1 FROM json_each(metadata, " + q.arg(path) + ") AS element WHERE " + condition + ")")
+		}
+		return nil
+	}
+
+	pathArg := q.arg(path)
+	condition, conditionErr := scalarCondition(q, "json_type(metadata, "+pathArg+")", "json_extract(metadata, "+pathArg+")", want)
+	if conditionErr != nil {
+		return conditionErr
+	}
+	q.where(condition)
+	return nil
+}
+
+// scalarCondition compares a JSON value, given as SQL expressions for its
+// type and value, with a decoded scalar
+func scalarCondition(q *query, jsonType, value string, want any) (string, error) {
+	switch want := want.(type) {
+	case nil:
+		return jsonType + " = 'null'", nil
+	case bool:
+		if want {
+			return jsonType + " = 'true'", nil
+		}
+		return jsonType + " = 'false'", nil
+	case string:
+		return "(" + jsonType + " = 'text' AND " + value + " = " + q.arg(want) + ")", nil
+	case float64:
+		return "(" + jsonType + " IN ('integer', 'real') AND " + value + " = " + q.arg(want) + ")", nil
+	}
+
+	return "", errs.InvalidError.New(fmt.Sprintf("unsupported metadata value %v", want))
+}
@@ -0,0 +1,222 @@
+// Package sqlite implements shorturl.Repository on SQLite, for small
+// deployments that run as a single binary
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+// DefaultTTL is how long a link lives when it is inserted without an expiry
+const DefaultTTL = 24 * time.Hour
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(DB *sql.DB) *Repository {
+	return &Repository{
+		DB,
+	}
+}
+
+const selectColumns = `id, name, link, COALESCE(idempotency_key, ''), expires_at, created_at, COALESCE(owner, ''), COALESCE(title, ''), COALESCE(description, ''), tags, metadata`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// micros is how times are stored
+func micros(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+func fromMicros(value int64) time.Time {
+	return time.UnixMicro(value).UTC()
+}
+
+func scanShortURL(row scanner) (shorturl.SelectableShortURL, error) {
+	var rawDBLink, rawTags, rawMetadata string
+	var expiresAt, createdAt int64
+	var surl shorturl.SelectableShortURL
+
+	scanErr := row.Scan(
+		&surl.ID,
+		&surl.Name,
+		&rawDBLink,
+		&surl.IdempotencyKey,
+		&expiresAt,
+		&createdAt,
+		&surl.Owner,
+		&surl.Title,
+		&surl.Description,
+		&rawTags,
+		&rawMetadata,
+	)
+	if scanErr != nil {
+		return surl, scanErr
+	}
+	surl.ExpiresAt = fromMicros(expiresAt)
+	surl.CreatedAt = fromMicros(createdAt)
+
+	link, linkErr := shorturl.NewLink(rawDBLink)
+	if linkErr != nil {
+		return surl, linkErr
+	}
+	surl.Link = link
+
+	if tagsErr := json.Unmarshal([]byte(rawTags), &surl.Tags); tagsErr != nil {
+		return surl, tagsErr
+	}
+	if metadataErr := json.Unmarshal([]byte(rawMetadata), &surl.Metadata); metadataErr != nil {
+		return surl, metadataErr
+	}
+
+	return surl, nil
+}
+
+func scanShortURLs(rows *sql.Rows) ([]shorturl.SelectableShortURL, error) {
+	var surls []shorturl.SelectableShortURL
+	for rows.Next() {
+		surl, scanErr := scanShortURL(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+
+		surls = append(surls, surl)
+	}
+
+	return surls, rows.Err()
+}
+
+func (r *Repository) SelectByName(ctx context.Context, name string) (shorturl.SelectableShortURL, error) {
+	row := r.DB.QueryRowContext(ctx, `
+		SELECT `+selectColumns+`
+		FROM shorturls
+		WHERE name = ?
+			AND expires_at > ?
+		ORDER BY expires_at DESC
+		LIMIT 1
+	`, name, micros(time.Now()))
+
+	surl, scanErr := scanShortURL(row)
+	if errors.Is(scanErr, sql.ErrNoRows) {
+		return surl, errs.NotFoundError.New(fmt.Sprintf("ByName: %v", scanErr))
+	}
+	if scanErr != nil {
+		return surl, scanErr
+	}
+
+	return surl, nil
+}
+
+func (r *Repository) SelectByIdempotencyKey(ctx context.Context, idempotencyKey shorturl.IdempotencyKey) (shorturl.SelectableShortURL, error) {
+	row := r.DB.QueryRowContext(ctx, `
+		SELECT `+selectColumns+`
+		FROM shorturls
+		WHERE idempotency_key = ?
+			AND expires_at > ?
+		LIMIT 1
+	`, idempotencyKey, micros(time.Now()))
+
+	surl, scanErr := scanShortURL(row)
+	if errors.Is(scanErr, sql.ErrNoRows) {
+		return surl, errs.NotFoundError.New(fmt.Sprintf("ByIdempotencyKey: %v", scanErr))
+	}
+	if scanErr != nil {
+		return surl, scanErr
+	}
+
+	return surl, nil
+}
+
+// row holds the column values of one link, with the defaults the Postgres
+// schema applies
+type row struct {
+	id, name, link, host, normalized string
+	idempotencyKey                   sql.NullString
+	expiresAt, now                   int64
+	owner, title, description        sql.NullString
+	tags, metadata                   string
+}
+
+func newRow(surl shorturl.ShortURL, now time.Time) (row, error) {
+	metadata := shorturl.Metadata{}
+	if surl.Metadata != nil {
+		metadata = surl.Metadata
+	}
+	encodedMetadata, metadataErr := json.Marshal(metadata)
+	if metadataErr != nil {
+		return row{}, errs.NotCreatedErr.New(metadataErr.Error())
+	}
+
+	tags := surl.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	encodedTags, tagsErr := json.Marshal(tags)
+	if tagsErr != nil {
+		return row{}, errs.NotCreatedErr.New(tagsErr.Error())
+	}
+
+	expiresAt := surl.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(DefaultTTL)
+	}
+
+	return row{
+		id:             string(surl.ID),
+		name:           surl.Name,
+		link:           surl.Link.String(),
+		host:           surl.Link.Hostname(),
+		normalized:     surl.Link.Normalized(),
+		idempotencyKey: nullString(string(surl.IdempotencyKey)),
+		expiresAt:      micros(expiresAt),
+		now:            micros(now),
+		owner:          nullString(surl.Owner),
+		title:          nullString(surl.Title),
+		description:    nullString(surl.Description),
+		tags:           string(encodedTags),
+		metadata:       string(encodedMetadata),
+	}, nil
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+const insertRow = `
+	INSERT INTO shorturls
+	(id, name, link, link_host, link_normalized, idempotency_key, expires_at, created_at, updated_at, owner, title, description, tags, metadata)
+	VALUES
+	(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+func (rw row) args() []any {
+	return []any{rw.id, rw.name, rw.link, rw.host, rw.normalized, rw.idempotencyKey, rw.expiresAt, rw.now, rw.now, rw.owner, rw.title, rw.description, rw.tags, rw.metadata}
+}
+
+func (r *Repository) Insert(ctx context.Context, surl shorturl.ShortURL) error {
+	if surl.Link == nil {
+		return errs.NotCreatedErr.New("missing link")
+	}
+
+	rw, rowErr := newRow(surl, time.Now())
+	if rowErr != nil {
+		return rowErr
+	}
+
+	_, insertionErr := r.DB.ExecContext(ctx, insertRow, rw.args()...)
+	if insertionErr != nil {
+		return errs.NotCreatedErr.New(insertionErr.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	infra_sqlite "github.com/rcovery/go-url-shortener/internal/infra/sqlite"
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+	"github.com/rcovery/go-url-shortener/shorturl/repotest"
+	"github.com/rcovery/go-url-shortener/shorturl/sqlite"
+)
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should read back what was inserted", func(t *testing.T) {
+		repo := sqlite.NewRepository(infra_sqlite.SetupDatabase(ctx, t))
+
+		surl := repotest.NewShortURL(t, "q3", time.Time{})
+		surl.Details = shorturl.Details{Owner: "marketing", Title: "Q3", Tags: []string{"launch"}, Metadata: shorturl.Metadata{"channel": "email"}}
+		if err := repo.Insert(ctx, surl); err != nil {
+			t.Fatalf("Insert() %v", err)
+		}
+
+		found, err := repo.SelectByName(ctx, "q3")
+		if err != nil {
+			t.Fatalf("SelectByName() %v", err)
+		}
+		if found.ID != surl.ID || found.Link.String() != "https://example.com/q3" || found.Owner != "marketing" {
+			t.Errorf("unexpected link %+v", found)
+		}
+		if len(found.Tags) != 1 || found.Metadata["channel"] != "email" {
+			t.Errorf("unexpected details %+v", found.Details)
+		}
+		if until := time.Until(found.ExpiresAt); until < 23*time.Hour || until > sqlite.DefaultTTL {
+			t.Errorf("want an expiry in a day, got %v", found.ExpiresAt)
+		}
+
+		byKey, err := repo.SelectByIdempotencyKey(ctx, surl.IdempotencyKey)
+		if err != nil || byKey.ID != surl.ID {
+			t.Errorf("want %q by idempotency key, got %+v %v", surl.ID, byKey, err)
+		}
+	})
+
+	t.Run("should not report database errors as not found", func(t *testing.T) {
+		db := infra_sqlite.SetupDatabase(ctx, t)
+		repo := sqlite.NewRepository(db)
+		db.Close()
+
+		if _, err := repo.SelectByName(ctx, "q3"); err == nil || errors.Is(err, errs.NotFoundError) {
+			t.Errorf("want the database error, got %v", err)
+		}
+		if _, err := repo.SelectByIdempotencyKey(ctx, "key"); err == nil || errors.Is(err, errs.NotFoundError) {
+			t.Errorf("want the database error, got %v", err)
+		}
+	})
+
+	t.Run("should apply link changes and record them", func(t *testing.T) {
+		repo := sqlite.NewRepository(infra_sqlite.SetupDatabase(ctx, t))
+		surl := repotest.NewShortURLTo(t, "q3", "https://old.example.com/q3", time.Time{})
+		if err := repo.Insert(ctx, surl); err != nil {
+			t.Fatalf("Insert() %v", err)
+		}
+
+		to, _ := shorturl.NewLink("https://new.example.com/q3")
+		applied, err := repo.UpdateLinks(ctx, []shorturl.LinkChange{{ID: surl.ID, Name: surl.Name, From: surl.Link, To: to}}, "migration")
+		if err != nil || applied != 1 {
+			t.Fatalf("want 1 change applied, got %d %v", applied, err)
+		}
+
+		query, _ := shorturl.NewDestinationQuery(shorturl.MatchHost, "new.example.com")
+		query.Limit = 10
+		found, err := repo.SelectByDestination(ctx, query)
+		if err != nil || len(found) != 1 {
+			t.Errorf("want the link under its new host, got %v %v", found, err)
+		}
+	})
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	repo := sqlite.NewRepository(infra_sqlite.SetupDatabase(ctx, t))
+	service := shorturl.NewService(repo)
+
+	for i, name := range []string{"c", "a", "b"} {
+		surl := repotest.NewShortURLTo(t, name, "https://docs.example.com/"+name, time.Now().Add(time.Duration(i+1)*time.Hour))
+		surl.Tags = []string{"docs", name}
+		surl.Metadata = shorturl.Metadata{"campaign": map[string]any{"channel": name, "index": i}, "labels": []any{"x", name}}
+		if err := repo.Insert(ctx, surl); err != nil {
+			t.Fatalf("Insert() %v", err)
+		}
+	}
+
+	walk := func(t *testing.T, filter shorturl.ListFilter) string {
+		t.Helper()
+
+		var names []string
+		err := service.Walk(ctx, filter, func(surl shorturl.SelectableShortURL) error {
+			names = append(names, surl.Name)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk() %v", err)
+		}
+		return fmt.Sprint(names)
+	}
+
+	t.Run("should page through in sort order", func(t *testing.T) {
+		if got := walk(t, shorturl.ListFilter{Sort: shorturl.SortNameDesc, Limit: 1}); got != "[c b a]" {
+			t.Errorf("want [c b a], got %v", got)
+		}
+		if got := walk(t, shorturl.ListFilter{Sort: shorturl.SortExpires, Limit: 1}); got != "[c a b]" {
+			t.Errorf("want [c a b], got %v", got)
+		}
+	})
+
+	t.Run("should filter by tags, metadata and domain", func(t *testing.T) {
+		filters := map[string]shorturl.ListFilter{
+			"tags":            {Tags: []string{"docs", "b"}},
+			"nested metadata": {Metadata: shorturl.Metadata{"campaign": map[string]any{"channel": "b"}}},
+			"numeric":         {Metadata: shorturl.Metadata{"campaign": map[string]any{"index": 2}}},
+			"array":           {Metadata: shorturl.Metadata{"labels": []any{"b"}}},
+		}
+
+		for name, filter := range filters {
+			filter.Domain = "example.com"
+			if got := walk(t, filter); got != "[b]" {
+				t.Errorf("%s: want [b], got %v", name, got)
+			}
+		}
+	})
+}
+
+func TestSearch(t *testing.T) {
+	t.Run("should rank matches and highlight them safely", func(t *testing.T) {
+		ctx := context.Background()
+		repo := sqlite.NewRepository(infra_sqlite.SetupDatabase(ctx, t))
+
+		surl := repotest.NewShortURLTo(t, "launch-deck", "https://example.com/deck", time.Time{})
+		surl.Title = "Launch <deck>"
+		if err := repo.Insert(ctx, surl); err != nil {
+			t.Fatalf("Insert() %v", err)
+		}
+		if err := repo.Insert(ctx, repotest.NewShortURL(t, "other", time.Time{})); err != nil {
+			t.Fatalf("Insert() %v", err)
+		}
+
+		results, err := repo.Search(ctx, `launc "`, 10)
+		if err != nil {
+			t.Fatalf("Search() %v", err)
+		}
+		if len(results) != 1 || results[0].Name != "launch-deck" {
+			t.Fatalf("want only launch-deck, got %+v", results)
+		}
+
+		want := "<mark>Launch</mark> &lt;deck&gt;"
+		if got := results[0].Highlights["title"]; got != want {
+			t.Errorf("want %q, got %q", want, got)
+		}
+	})
+}
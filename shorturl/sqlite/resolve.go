@@ -0,0 +1,39 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+func (r *Repository) SelectByNames(ctx context.Context, names []string) ([]shorturl.SelectableShortURL, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	encodedNames, namesErr := jsonArray(names)
+	if namesErr != nil {
+		return nil, namesErr
+	}
+
+	rows, queryErr := r.DB.QueryContext(ctx, `
+		SELECT `+selectColumns+`
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (
+				PARTITION BY name
+				ORDER BY expires_at > ? DESC, expires_at DESC
+			) AS position
+			FROM shorturls
+			WHERE name IN (SELECT value FROM json_each(?))
+		)
+		WHERE position = 1
+		ORDER BY name
+	`, micros(time.Now()), encodedNames)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	return scanShortURLs(rows)
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+func (r *Repository) UpdateLinks(ctx context.Context, changes []shorturl.LinkChange, reason string) (int, error) {
+	tx, txErr := r.DB.BeginTx(ctx, nil)
+	if txErr != nil {
+		return 0, txErr
+	}
+	defer tx.Rollback()
+
+	now := micros(time.Now())
+	applied := 0
+	for _, change := range changes {
+		result, updateErr := tx.ExecContext(ctx, `
+			UPDATE shorturls
+			SET link = ?, link_host = ?, link_normalized = ?, updated_at = ?
+			WHERE id = ?
+				AND link = ?
+		`, change.To.String(), change.To.Hostname(), change.To.Normalized(), now, change.ID, change.From.String())
+		if updateErr != nil {
+			return 0, updateErr
+		}
+
+		updated, rowsErr := result.RowsAffected()
+		if rowsErr != nil {
+			return 0, rowsErr
+		}
+		if updated == 0 {
+			continue
+		}
+
+		_, historyErr := tx.ExecContext(ctx, `
+			INSERT INTO shorturl_link_history
+			(shorturl_id, old_link, new_link, reason, changed_at)
+			VALUES
+			(?, ?, ?, NULLIF(?, ''), ?)
+		`, change.ID, change.From.String(), change.To.String(), reason, now)
+		if historyErr != nil {
+			return 0, historyErr
+		}
+
+		applied++
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		return 0, commitErr
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"context"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+// Search ranks active links with FTS5's bm25 over name, title, description, tags
+// and destination. Every query word also matches as a prefix
+func (r *Repository) Search(ctx context.Context, query string, limit int) ([]shorturl.SearchResult, error) {
+	match := matchExpression(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	rows, queryErr := r.DB.QueryContext(ctx, `
+		SELECT `+qualifiedColumns+`,
+			-bm25(shorturls_fts, 10.0, 5.0, 1.0, 2.0, 1.0) AS rank,
+			highlight(shorturls_fts, 0, '<mark>', '</mark>'),
+			COALESCE(highlight(shorturls_fts, 1, '<mark>', '</mark>'), ''),
+			highlight(shorturls_fts, 4, '<mark>', '</mark>')
+		FROM shorturls_fts
+		JOIN shorturls s ON s.rowid = shorturls_fts.rowid
+		WHERE shorturls_fts MATCH ?
+			AND s.expires_at > ?
+		ORDER BY rank DESC, s.id DESC
+		LIMIT ?
+	`, match, micros(time.Now()), limit)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	var results []shorturl.SearchResult
+	for rows.Next() {
+		var result shorturl.SearchResult
+		var nameHighlight, titleHighlight, linkHighlight string
+
+		surl, scanErr := scanShortURL(scanWith(rows, &result.Rank, &nameHighlight, &titleHighlight, &linkHighlight))
+		if scanErr != nil {
+			return nil, scanErr
+		}
+
+		result.SelectableShortURL = surl
+		for field, highlight := range map[string]string{
+			"name":  nameHighlight,
+			"title": titleHighlight,
+			"link":  linkHighlight,
+		} {
+			if !strings.Contains(highlight, "<mark>") {
+				continue
+			}
+			if result.Highlights == nil {
+				result.Highlights = map[string]string{}
+			}
+			result.Highlights[field] = escapeHighlight(highlight)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// qualifiedColumns is selectColumns for a query joining shorturls as s
+var qualifiedColumns = `s.id, s.name, s.link, COALESCE(s.idempotency_key, ''), s.expires_at, s.created_at, COALESCE(s.owner, ''), COALESCE(s.title, ''), COALESCE(s.description, ''), s.tags, s.metadata`
+
+// matchExpression quotes every word of a free-text query, so FTS5 syntax
+// in it is searched for literally
+func matchExpression(query string) string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+
+	return strings.Join(terms, " OR ")
+}
+
+var unescapeMarks = strings.NewReplacer("&lt;mark&gt;", "<mark>", "&lt;/mark&gt;", "</mark>")
+
+// escapeHighlight makes a highlight safe to render as HTML, keeping only
+// the <mark> tags added by highlight()
+func escapeHighlight(highlight string) string {
+	return unescapeMarks.Replace(html.EscapeString(highlight))
+}
+
+// extraScanner appends destinations after the ones scanShortURL reads,
+// for queries that select more than selectColumns
+type extraScanner struct {
+	row   scanner
+	extra []any
+}
+
+func scanWith(row scanner, extra ...any) scanner {
+	return extraScanner{row, extra}
+}
+
+func (s extraScanner) Scan(dest ...any) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
@@ -0,0 +1,153 @@
+package staticsite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+// ManifestFile is written at the root of the output directory
+const ManifestFile = "manifest.json"
+
+const pageSize = 500
+
+type Manifest struct {
+	GeneratedAt time.Time        `json:"generatedAt"`
+	Links       map[string]Entry `json:"links"`
+}
+
+type Entry struct {
+	Link      string    `json:"link"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// Hash is the SHA-256 of the page, used to skip unchanged pages on the next run
+	Hash string `json:"hash"`
+}
+
+type Report struct {
+	Written   int      `json:"written"`
+	Unchanged int      `json:"unchanged"`
+	Removed   int      `json:"removed"`
+	Skipped   []string `json:"skipped,omitempty"`
+}
+
+// Generate writes <out>/<name>/index.html for every link active at now and
+// the manifest. Pages whose content matches the previous manifest are left
+// untouched, and pages of links no longer active are removed, so only the
+// difference needs uploading
+func Generate(ctx context.Context, out string, service *shorturl.Service, now time.Time) (Report, error) {
+	var report Report
+
+	previous, manifestErr := readManifest(out)
+	if manifestErr != nil {
+		return report, manifestErr
+	}
+
+	current := Manifest{GeneratedAt: now.UTC(), Links: map[string]Entry{}}
+	filter := shorturl.ListFilter{ExpiresAfter: now, Sort: shorturl.SortName, Limit: pageSize}
+	walkErr := service.Walk(ctx, filter, func(surl shorturl.SelectableShortURL) error {
+		if !validDirName(surl.Name) {
+			report.Skipped = append(report.Skipped, surl.Name)
+			return nil
+		}
+
+		page := Page(surl)
+		entry := Entry{Link: surl.Link.String(), ExpiresAt: surl.ExpiresAt.UTC(), Hash: hashOf(page)}
+		current.Links[surl.Name] = entry
+
+		pagePath := filepath.Join(out, surl.Name, "index.html")
+		if old, ok := previous.Links[surl.Name]; ok && old.Hash == entry.Hash && fileExists(pagePath) {
+			report.Unchanged++
+			return nil
+		}
+
+		if err := writeFileAtomic(pagePath, page); err != nil {
+			return err
+		}
+		report.Written++
+		return nil
+	})
+	if walkErr != nil {
+		// The previous manifest stays, so the next run retries everything this one wrote
+		return report, walkErr
+	}
+
+	for name := range previous.Links {
+		if _, ok := current.Links[name]; ok || !validDirName(name) {
+			continue
+		}
+
+		removeErr := os.Remove(filepath.Join(out, name, "index.html"))
+		if removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			return report, removeErr
+		}
+		// Leave the directory when something else was put there
+		_ = os.Remove(filepath.Join(out, name))
+		report.Removed++
+	}
+
+	encoded, encodeErr := json.MarshalIndent(current, "", "  ")
+	if encodeErr != nil {
+		return report, encodeErr
+	}
+	return report, writeFileAtomic(filepath.Join(out, ManifestFile), append(encoded, '\n'))
+}
+
+func readManifest(out string) (Manifest, error) {
+	manifest := Manifest{Links: map[string]Entry{}}
+
+	content, readErr := os.ReadFile(filepath.Join(out, ManifestFile))
+	if errors.Is(readErr, fs.ErrNotExist) {
+		return manifest, nil
+	}
+	if readErr != nil {
+		return manifest, readErr
+	}
+
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return manifest, err
+	}
+	if manifest.Links == nil {
+		manifest.Links = map[string]Entry{}
+	}
+	return manifest, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// writeFileAtomic replaces path in one step, so a sync running alongside
+// never uploads a half-written page
+func writeFileAtomic(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	temp, createErr := os.CreateTemp(dir, ".tmp-*")
+	if createErr != nil {
+		return createErr
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Chmod(0o644); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
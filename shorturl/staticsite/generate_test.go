@@ -0,0 +1,84 @@
+package staticsite_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
+	"github.com/rcovery/go-url-shortener/shorturl/staticsite"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Run("should only rewrite pages that changed since the last run", func(t *testing.T) {
+		ctx := context.Background()
+		service := shorturl.NewService(memory.NewRepository())
+		for _, name := range []string{"q3", "q4"} {
+			id, _ := shorturl.NewID()
+			idempotencyKey, _ := shorturl.NewIdempotencyKey()
+			link, _ := shorturl.NewLink("https://example.com/" + name)
+			if _, err := service.Create(ctx, id, idempotencyKey, name, link, shorturl.Details{}); err != nil {
+				t.Fatalf("Create failed unexpectedly: %v", err)
+			}
+		}
+
+		out := t.TempDir()
+		first, err := staticsite.Generate(ctx, out, service, time.Now())
+		if err != nil {
+			t.Fatalf("Generate failed unexpectedly: %v", err)
+		}
+		if first.Written != 2 || first.Unchanged != 0 {
+			t.Errorf("want 2 pages written, got %+v", first)
+		}
+
+		second, err := staticsite.Generate(ctx, out, service, time.Now())
+		if err != nil {
+			t.Fatalf("Generate failed unexpectedly: %v", err)
+		}
+		if second.Written != 0 || second.Unchanged != 2 {
+			t.Errorf("want 2 pages unchanged, got %+v", second)
+		}
+
+		// Generating after every link expired removes their pages
+		third, err := staticsite.Generate(ctx, out, service, time.Now().Add(48*time.Hour))
+		if err != nil {
+			t.Fatalf("Generate failed unexpectedly: %v", err)
+		}
+		if third.Removed != 2 {
+			t.Errorf("want 2 pages removed, got %+v", third)
+		}
+		if _, err := os.Stat(filepath.Join(out, "q3", "index.html")); !os.IsNotExist(err) {
+			t.Errorf("want q3 removed, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(out, staticsite.ManifestFile)); err != nil {
+			t.Errorf("want the manifest kept, got %v", err)
+		}
+	})
+
+	t.Run("should skip a link named like the manifest", func(t *testing.T) {
+		ctx := context.Background()
+		service := shorturl.NewService(memory.NewRepository())
+		for _, name := range []string{"q3", staticsite.ManifestFile} {
+			id, _ := shorturl.NewID()
+			idempotencyKey, _ := shorturl.NewIdempotencyKey()
+			link, _ := shorturl.NewLink("https://example.com/" + name)
+			if _, err := service.Create(ctx, id, idempotencyKey, name, link, shorturl.Details{}); err != nil {
+				t.Fatalf("Create failed unexpectedly: %v", err)
+			}
+		}
+
+		out := t.TempDir()
+		for range 2 {
+			report, err := staticsite.Generate(ctx, out, service, time.Now())
+			if err != nil {
+				t.Fatalf("Generate failed unexpectedly: %v", err)
+			}
+			if len(report.Skipped) != 1 || report.Skipped[0] != staticsite.ManifestFile {
+				t.Errorf("want the manifest's name skipped, got %+v", report)
+			}
+		}
+	})
+}
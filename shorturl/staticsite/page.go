@@ -0,0 +1,60 @@
+// Package staticsite publishes active links as static HTML redirect pages,
+// so a CDN can serve them without reaching this service
+package staticsite
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+const pageTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>%[1]s</title>
+<meta name="robots" content="noindex">
+<meta http-equiv="refresh" content="0; url=%[2]s">
+<link rel="canonical" href="%[2]s">
+</head>
+<body>
+<p>Redirecting to <a href="%[2]s">%[2]s</a></p>
+</body>
+</html>
+`
+
+// Page renders the redirect page of one link
+func Page(surl shorturl.SelectableShortURL) []byte {
+	title := surl.Title
+	if title == "" {
+		title = surl.Name
+	}
+
+	return fmt.Appendf(nil, pageTemplate, html.EscapeString(title), html.EscapeString(surl.Link.String()))
+}
+
+func hashOf(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// validDirName reports whether name can be used as a single directory
+// below the output directory on every common filesystem and object store.
+// The manifest's name is taken, whatever its case
+func validDirName(name string) bool {
+	if name == "" || name == "." || name == ".." || strings.HasPrefix(name, ".") || strings.EqualFold(name, ManifestFile) {
+		return false
+	}
+
+	for _, r := range name {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package staticsite_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/staticsite"
+)
+
+func TestPage(t *testing.T) {
+	t.Run("should escape the destination in every attribute", func(t *testing.T) {
+		link, _ := shorturl.NewLink(`https://example.com/?q="><script>&x=1`)
+		page := string(staticsite.Page(shorturl.SelectableShortURL{Name: "q3", Link: link}))
+
+		if strings.Contains(page, "<script>") {
+			t.Errorf("want the destination escaped, got %s", page)
+		}
+
+		want := `<meta http-equiv="refresh" content="0; url=https://example.com/?q=&#34;&gt;&lt;script&gt;&amp;x=1">`
+		if !strings.Contains(page, want) {
+			t.Errorf("want %q in %s", want, page)
+		}
+	})
+
+	t.Run("should ask not to be indexed", func(t *testing.T) {
+		link, _ := shorturl.NewLink("https://example.com")
+		page := string(staticsite.Page(shorturl.SelectableShortURL{Name: "q3", Link: link}))
+
+		for _, want := range []string{`<meta name="robots" content="noindex">`, `<link rel="canonical" href="https://example.com">`} {
+			if !strings.Contains(page, want) {
+				t.Errorf("want %q in %s", want, page)
+			}
+		}
+	})
+}
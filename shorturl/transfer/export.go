@@ -0,0 +1,156 @@
+package transfer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+const exportPageSize = 500
+
+var csvHeader = []string{"name", "link", "title", "description", "tags", "owner", "metadata", "expires_at", "created_at", "idempotency_key", "id"}
+
+// Encoder writes links one at a time, so exports stream instead of
+// being built in memory
+type Encoder interface {
+	Encode(surl shorturl.SelectableShortURL) error
+	// Close writes whatever the format needs after the last link
+	Close() error
+}
+
+func NewEncoder(w io.Writer, format Format) (Encoder, error) {
+	switch format {
+	case CSV:
+		return &csvEncoder{writer: csv.NewWriter(w)}, nil
+	case JSON:
+		return &jsonEncoder{w: w}, nil
+	case NDJSON:
+		return &ndjsonEncoder{encoder: json.NewEncoder(w)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// Export writes every link matching filter to w and returns how many were written
+func Export(ctx context.Context, w io.Writer, format Format, service *shorturl.Service, filter shorturl.ListFilter) (int, error) {
+	encoder, encoderErr := NewEncoder(w, format)
+	if encoderErr != nil {
+		return 0, encoderErr
+	}
+
+	exported := 0
+	filter.Limit = exportPageSize
+	walkErr := service.Walk(ctx, filter, func(surl shorturl.SelectableShortURL) error {
+		exported++
+		return encoder.Encode(surl)
+	})
+	if walkErr != nil {
+		return exported, walkErr
+	}
+
+	return exported, encoder.Close()
+}
+
+type csvEncoder struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (e *csvEncoder) Encode(surl shorturl.SelectableShortURL) error {
+	if !e.headerWritten {
+		if err := e.writer.Write(csvHeader); err != nil {
+			return err
+		}
+		e.headerWritten = true
+	}
+
+	metadata := ""
+	if len(surl.Metadata) > 0 {
+		encoded, err := json.Marshal(surl.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = string(encoded)
+	}
+
+	return e.writer.Write([]string{
+		surl.Name,
+		surl.Link.String(),
+		surl.Title,
+		surl.Description,
+		strings.Join(surl.Tags, ","),
+		surl.Owner,
+		metadata,
+		formatTime(surl.ExpiresAt),
+		formatTime(surl.CreatedAt),
+		string(surl.IdempotencyKey),
+		string(surl.ID),
+	})
+}
+
+func (e *csvEncoder) Close() error {
+	if !e.headerWritten {
+		if err := e.writer.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+type jsonEncoder struct {
+	w       io.Writer
+	started bool
+}
+
+func (e *jsonEncoder) Encode(surl shorturl.SelectableShortURL) error {
+	separator := ",\n"
+	if !e.started {
+		separator = "[\n"
+		e.started = true
+	}
+
+	encoded, err := json.Marshal(surl)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(e.w, separator+string(encoded))
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	closing := "\n]\n"
+	if !e.started {
+		closing = "[]\n"
+	}
+
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
+
+type ndjsonEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonEncoder) Encode(surl shorturl.SelectableShortURL) error {
+	return e.encoder.Encode(surl)
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package transfer_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/transfer"
+)
+
+func TestEncoder(t *testing.T) {
+	link, _ := shorturl.NewLink("https://example.com/q3.pdf")
+	surl := shorturl.SelectableShortURL{
+		ID:   "0190a000-0000-7000-8000-000000000001",
+		Name: "q3",
+		Link: link,
+		Details: shorturl.Details{
+			Title:    "Q3, final",
+			Tags:     []string{"email", "launch"},
+			Metadata: shorturl.Metadata{"channel": "email"},
+		},
+	}
+
+	for _, format := range []transfer.Format{transfer.CSV, transfer.JSON, transfer.NDJSON} {
+		t.Run("should read back its own "+string(format)+" export", func(t *testing.T) {
+			var buffer bytes.Buffer
+			encoder, err := transfer.NewEncoder(&buffer, format)
+			if err != nil {
+				t.Fatalf("NewEncoder() %v", err)
+			}
+			for range 2 {
+				if err = encoder.Encode(surl); err != nil {
+					t.Fatalf("Encode() %v", err)
+				}
+			}
+			if err = encoder.Close(); err != nil {
+				t.Fatalf("Close() %v", err)
+			}
+
+			records := decodeAll(t, buffer.String(), format)
+
+			if len(records) != 2 {
+				t.Fatalf("want 2 records, got %d", len(records))
+			}
+			record := records[0]
+			if record.Name != surl.Name || record.Link != link.String() || record.Title != surl.Title {
+				t.Errorf("want %+v, got %+v", surl, record)
+			}
+			if len(record.Tags) != 2 || record.Metadata["channel"] != "email" {
+				t.Errorf("want %+v, got %+v", surl.Details, record.Details)
+			}
+		})
+	}
+
+	t.Run("should write an empty JSON array", func(t *testing.T) {
+		var buffer bytes.Buffer
+		encoder, _ := transfer.NewEncoder(&buffer, transfer.JSON)
+		if err := encoder.Close(); err != nil {
+			t.Fatalf("Close() %v", err)
+		}
+
+		if got := buffer.String(); got != "[]\n" {
+			t.Errorf("want %q, got %q", "[]\n", got)
+		}
+	})
+}
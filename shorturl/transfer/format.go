@@ -0,0 +1,39 @@
+// Package transfer moves links in and out of the shortener as CSV, JSON
+// or newline-delimited JSON
+package transfer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+type Format string
+
+const (
+	CSV    Format = "csv"
+	JSON   Format = "json"
+	NDJSON Format = "ndjson"
+)
+
+func ParseFormat(raw string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(raw))); format {
+	case CSV, JSON, NDJSON:
+		return format, nil
+	case "jsonl":
+		return NDJSON, nil
+	}
+
+	return "", errs.InvalidError.New(fmt.Sprintf("unknown format %q", raw))
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case NDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/json"
+}
@@ -0,0 +1,237 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/errs"
+)
+
+const (
+	importChunkSize   = 500
+	maxRenameAttempts = 20
+)
+
+// ConflictStrategy is what an import does with a name already taken by an active link
+type ConflictStrategy string
+
+const (
+	// Skip leaves the existing link alone
+	Skip ConflictStrategy = "skip"
+	// Overwrite points the existing link to the imported destination
+	Overwrite ConflictStrategy = "overwrite"
+	// Rename imports the link as name-2, name-3, ...
+	Rename ConflictStrategy = "rename"
+)
+
+func ParseConflictStrategy(raw string) (ConflictStrategy, error) {
+	switch strategy := ConflictStrategy(strings.ToLower(strings.TrimSpace(raw))); strategy {
+	case Skip, Overwrite, Rename:
+		return strategy, nil
+	case "":
+		return Skip, nil
+	}
+
+	return "", errs.InvalidError.New(fmt.Sprintf("unknown conflict strategy %q", raw))
+}
+
+type ImportStatus string
+
+const (
+	ImportCreated     ImportStatus = "created"
+	ImportExisting    ImportStatus = "existing"
+	ImportSkipped     ImportStatus = "skipped"
+	ImportOverwritten ImportStatus = "overwritten"
+	ImportRenamed     ImportStatus = "renamed"
+	ImportFailed      ImportStatus = "error"
+)
+
+type ImportItem struct {
+	Row       int          `json:"row"`
+	Name      string       `json:"name"`
+	Status    ImportStatus `json:"status"`
+	RenamedTo string       `json:"renamedTo,omitempty"`
+	Error     string       `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	Total       int `json:"total"`
+	Created     int `json:"created"`
+	Existing    int `json:"existing"`
+	Skipped     int `json:"skipped"`
+	Overwritten int `json:"overwritten"`
+	Renamed     int `json:"renamed"`
+	Failed      int `json:"failed"`
+	// Items lists every record that was not simply created
+	Items []ImportItem `json:"items"`
+}
+
+func (report *ImportReport) add(item ImportItem) {
+	switch item.Status {
+	case ImportCreated:
+		report.Created++
+		return
+	case ImportExisting:
+		report.Existing++
+	case ImportSkipped:
+		report.Skipped++
+	case ImportOverwritten:
+		report.Overwritten++
+	case ImportRenamed:
+		report.Renamed++
+	case ImportFailed:
+		report.Failed++
+	}
+	report.Items = append(report.Items, item)
+}
+
+// Import creates the links read from r through Service.CreateMany, in
+// chunks, resolving name conflicts with strategy. Invalid records are
+// reported and skipped; a file that cannot be read stops the import,
+// keeping the chunks already imported
+func Import(ctx context.Context, r io.Reader, format Format, strategy ConflictStrategy, service *shorturl.Service) (ImportReport, error) {
+	report := ImportReport{Items: []ImportItem{}}
+	chunk := make([]Record, 0, importChunkSize)
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		err := importChunk(ctx, chunk, strategy, service, &report)
+		chunk = chunk[:0]
+		return err
+	}
+
+	decodeErr := DecodeRecords(r, format, func(record Record) error {
+		report.Total++
+		chunk = append(chunk, record)
+		if len(chunk) < importChunkSize {
+			return nil
+		}
+		return flush()
+	})
+	if decodeErr != nil {
+		return report, decodeErr
+	}
+
+	return report, flush()
+}
+
+type pendingRecord struct {
+	record Record
+	surl   shorturl.ShortURL
+}
+
+func importChunk(ctx context.Context, records []Record, strategy ConflictStrategy, service *shorturl.Service, report *ImportReport) error {
+	pending := make([]pendingRecord, 0, len(records))
+	for _, record := range records {
+		link, linkErr := shorturl.NewLink(strings.TrimSpace(record.Link))
+		if linkErr != nil || link.Hostname() == "" {
+			report.add(ImportItem{Row: record.Row, Name: record.Name, Status: ImportFailed, Error: "invalid_link"})
+			continue
+		}
+
+		pending = append(pending, pendingRecord{
+			record: record,
+			surl: shorturl.ShortURL{
+				Name:           record.Name,
+				Link:           link,
+				IdempotencyKey: record.IdempotencyKey,
+				ExpiresAt:      record.ExpiresAt,
+				Details:        record.Details,
+			},
+		})
+	}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		items := make([]shorturl.ShortURL, len(pending))
+		for i, p := range pending {
+			items[i] = p.surl
+		}
+
+		results, createErr := service.CreateMany(ctx, items)
+		if createErr != nil {
+			return createErr
+		}
+
+		var conflicts []pendingRecord
+		for i, result := range results {
+			p := pending[i]
+			item := ImportItem{Row: p.record.Row, Name: p.record.Name}
+
+			switch {
+			case result.Status == shorturl.BatchCreated && p.surl.Name != p.record.Name:
+				item.Status, item.RenamedTo = ImportRenamed, p.surl.Name
+			case result.Status == shorturl.BatchCreated:
+				item.Status = ImportCreated
+			case result.Status == shorturl.BatchExisting:
+				item.Status = ImportExisting
+			case result.Error == "name_taken" || result.Error == "duplicate_in_batch":
+				conflicts = append(conflicts, p)
+				continue
+			default:
+				item.Status, item.Error = ImportFailed, result.Error
+			}
+
+			report.add(item)
+		}
+
+		pending = pending[:0]
+		switch strategy {
+		case Skip:
+			for _, p := range conflicts {
+				report.add(ImportItem{Row: p.record.Row, Name: p.record.Name, Status: ImportSkipped, Error: "name_taken"})
+			}
+		case Overwrite:
+			overwriteErr := overwrite(ctx, conflicts, service, report)
+			if overwriteErr != nil {
+				return overwriteErr
+			}
+		case Rename:
+			for _, p := range conflicts {
+				if attempt >= maxRenameAttempts {
+					report.add(ImportItem{Row: p.record.Row, Name: p.record.Name, Status: ImportFailed, Error: "name_taken"})
+					continue
+				}
+
+				p.surl.Name = fmt.Sprintf("%s-%d", p.record.Name, attempt+1)
+				pending = append(pending, p)
+			}
+		}
+	}
+
+	return nil
+}
+
+func overwrite(ctx context.Context, conflicts []pendingRecord, service *shorturl.Service, report *ImportReport) error {
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	surls := make([]shorturl.ShortURL, len(conflicts))
+	for i, p := range conflicts {
+		surls[i] = p.surl
+	}
+
+	overwritten, overwriteErr := service.Overwrite(ctx, surls, "import")
+	if overwriteErr != nil {
+		return overwriteErr
+	}
+	done := make(map[string]bool, len(overwritten))
+	for _, name := range overwritten {
+		done[name] = true
+	}
+
+	for _, p := range conflicts {
+		if done[p.surl.Name] {
+			report.add(ImportItem{Row: p.record.Row, Name: p.record.Name, Status: ImportOverwritten})
+			continue
+		}
+		report.add(ImportItem{Row: p.record.Row, Name: p.record.Name, Status: ImportFailed, Error: "name_taken"})
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package transfer_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+	"github.com/rcovery/go-url-shortener/shorturl/memory"
+	"github.com/rcovery/go-url-shortener/shorturl/transfer"
+)
+
+func TestImport(t *testing.T) {
+	input := "name,link\nq3,https://example.com/new\nbroken,not a link\n"
+
+	tests := []struct {
+		strategy transfer.ConflictStrategy
+		status   transfer.ImportStatus
+		want     string
+	}{
+		{transfer.Skip, transfer.ImportSkipped, "https://example.com/old"},
+		{transfer.Overwrite, transfer.ImportOverwritten, "https://example.com/new"},
+		{transfer.Rename, transfer.ImportRenamed, "https://example.com/old"},
+	}
+
+	for _, tt := range tests {
+		t.Run("should resolve conflicts with "+string(tt.strategy), func(t *testing.T) {
+			ctx := context.Background()
+			service := shorturl.NewService(memory.NewRepository())
+
+			id, _ := shorturl.NewID()
+			idempotencyKey, _ := shorturl.NewIdempotencyKey()
+			link, _ := shorturl.NewLink("https://example.com/old")
+			if _, err := service.Create(ctx, id, idempotencyKey, "q3", link, shorturl.Details{}); err != nil {
+				t.Fatalf("Create failed unexpectedly: %v", err)
+			}
+
+			report, err := transfer.Import(ctx, strings.NewReader(input), transfer.CSV, tt.strategy, service)
+			if err != nil {
+				t.Fatalf("Import failed unexpectedly: %v", err)
+			}
+
+			if report.Total != 2 || report.Failed != 1 || len(report.Items) != 2 {
+				t.Fatalf("unexpected report %+v", report)
+			}
+			if report.Items[0].Status != tt.status && report.Items[1].Status != tt.status {
+				t.Errorf("want a %q item, got %+v", tt.status, report.Items)
+			}
+
+			current, err := service.Select(ctx, "q3")
+			if err != nil {
+				t.Fatalf("cannot select q3: %v", err)
+			}
+			if current.String() != tt.want {
+				t.Errorf("want %q, got %q", tt.want, current)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rcovery/go-url-shortener/shorturl"
+)
+
+// Record is one link as read from an import file, before validation
+type Record struct {
+	// Row is the 1-based position of the record in its file, not counting a CSV header
+	Row            int                     `json:"-"`
+	Name           string                  `json:"name"`
+	Link           string                  `json:"link"`
+	IdempotencyKey shorturl.IdempotencyKey `json:"idempotencyKey,omitempty"`
+	ExpiresAt      time.Time               `json:"expiresAt,omitzero"`
+	shorturl.Details
+}
+
+// csvColumns maps the normalized headers (lowercase, letters and digits
+// only) of our own export and of common hosted shorteners' exports to
+// Record fields. When several candidates are present the first one wins,
+// so Bitly's "long_url" is preferred over its "link", which is the short link
+var csvColumns = map[string][]string{
+	"name":        {"name", "slug", "slashtag", "alias", "keyword", "backhalf", "custombackhalf", "path"},
+	"link":        {"longurl", "destination", "destinationurl", "originalurl", "targeturl", "url", "link"},
+	"shortURL":    {"shorturl", "shortlink", "bitlink", "tinyurl", "link"},
+	"title":       {"title"},
+	"description": {"description", "notes", "note"},
+	"tags":        {"tags", "tag", "labels"},
+	"owner":       {"owner"},
+	"metadata":    {"metadata"},
+	"expiresAt":   {"expiresat", "expires", "expiration", "expirationdate", "expiry"},
+	"key":         {"idempotencykey"},
+}
+
+var expiryLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+func normalizeHeader(header string) string {
+	var normalized strings.Builder
+	for _, r := range strings.ToLower(strings.TrimPrefix(header, "\uFEFF")) {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			normalized.WriteRune(r)
+		}
+	}
+	return normalized.String()
+}
+
+// DecodeRecords reads records from r and calls fn for each one. A record
+// that cannot be read at all stops decoding; field values are validated
+// later, when the record is imported
+func DecodeRecords(r io.Reader, format Format, fn func(Record) error) error {
+	switch format {
+	case CSV:
+		return decodeCSV(r, fn)
+	case JSON:
+		return decodeJSONArray(r, fn)
+	case NDJSON:
+		return decodeNDJSON(r, fn)
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func decodeCSV(r io.Reader, fn func(Record) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, headerErr := reader.Read()
+	if headerErr != nil {
+		return fmt.Errorf("reading csv header: %w", headerErr)
+	}
+
+	positions := map[string]int{}
+	for i, column := range header {
+		if _, taken := positions[normalizeHeader(column)]; !taken {
+			positions[normalizeHeader(column)] = i
+		}
+	}
+
+	fields := map[string]int{}
+	for field, candidates := range csvColumns {
+		for _, candidate := range candidates {
+			if position, ok := positions[candidate]; ok {
+				fields[field] = position
+				break
+			}
+		}
+	}
+	if _, ok := fields["link"]; !ok {
+		return errors.New("csv has no destination column")
+	}
+	// The same column cannot be both the destination and the short link
+	if fields["shortURL"] == fields["link"] {
+		delete(fields, "shortURL")
+	}
+
+	for row := 1; ; row++ {
+		values, readErr := reader.Read()
+		if errors.Is(readErr, io.EOF) {
+			return nil
+		}
+		if readErr != nil {
+			return fmt.Errorf("reading csv row %d: %w", row, readErr)
+		}
+
+		value := func(field string) string {
+			position, ok := fields[field]
+			if !ok || position >= len(values) {
+				return ""
+			}
+			return strings.TrimSpace(values[position])
+		}
+
+		record := Record{
+			Row:            row,
+			Name:           value("name"),
+			Link:           value("link"),
+			IdempotencyKey: shorturl.IdempotencyKey(value("key")),
+			Details: shorturl.Details{
+				Owner:       value("owner"),
+				Title:       value("title"),
+				Description: value("description"),
+				Tags:        splitTags(value("tags")),
+			},
+		}
+		if record.Name == "" {
+			record.Name = nameFromShortURL(value("shortURL"))
+		}
+		if rawMetadata := value("metadata"); rawMetadata != "" {
+			if metadataErr := json.Unmarshal([]byte(rawMetadata), &record.Metadata); metadataErr != nil {
+				return fmt.Errorf("reading csv row %d: invalid metadata: %w", row, metadataErr)
+			}
+		}
+		if rawExpiry := value("expiresAt"); rawExpiry != "" {
+			expiresAt, expiryErr := parseExpiry(rawExpiry)
+			if expiryErr != nil {
+				return fmt.Errorf("reading csv row %d: %w", row, expiryErr)
+			}
+			record.ExpiresAt = expiresAt
+		}
+
+		if fnErr := fn(record); fnErr != nil {
+			return fnErr
+		}
+	}
+}
+
+func splitTags(raw string) []string {
+	return strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == '|' || r == ';'
+	})
+}
+
+// nameFromShortURL turns "https://bit.ly/q3-deck" into "q3-deck"
+func nameFromShortURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	name := path.Base(strings.TrimSuffix(parsed.Path, "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+func parseExpiry(raw string) (time.Time, error) {
+	for _, layout := range expiryLayouts {
+		if expiresAt, err := time.Parse(layout, raw); err == nil {
+			return expiresAt, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry %q", raw)
+}
+
+func decodeJSONArray(r io.Reader, fn func(Record) error) error {
+	decoder := json.NewDecoder(r)
+
+	opening, tokenErr := decoder.Token()
+	if tokenErr != nil {
+		return fmt.Errorf("reading json: %w", tokenErr)
+	}
+	if opening != json.Delim('[') {
+		return errors.New("json import must be an array of links")
+	}
+
+	for row := 1; decoder.More(); row++ {
+		var record Record
+		if decodeErr := decoder.Decode(&record); decodeErr != nil {
+			return fmt.Errorf("reading json item %d: %w", row, decodeErr)
+		}
+		record.Row = row
+
+		if fnErr := fn(record); fnErr != nil {
+			return fnErr
+		}
+	}
+
+	if _, tokenErr = decoder.Token(); tokenErr != nil {
+		return fmt.Errorf("reading json: %w", tokenErr)
+	}
+	return nil
+}
+
+func decodeNDJSON(r io.Reader, fn func(Record) error) error {
+	decoder := json.NewDecoder(r)
+
+	for row := 1; ; row++ {
+		var record Record
+		decodeErr := decoder.Decode(&record)
+		if errors.Is(decodeErr, io.EOF) {
+			return nil
+		}
+		if decodeErr != nil {
+			return fmt.Errorf("reading ndjson line %d: %w", row, decodeErr)
+		}
+		record.Row = row
+
+		if fnErr := fn(record); fnErr != nil {
+			return fnErr
+		}
+	}
+}
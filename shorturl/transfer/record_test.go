@@ -0,0 +1,88 @@
+package transfer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rcovery/go-url-shortener/shorturl/transfer"
+)
+
+func decodeAll(t *testing.T, input string, format transfer.Format) []transfer.Record {
+	t.Helper()
+
+	var records []transfer.Record
+	err := transfer.DecodeRecords(strings.NewReader(input), format, func(record transfer.Record) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DecodeRecords() %v", err)
+	}
+
+	return records
+}
+
+func TestDecodeRecords(t *testing.T) {
+	t.Run("should read our own CSV export", func(t *testing.T) {
+		input := "name,link,title,description,tags,owner,metadata,expires_at,created_at,idempotency_key,id\n" +
+			`q3,https://example.com/q3.pdf,Q3 deck,,"email,launch",marketing,"{""channel"":""email""}",2030-01-02T03:04:05Z,,,` + "\n"
+
+		records := decodeAll(t, input, transfer.CSV)
+
+		if len(records) != 1 {
+			t.Fatalf("want 1 record, got %d", len(records))
+		}
+		record := records[0]
+		if record.Name != "q3" || record.Link != "https://example.com/q3.pdf" || record.Owner != "marketing" {
+			t.Errorf("unexpected record %+v", record)
+		}
+		if len(record.Tags) != 2 || record.Metadata["channel"] != "email" || record.ExpiresAt.Year() != 2030 {
+			t.Errorf("unexpected details %+v", record)
+		}
+	})
+
+	t.Run("should read a Bitly style export", func(t *testing.T) {
+		input := "id,link,custom_bitlinks,long_url,title,tags,created_at\n" +
+			"bit.ly/3abc,https://bit.ly/3abc,,https://example.com/launch,Launch,launch|social,2024-01-01 10:00:00\n"
+
+		records := decodeAll(t, input, transfer.CSV)
+
+		if records[0].Name != "3abc" {
+			t.Errorf("want %q, got %q", "3abc", records[0].Name)
+		}
+		if records[0].Link != "https://example.com/launch" {
+			t.Errorf("want %q, got %q", "https://example.com/launch", records[0].Link)
+		}
+		if len(records[0].Tags) != 2 {
+			t.Errorf("want 2 tags, got %q", records[0].Tags)
+		}
+	})
+
+	t.Run("should read a Rebrandly style export", func(t *testing.T) {
+		input := "Slashtag,Destination,Title,Short URL\nsummer,https://example.com/summer,Summer,rebrand.ly/summer\n"
+
+		records := decodeAll(t, input, transfer.CSV)
+
+		if records[0].Name != "summer" || records[0].Link != "https://example.com/summer" {
+			t.Errorf("unexpected record %+v", records[0])
+		}
+	})
+
+	t.Run("should reject a CSV without a destination", func(t *testing.T) {
+		err := transfer.DecodeRecords(strings.NewReader("name,title\nq3,Q3\n"), transfer.CSV, func(transfer.Record) error { return nil })
+		if err == nil {
+			t.Errorf("expected an error for a CSV without destination, got nil")
+		}
+	})
+
+	t.Run("should read JSON and NDJSON", func(t *testing.T) {
+		jsonRecords := decodeAll(t, `[{"name": "a", "link": "https://example.com/a"}, {"name": "b", "link": "https://example.com/b", "tags": ["x"]}]`, transfer.JSON)
+		ndjsonRecords := decodeAll(t, "{\"name\": \"a\", \"link\": \"https://example.com/a\"}\n{\"name\": \"b\", \"link\": \"https://example.com/b\", \"tags\": [\"x\"]}\n", transfer.NDJSON)
+
+		for _, records := range [][]transfer.Record{jsonRecords, ndjsonRecords} {
+			if len(records) != 2 || records[1].Row != 2 || records[1].Tags[0] != "x" {
+				t.Errorf("unexpected records %+v", records)
+			}
+		}
+	})
+}